@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/auth"
+	"github.com/drerr0r/url-shortener/internal/config"
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/storage"
+)
+
+const apiKeyUsage = "usage: url-shortener apikey create NAME [--role viewer|editor|admin] | list | revoke ID [flags]"
+
+// runAPIKey выполняет подкоманду apikey: выпуск, просмотр и отзыв API ключей
+func runAPIKey(args []string) error {
+	if len(args) == 0 {
+		return errors.New(apiKeyUsage)
+	}
+
+	// У create и revoke первый аргумент после команды - имя или ID ключа
+	command, flags := args[0], args[1:]
+	var operand string
+	if command == "create" || command == "revoke" {
+		if len(flags) == 0 {
+			return errors.New(apiKeyUsage)
+		}
+		operand, flags = flags[0], flags[1:]
+	}
+
+	// --role относится к create, а не к конфигурации, поэтому разбирается до config.Load
+	role := models.RoleEditor
+	if command == "create" {
+		value, rest, err := takeFlag(flags, "role")
+		if err != nil {
+			return err
+		}
+		if value != "" {
+			parsed, ok := models.ParseRole(value)
+			if !ok {
+				return fmt.Errorf("invalid role %q, expected viewer, editor or admin", value)
+			}
+			role = parsed
+		}
+		flags = rest
+	}
+
+	cfg, err := config.Load(flags)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	db, err := storage.Connect(context.Background(), cfg.GetDSNs()...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	keys := storage.NewPostgresStorage(db)
+	ctx := context.Background()
+	switch command {
+	case "create":
+		key, secret, err := auth.CreateKey(ctx, keys, operand, role)
+		if err != nil {
+			return fmt.Errorf("failed to create API key: %w", err)
+		}
+		fmt.Printf("Created API key %d (%s, role %s)\n", key.ID, key.Name, key.Role)
+		fmt.Println("Store it now, it will not be shown again:")
+		fmt.Println(secret)
+	case "list":
+		list, err := keys.ListAPIKeys(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list API keys: %w", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tROLE\tPREFIX\tCREATED AT\tLAST USED\tREVOKED AT")
+		for _, key := range list {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.Name, key.Role, key.Prefix,
+				key.CreatedAt.Format(time.RFC3339), formatOptionalTime(key.LastUsedAt), formatOptionalTime(key.RevokedAt))
+		}
+		return w.Flush()
+	case "revoke":
+		id, err := strconv.ParseInt(operand, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid API key ID %q", operand)
+		}
+		if err := keys.RevokeAPIKey(ctx, id); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return fmt.Errorf("API key %d not found", id)
+			}
+			return fmt.Errorf("failed to revoke API key: %w", err)
+		}
+		fmt.Printf("Revoked API key %d\n", id)
+	default:
+		return fmt.Errorf("unknown apikey command %q; %s", command, apiKeyUsage)
+	}
+	return nil
+}
+
+// takeFlag извлекает из аргументов флаг --name VALUE или --name=VALUE и возвращает остальные аргументы
+func takeFlag(args []string, name string) (string, []string, error) {
+	var value string
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--"+name || arg == "-"+name:
+			if i+1 >= len(args) {
+				return "", nil, fmt.Errorf("flag --%s requires a value", name)
+			}
+			value = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--"+name+"="):
+			value = strings.TrimPrefix(arg, "--"+name+"=")
+		case strings.HasPrefix(arg, "-"+name+"="):
+			value = strings.TrimPrefix(arg, "-"+name+"=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return value, rest, nil
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
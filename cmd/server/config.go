@@ -0,0 +1,25 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/drerr0r/url-shortener/internal/config"
+)
+
+const configUsage = "usage: url-shortener config print [flags]"
+
+// runConfig выполняет подкоманду config. print выводит действующую конфигурацию
+// с учетом файла, окружения и флагов; секреты скрыты
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return errors.New(configUsage)
+	}
+
+	cfg, err := config.Load(args[1:])
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	return config.Print(os.Stdout, cfg)
+}
@@ -1,155 +1,393 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"expvar"
 	"log"
-	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/drerr0r/url-shortener/internal/audit"
+	"github.com/drerr0r/url-shortener/internal/auth"
+	"github.com/drerr0r/url-shortener/internal/clicks"
 	"github.com/drerr0r/url-shortener/internal/config"
 	"github.com/drerr0r/url-shortener/internal/handlers"
+	"github.com/drerr0r/url-shortener/internal/health"
+	"github.com/drerr0r/url-shortener/internal/janitor"
+	"github.com/drerr0r/url-shortener/internal/metrics"
 	"github.com/drerr0r/url-shortener/internal/middleware"
+	"github.com/drerr0r/url-shortener/internal/migrate"
+	"github.com/drerr0r/url-shortener/internal/oidc"
+	"github.com/drerr0r/url-shortener/internal/ratelimit"
+	"github.com/drerr0r/url-shortener/internal/server"
+	"github.com/drerr0r/url-shortener/internal/settings"
+	"github.com/drerr0r/url-shortener/internal/shortcode"
 	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/drerr0r/url-shortener/internal/tracing"
 	"github.com/gin-gonic/gin"
-	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
-	"github.com/pressly/goose/v3"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
 )
 
-// applyMigrations автоматически применяет миграции базы данных при запуске
-// Проверяет существование таблицы urls и применяет миграции если необходимо
-func applyMigrations(db *sqlx.DB) error {
-	// Проверяем существование таблицы urls
-	var tableExists bool
-	err := db.QueryRow(`
-		SELECT EXISTS (
-			SELECT FROM information_schema.tables 
-			WHERE table_schema = 'public' 
-			AND table_name = 'urls'
-		)
-	`).Scan(&tableExists)
-
-	if err != nil {
-		return fmt.Errorf("failed to check table existence: %w", err)
-	}
-
-	// Если таблица не существует, применяем миграции
-	if !tableExists {
-		log.Println("Database table 'urls' not found. Applying migrations...")
-
-		// Применяем миграции с помощью goose
-		if err := goose.SetDialect("postgres"); err != nil {
-			return fmt.Errorf("failed to set dialect: %w", err)
-		}
-
-		if err := goose.Up(db.DB, "migrations"); err != nil {
-			return fmt.Errorf("failed to apply migrations: %w", err)
+func main() {
+	// Подкоманды: url-shortener migrate up|down|status|redo, url-shortener config print,
+	// url-shortener apikey create|list|revoke, url-shortener user role
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			if err := runMigrate(os.Args[2:]); err != nil {
+				log.Fatalf("Migration failed: %v", err)
+			}
+			return
+		case "config":
+			if err := runConfig(os.Args[2:]); err != nil {
+				log.Fatalf("Config command failed: %v", err)
+			}
+			return
+		case "apikey":
+			if err := runAPIKey(os.Args[2:]); err != nil {
+				log.Fatalf("API key command failed: %v", err)
+			}
+			return
+		case "user":
+			if err := runUser(os.Args[2:]); err != nil {
+				log.Fatalf("User command failed: %v", err)
+			}
+			return
 		}
+	}
 
-		log.Println("Migrations applied successfully")
-	} else {
-		log.Println("Database table 'urls' already exists. Skipping migrations.")
+	// Загрузка конфигурации: файл (--config или CONFIG_FILE), окружение, флаги
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	return nil
-}
+	// Настройки, которые меняются без перезапуска: компоненты подписываются на новые снимки
+	liveSettings := settings.NewStore(settings.FromConfig(cfg))
+	liveSettings.Subscribe(func(s *settings.Settings) {
+		zerolog.SetGlobalLevel(s.LogLevel)
+	})
 
-func main() {
-	// Загрузка конфигурации
-	cfg, err := config.LoadConfig()
+	// Трассировка OpenTelemetry
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.TracingExporter,
+		Endpoint:    cfg.TracingEndpoint,
+		Insecure:    cfg.TracingInsecure,
+		ServiceName: cfg.TracingServiceName,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		log.Fatalf("Failed to set up tracing: %v", err)
 	}
 
 	// Подключение к базе данных
-	db, err := sqlx.Connect("postgres", cfg.GetDSN())
+	log.Printf("Connecting to database: %s", cfg.RedactedDSN())
+	db, err := storage.Connect(context.Background(), cfg.GetDSNs()...)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
-	defer db.Close()
 
 	// Настройка пула соединений
 	db.SetMaxOpenConns(cfg.DBMaxOpenConns)
 	db.SetMaxIdleConns(cfg.DBMaxIdleConns)
 	db.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
 
-	// Автоматическое применение миграций
-	if err := applyMigrations(db); err != nil {
-		log.Fatalf("Failed to apply database migrations: %v", err)
+	// Миграции схемы; реплики применяют их по очереди под advisory lock
+	migrator, err := migrate.New(db.DB)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+	if cfg.MigrateOnStart {
+		if _, err := migrator.Up(context.Background()); err != nil {
+			log.Fatalf("Failed to apply database migrations: %v", err)
+		}
+	}
+
+	// Метрики Prometheus: HTTP, хранилище, пул соединений и Go runtime
+	appMetrics := metrics.New()
+	appMetrics.RegisterDBStats(db.DB, cfg.DBName)
+
+	// Создание хранилища; метрики и спаны снимаются с запросов к базе, под кэшами
+	postgresStorage := storage.NewPostgresStorage(db)
+	var store storage.Storage = tracing.NewTracedStorage(
+		metrics.NewInstrumentedStorage(postgresStorage, appMetrics),
+	)
+	// Статистика читается мимо кэшей, чтобы click_count не отставал
+	uncachedStore := store
+
+	// Кэш ссылок в Redis перед базой данных
+	var redisClient *redis.Client
+	if cfg.CacheEnabled {
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:     cfg.GetRedisAddr(),
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+		})
+		// Недоступный Redis не мешает старту: кэш сам откатывается на базу данных
+		if err := redisClient.Ping(context.Background()).Err(); err != nil {
+			log.Printf("Redis is unavailable, cache will fall back to database: %v", err)
+		}
+		redisCache := storage.NewRedisCache(store, redisClient, storage.RedisCacheOptions{
+			TTL:         cfg.CacheTTL,
+			NegativeTTL: cfg.CacheNegativeTTL,
+		})
+		liveSettings.Subscribe(func(s *settings.Settings) {
+			redisCache.SetTTL(s.CacheTTL, s.CacheNegativeTTL)
+		})
+		store = redisCache
 	}
 
-	// Создание хранилища
-	storage := storage.NewPostgresStorage(db)
+	// LRU кэш горячих ссылок в памяти; параллельные промахи схлопываются в один запрос
+	if cfg.MemoryCacheEnabled {
+		memoryCache := storage.NewMemoryCache(store, storage.MemoryCacheOptions{
+			Size:        cfg.MemoryCacheSize,
+			TTL:         cfg.MemoryCacheTTL,
+			NegativeTTL: cfg.MemoryCacheNegativeTTL,
+		})
+		expvar.Publish("url_cache", expvar.Func(func() any { return memoryCache.Stats() }))
+		appMetrics.RegisterMemoryCacheStats(memoryCache.Stats)
+		liveSettings.Subscribe(func(s *settings.Settings) {
+			memoryCache.SetTTL(s.MemoryCacheTTL, s.MemoryCacheNegativeTTL)
+		})
+		store = memoryCache
+	}
+
+	// Регистратор кликов: в режиме async клики агрегируются в памяти и пишутся батчами
+	var recorder clicks.Recorder
+	if cfg.ClickRecorderMode == "sync" {
+		recorder = clicks.NewSyncRecorder(store)
+	} else {
+		buffered := clicks.NewBufferedRecorder(store, clicks.Options{
+			QueueSize:     cfg.ClickQueueSize,
+			BatchSize:     cfg.ClickBatchSize,
+			FlushInterval: cfg.ClickFlushInterval,
+		})
+		expvar.Publish("click_recorder", expvar.Func(func() any { return buffered.Stats() }))
+		appMetrics.RegisterClickRecorderStats(buffered.Stats)
+		recorder = buffered
+	}
+
+	// Фоновая очистка истекших ссылок и старых событий журнала аудита
+	var cleaner *janitor.Janitor
+	if cfg.JanitorEnabled {
+		cleaner = janitor.New(store, janitor.Options{
+			Interval:       cfg.JanitorInterval,
+			Mode:           cfg.JanitorMode,
+			BatchSize:      cfg.JanitorBatchSize,
+			Audit:          postgresStorage,
+			AuditRetention: cfg.AuditRetention,
+		})
+		cleaner.Start()
+	}
+
+	// Генератор коротких кодов с повтором при коллизиях
+	generator, err := shortcode.NewGenerator(cfg.AppShortCodeStrategy, store, uint64(cfg.AppShortCodeSequenceKey))
+	if err != nil {
+		log.Fatalf("Failed to create short code generator: %v", err)
+	}
+	allocator := shortcode.NewAllocator(store, shortcode.Options{
+		Generator:   generator,
+		Length:      cfg.AppShortCodeLength,
+		MaxLength:   cfg.AppShortCodeMaxLength,
+		MaxAttempts: cfg.AppShortCodeMaxAttempts,
+		Backoff:     cfg.AppShortCodeRetryBackoff,
+	})
+
+	passwordLimiter := ratelimit.NewAttemptLimiter(cfg.PasswordMaxAttempts, cfg.PasswordAttemptWindow)
+	liveSettings.Subscribe(func(s *settings.Settings) {
+		passwordLimiter.SetLimits(s.PasswordMaxAttempts, s.PasswordAttemptWindow)
+	})
 
 	// Создание обработчиков
-	urlHandler := handlers.NewURLHandler(storage)
+	urlHandler := handlers.NewURLHandler(store,
+		handlers.WithClickRecorder(recorder),
+		handlers.WithAllocator(allocator),
+		handlers.WithIPHashSalt(cfg.ClickIPSalt),
+		handlers.WithStatsStorage(uncachedStore),
+		handlers.WithMetrics(appMetrics),
+		handlers.WithPasswordLimiter(passwordLimiter),
+		handlers.WithSettings(liveSettings),
+	)
+
+	// Перезагрузка настроек по SIGHUP и при изменении файла конфигурации
+	configFile, err := config.FilePath(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	watcher := settings.NewWatcher(liveSettings, cfg, func() (*config.Config, error) {
+		return config.Load(os.Args[1:])
+	}, settings.WatchOptions{
+		Path:     configFile,
+		Interval: cfg.SettingsWatchInterval,
+	})
+	watcher.Start()
 
 	// Настройка роутера
 	router := gin.Default()
 
+	// По умолчанию gin верит X-Forwarded-For от любого клиента, и тогда ограничения по IP
+	// обходятся подменой заголовка. Доверяем только явно перечисленным прокси
+	if err := router.SetTrustedProxies(cfg.TrustedProxyList()); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
+
 	// 🔴 ДОБАВЛЕНО: Загрузка HTML шаблонов
 	router.LoadHTMLGlob("templates/*")
 
 	// Middleware
+	router.Use(middleware.RequestID())
+	router.Use(tracing.Middleware())
+	router.Use(appMetrics.Middleware())
 	router.Use(middleware.LoggingMiddleware())
 	router.Use(middleware.RecoveryMiddleware())
 
-	// 🔴 ДОБАВЛЕНО: Обработчик для главной страницы с HTML формой
-	router.GET("/", func(c *gin.Context) {
-		c.HTML(http.StatusOK, "index.html", gin.H{})
+	srv := server.New(router, server.Options{
+		Addr:            ":" + cfg.ServerPort,
+		ReadTimeout:     cfg.ServerReadTimeout,
+		WriteTimeout:    cfg.ServerWriteTimeout,
+		IdleTimeout:     cfg.ServerIdleTimeout,
+		ShutdownTimeout: cfg.ServerShutdownTimeout,
+		DrainDelay:      cfg.ServerDrainDelay,
+	})
+
+	// Порядок остановки: сначала фоновые задачи, пишущие в хранилище,
+	// затем соединения, которыми они пользуются, и в конце экспорт трейсов
+	srv.OnShutdown("settings watcher", func(context.Context) error {
+		watcher.Stop()
+		return nil
+	})
+	if cleaner != nil {
+		srv.OnShutdown("janitor", func(context.Context) error {
+			cleaner.Stop()
+			return nil
+		})
+	}
+	srv.OnShutdown("click recorder", func(context.Context) error {
+		return recorder.Close()
+	})
+	if redisClient != nil {
+		srv.OnShutdown("redis", func(context.Context) error {
+			return redisClient.Close()
+		})
+	}
+	srv.OnShutdown("database", func(context.Context) error {
+		return db.Close()
 	})
+	srv.OnShutdown("tracing", shutdownTracing)
 
-	// Маршруты API
-	api := router.Group("/api/v1")
+	// Клиент определяется по Authorization: Bearer или по cookie сессии, без них запрос анонимный
+	authenticator := auth.New(postgresStorage, auth.WithSessions(postgresStorage, auth.SessionOptions{
+		TTL:    cfg.SessionTTL,
+		Secure: cfg.SessionCookieSecure,
+	}))
+
+	// Веб-интерфейс: главная страница, учетные записи и список ссылок пользователя
+	// Ограничитель общий с паролями ссылок: ключи входа начинаются с зарезервированного "login|"
+	accountOptions := []handlers.AccountOption{
+		handlers.WithLoginLimiter(passwordLimiter),
+		handlers.WithPasswordLogin(!cfg.SSOOnly),
+	}
+	if cfg.OIDCIssuer != "" {
+		roles, err := oidc.ParseRoleMapping(cfg.OIDCRolesClaim, cfg.OIDCRoleMapping, cfg.OIDCDefaultRole)
+		if err != nil {
+			log.Fatalf("Invalid OIDC role mapping: %v", err)
+		}
+		provider := oidc.NewProvider(oidc.Config{
+			Issuer:       cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCCallbackURL(),
+			Scopes:       cfg.OIDCScopeList(),
+		})
+		accountOptions = append(accountOptions, handlers.WithSSO(provider, roles))
+		log.Printf("OIDC login enabled: issuer %s, redirect URL %s", cfg.OIDCIssuer, cfg.OIDCCallbackURL())
+	}
+	accountHandler := handlers.NewAccountHandler(postgresStorage, store, authenticator, accountOptions...)
+	web := router.Group("/", authenticator.Middleware())
 	{
-		api.POST("/shorten", urlHandler.ShortenURLHandler)
-		api.GET("/stats/:shortCode", urlHandler.GetURLStatsHandler)
+		web.GET("/", accountHandler.HomeHandler)
+		web.GET("/signup", accountHandler.SignupPageHandler)
+		web.POST("/signup", auth.RequireCSRF(), accountHandler.SignupHandler)
+		web.GET("/login", accountHandler.LoginPageHandler)
+		web.POST("/login", auth.RequireCSRF(), accountHandler.LoginHandler)
+		web.POST("/logout", accountHandler.LogoutHandler)
+		web.GET("/login/sso", accountHandler.SSOLoginHandler)
+		web.GET("/login/sso/callback", accountHandler.SSOCallbackHandler)
+		web.GET("/my/links", accountHandler.MyLinksHandler)
+	}
+
+	// Маршруты API. Права на действия определяет политика доступа auth по роли клиента,
+	// AuditOrigin передает хранилищу клиента запроса для журнала изменений ссылок
+	shortenAccess := []gin.HandlerFunc{}
+	if cfg.AuthRequireAPIKey {
+		shortenAccess = append(shortenAccess, auth.RequireAuth())
+	}
+	shortenAccess = append(shortenAccess, auth.Authorize(auth.ActionCreateLink))
+	viewStats := auth.Authorize(auth.ActionViewStats)
+	api := router.Group("/api/v1", authenticator.Middleware(), middleware.AuditOrigin())
+	{
+		api.POST("/shorten", append(shortenAccess, urlHandler.ShortenURLHandler)...)
+		api.GET("/stats/:shortCode", viewStats, urlHandler.GetURLStatsHandler)
+		api.GET("/stats/:shortCode/clicks", viewStats, urlHandler.GetURLClicksHandler)
+		api.GET("/stats/:shortCode/timeseries", viewStats, urlHandler.GetURLTimeSeriesHandler)
+		api.DELETE("/urls/:shortCode", auth.Authorize(auth.ActionDeleteLink), urlHandler.DeleteURLHandler)
+	}
+
+	// API администратора: любые ссылки, только для роли admin. Каждый запрос, включая отказы, попадает в аудит
+	adminHandler := handlers.NewAdminHandler(store, postgresStorage, audit.NewLogRecorder())
+	admin := api.Group("/admin")
+	{
+		admin.GET("/urls", adminHandler.Guard(auth.ActionAdminList), adminHandler.ListURLsHandler)
+		admin.GET("/urls/:shortCode", adminHandler.Guard(auth.ActionAdminView), adminHandler.GetURLHandler)
+		admin.DELETE("/urls/:shortCode", adminHandler.Guard(auth.ActionAdminDelete), adminHandler.DeleteURLHandler)
+		admin.POST("/urls/:shortCode/disable", adminHandler.Guard(auth.ActionAdminDisable), adminHandler.DisableURLHandler)
+		admin.POST("/urls/:shortCode/enable", adminHandler.Guard(auth.ActionAdminEnable), adminHandler.EnableURLHandler)
+		admin.GET("/audit", adminHandler.Guard(auth.ActionAdminAudit), adminHandler.AuditEventsHandler)
 	}
 
 	router.GET("/:shortCode", urlHandler.RedirectHandler)
+	router.POST("/:shortCode", urlHandler.UnlockHandler)
 
-	// Health check с проверкой базы данных
-	router.GET("/health", func(c *gin.Context) {
-		// Проверка доступности базы данных
-		if err := db.Ping(); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status": "database unavailable",
-				"error":  err.Error(),
-			})
-			return
-		}
+	// Внутренние метрики (очередь кликов и т.п.) в формате expvar. В них есть параметры запуска
+	// процесса, поэтому доступ только администратору
+	router.GET("/debug/vars", authenticator.Middleware(), middleware.AuditOrigin(),
+		adminHandler.Guard(auth.ActionAdminDebug), gin.WrapH(expvar.Handler()))
 
-		// Проверка существования таблицы
-		var tableExists bool
-		err := db.QueryRow(`
-			SELECT EXISTS (
-				SELECT FROM information_schema.tables 
-				WHERE table_schema = 'public' 
-				AND table_name = 'urls'
-			)
-		`).Scan(&tableExists)
-
-		if err != nil || !tableExists {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status": "database schema error",
-				"error":  "urls table does not exist",
-			})
-			return
-		}
+	// Метрики в формате Prometheus
+	router.GET("/metrics", gin.WrapH(appMetrics.Handler()))
 
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	// Пробы: /livez - процесс жив, /readyz - сервер готов и зависимости доступны
+	checks := health.New(health.Options{
+		CacheTTL: cfg.HealthCacheTTL,
+		Timeout:  cfg.HealthCheckTimeout,
+		Debug:    cfg.HealthDebug,
+		Ready:    srv.Ready,
 	})
-
-	// Запуск сервера
-	server := &http.Server{
-		Addr:         ":" + cfg.ServerPort,
-		Handler:      router,
-		ReadTimeout:  cfg.ServerReadTimeout,
-		WriteTimeout: cfg.ServerWriteTimeout,
-		IdleTimeout:  cfg.ServerIdleTimeout,
+	checks.Register("postgres", health.PostgresChecker(db.DB))
+	checks.Register("migrations", health.MigrationChecker(db.DB, migrator.Latest()))
+	if redisClient != nil {
+		// Кэш откатывается на базу данных, поэтому недоступный Redis не снимает готовность
+		checks.RegisterOptional("redis", health.RedisChecker(redisClient))
 	}
+	router.GET("/livez", checks.LivenessHandler)
+	router.GET("/readyz", checks.ReadinessHandler)
+	// Старый адрес оставлен для существующих мониторингов
+	router.GET("/health", checks.ReadinessHandler)
+
+	// Запуск сервера; SIGINT и SIGTERM запускают плавную остановку
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		// Повторный сигнал завершает процесс сразу, не дожидаясь остановки
+		stop()
+	}()
 
 	log.Printf("Server starting on port %s", cfg.ServerPort)
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("Server failed to start: %v", err)
+	if err := srv.ListenAndServe(ctx); err != nil {
+		log.Fatalf("Server stopped with error: %v", err)
 	}
+	log.Println("Server stopped")
 }
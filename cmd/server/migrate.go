@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/config"
+	"github.com/drerr0r/url-shortener/internal/migrate"
+	"github.com/drerr0r/url-shortener/internal/storage"
+)
+
+const migrateUsage = "usage: url-shortener migrate up|down|status|redo [flags]"
+
+// runMigrate выполняет подкоманду migrate с настройками подключения из окружения
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	cfg, err := config.Load(args[1:])
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	db, err := storage.Connect(context.Background(), cfg.GetDSNs()...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	migrator, err := migrate.New(db.DB)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		results, err := migrator.Up(ctx)
+		for _, result := range results {
+			fmt.Println(result)
+		}
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			fmt.Println("No pending migrations")
+		}
+	case "down":
+		result, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Println(result)
+	case "redo":
+		results, err := migrator.Redo(ctx)
+		for _, result := range results {
+			fmt.Println(result)
+		}
+		if err != nil {
+			return err
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tSTATE\tAPPLIED AT\tFILE")
+		for _, status := range statuses {
+			appliedAt := "-"
+			if !status.AppliedAt.IsZero() {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Source.Version, status.State, appliedAt, status.Source.Path)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q; %s", args[0], migrateUsage)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/drerr0r/url-shortener/internal/config"
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/storage"
+)
+
+const userUsage = "usage: url-shortener user role EMAIL viewer|editor|admin [flags]"
+
+// runUser выполняет подкоманду user. Сейчас это только смена роли, например чтобы назначить первого администратора.
+// Роль пользователей SSO пересчитывается из групп провайдера при каждом входе
+func runUser(args []string) error {
+	if len(args) < 3 || args[0] != "role" {
+		return errors.New(userUsage)
+	}
+	email, roleName, flags := strings.ToLower(strings.TrimSpace(args[1])), args[2], args[3:]
+
+	role, ok := models.ParseRole(roleName)
+	if !ok {
+		return fmt.Errorf("invalid role %q, expected viewer, editor or admin", roleName)
+	}
+
+	cfg, err := config.Load(flags)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	db, err := storage.Connect(context.Background(), cfg.GetDSNs()...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	users := storage.NewPostgresStorage(db)
+	ctx := context.Background()
+	user, err := users.GetUserByEmail(ctx, email)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("user %s not found", email)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	user.Role = role
+	if err := users.UpdateUser(ctx, user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	fmt.Printf("User %s now has role %s\n", user.Email, user.Role)
+	return nil
+}
@@ -0,0 +1,76 @@
+// internal/audit/audit.go
+
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Event описывает привилегированное действие: кто, что и над какой ссылкой сделал и чем это закончилось
+type Event struct {
+	Time      time.Time `json:"time"`
+	Actor     string    `json:"actor"`      // "user:<id>", "key:<id>" или "anonymous"
+	ActorRole string    `json:"actor_role"` // Роль клиента на момент действия
+	Action    string    `json:"action"`
+	ShortCode string    `json:"short_code,omitempty"`
+	ClientIP  string    `json:"client_ip"`
+	RequestID string    `json:"request_id,omitempty"`
+	Status    int       `json:"status"` // HTTP статус ответа; 401 и 403 - попытка без прав
+}
+
+// Recorder сохраняет события аудита. Ошибка записи не должна отменять само действие
+type Recorder interface {
+	Record(ctx context.Context, event Event)
+}
+
+// LogRecorder пишет события аудита в лог приложения отдельным сообщением с полем audit=true
+type LogRecorder struct {
+	logger zerolog.Logger
+}
+
+func NewLogRecorder() *LogRecorder {
+	return &LogRecorder{logger: log.Logger.With().Bool("audit", true).Logger()}
+}
+
+// Record пишет событие в лог
+func (r *LogRecorder) Record(_ context.Context, event Event) {
+	r.logger.Info().
+		Time("at", event.Time).
+		Str("actor", event.Actor).
+		Str("actor_role", event.ActorRole).
+		Str("action", event.Action).
+		Str("short_code", event.ShortCode).
+		Str("client_ip", event.ClientIP).
+		Str("request_id", event.RequestID).
+		Int("status", event.Status).
+		Msg("audit event")
+}
+
+// MemoryRecorder хранит события в памяти. Используется в тестах
+type MemoryRecorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func NewMemoryRecorder() *MemoryRecorder {
+	return &MemoryRecorder{}
+}
+
+// Record добавляет событие в список
+func (r *MemoryRecorder) Record(_ context.Context, event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// Events возвращает копию записанных событий
+func (r *MemoryRecorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
@@ -0,0 +1,36 @@
+// internal/audit/origin.go
+
+package audit
+
+import "context"
+
+// Исполнители изменений, сделанных не по запросу клиента, записываются как "system:<задача>"
+const (
+	SystemActorPrefix = "system:"
+	// SystemActor исполнитель изменений без источника в контексте
+	SystemActor = SystemActorPrefix + "internal"
+)
+
+// Origin источник изменения: кто его сделал, с какого IP и в каком запросе.
+// Хранилище берет его из контекста и записывает в журнал вместе с изменением ссылки
+type Origin struct {
+	Actor     string
+	ClientIP  string
+	RequestID string
+}
+
+type originKey struct{}
+
+// WithOrigin возвращает контекст с источником изменений
+func WithOrigin(ctx context.Context, origin Origin) context.Context {
+	return context.WithValue(ctx, originKey{}, origin)
+}
+
+// OriginFromContext возвращает источник изменений из контекста. Без него исполнитель - SystemActor
+func OriginFromContext(ctx context.Context) Origin {
+	origin, _ := ctx.Value(originKey{}).(Origin)
+	if origin.Actor == "" {
+		origin.Actor = SystemActor
+	}
+	return origin
+}
@@ -0,0 +1,160 @@
+// internal/auth/auth.go
+
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// principalKey ключ gin.Context, под которым хранится аутентифицированный клиент
+const principalKey = "auth.principal"
+
+// touchInterval как часто обновлять last_used_at ключа; чаще раза в минуту смысла нет
+const touchInterval = time.Minute
+
+// Principal аутентифицированный клиент запроса: API ключ или пользователь с сессией
+type Principal struct {
+	KeyID   int64  // ID API ключа, 0 - вход по сессии
+	KeyName string // Название ключа для логов
+	UserID  int64  // ID пользователя, 0 - вход по ключу
+	Email   string
+	Role    models.Role // Роль пользователя или ключа
+
+	session *models.Session // Сессия из cookie, nil при входе по ключу
+}
+
+// Owner возвращает владельца для ссылок, создаваемых клиентом. Для анонимного клиента пустой
+func (p *Principal) Owner() models.Owner {
+	var owner models.Owner
+	if p == nil {
+		return owner
+	}
+	if p.KeyID != 0 {
+		id := p.KeyID
+		owner.KeyID = &id
+	}
+	if p.UserID != 0 {
+		id := p.UserID
+		owner.UserID = &id
+	}
+	return owner
+}
+
+// Actor возвращает идентификатор клиента для журнала аудита
+func (p *Principal) Actor() string {
+	switch {
+	case p == nil:
+		return "anonymous"
+	case p.KeyID != 0:
+		return "key:" + strconv.FormatInt(p.KeyID, 10)
+	default:
+		return "user:" + strconv.FormatInt(p.UserID, 10)
+	}
+}
+
+// FromContext возвращает клиента, прошедшего аутентификацию. nil - анонимный запрос
+func FromContext(c *gin.Context) *Principal {
+	if value, ok := c.Get(principalKey); ok {
+		return value.(*Principal)
+	}
+	return nil
+}
+
+// Authenticator проверяет API ключи из заголовка Authorization: Bearer
+// и, если подключены сессии, cookie сессии веб-интерфейса
+type Authenticator struct {
+	keys     storage.APIKeyStorage
+	users    storage.UserStorage // nil - сессии отключены
+	sessions SessionOptions
+	now      func() time.Time
+}
+
+// Option настраивает Authenticator
+type Option func(*Authenticator)
+
+// WithSessions включает вход по cookie сессии для пользователей веб-интерфейса
+func WithSessions(users storage.UserStorage, opts SessionOptions) Option {
+	return func(a *Authenticator) {
+		a.users = users
+		a.sessions = opts
+	}
+}
+
+func New(keys storage.APIKeyStorage, opts ...Option) *Authenticator {
+	a := &Authenticator{keys: keys, now: time.Now}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.sessions.TTL <= 0 {
+		a.sessions.TTL = 7 * 24 * time.Hour
+	}
+	return a
+}
+
+// Middleware аутентифицирует запрос по ключу или cookie сессии. Ключ имеет приоритет;
+// неверный или отозванный ключ - 401. Недействительная сессия не ошибка: запрос
+// продолжается как анонимный. Изменяющие запросы сессии проверяются на CSRF токен
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			if a.users != nil && !a.authenticateSession(c) {
+				return
+			}
+			c.Next()
+			return
+		}
+
+		scheme, secret, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || !IsKeyFormat(strings.TrimSpace(secret)) {
+			unauthorized(c, "Invalid authorization header")
+			return
+		}
+
+		key, err := a.keys.GetAPIKeyByHash(c.Request.Context(), HashKey(strings.TrimSpace(secret)))
+		if errors.Is(err, storage.ErrNotFound) || (err == nil && key.IsRevoked()) {
+			unauthorized(c, "Invalid API key")
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to look up API key")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		now := a.now()
+		if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= touchInterval {
+			if err := a.keys.TouchAPIKey(c.Request.Context(), key.ID, now); err != nil {
+				log.Warn().Err(err).Int64("key_id", key.ID).Msg("Failed to update API key usage")
+			}
+		}
+
+		c.Set(principalKey, &Principal{KeyID: key.ID, KeyName: key.Name, Role: key.Role})
+		c.Next()
+	}
+}
+
+// RequireAuth отклоняет анонимные запросы. Ставится после Authenticator.Middleware
+func RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if FromContext(c) == nil {
+			unauthorized(c, "Authentication required")
+			return
+		}
+		c.Next()
+	}
+}
+
+func unauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="url-shortener"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
+}
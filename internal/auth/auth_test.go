@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRouter(keys storage.APIKeyStorage) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(New(keys).Middleware())
+	router.GET("/whoami", func(c *gin.Context) {
+		principal := FromContext(c)
+		if principal == nil {
+			c.String(http.StatusOK, "anonymous")
+			return
+		}
+		c.String(http.StatusOK, principal.KeyName)
+	})
+	router.GET("/private", RequireAuth(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return router
+}
+
+func request(router *gin.Engine, path, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreateKey(t *testing.T) {
+	keys := storage.NewMockStorage()
+
+	key, secret, err := CreateKey(context.Background(), keys, "ci", models.RoleEditor)
+	require.NoError(t, err)
+	assert.True(t, IsKeyFormat(secret))
+	assert.Equal(t, secret[:keyPrefixLength], key.Prefix)
+	assert.NotContains(t, key.KeyHash, secret)
+
+	// В хранилище попадает только хэш
+	stored, err := keys.GetAPIKeyByHash(context.Background(), HashKey(secret))
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, stored.ID)
+}
+
+func TestMiddleware(t *testing.T) {
+	keys := storage.NewMockStorage()
+	key, secret, err := CreateKey(context.Background(), keys, "ci", models.RoleEditor)
+	require.NoError(t, err)
+	router := newRouter(keys)
+
+	t.Run("Anonymous request", func(t *testing.T) {
+		w := request(router, "/whoami", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "anonymous", w.Body.String())
+	})
+
+	t.Run("Valid key", func(t *testing.T) {
+		w := request(router, "/whoami", "Bearer "+secret)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "ci", w.Body.String())
+
+		stored, err := keys.GetAPIKeyByHash(context.Background(), key.KeyHash)
+		require.NoError(t, err)
+		assert.NotNil(t, stored.LastUsedAt)
+	})
+
+	t.Run("Malformed header", func(t *testing.T) {
+		w := request(router, "/whoami", "Basic dXNlcjpwYXNz")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("Unknown key", func(t *testing.T) {
+		w := request(router, "/whoami", "Bearer "+keyScheme+strings.Repeat("x", keySecretLength))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Key required", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, request(router, "/private", "").Code)
+		assert.Equal(t, http.StatusNoContent, request(router, "/private", "Bearer "+secret).Code)
+	})
+
+	t.Run("Revoked key", func(t *testing.T) {
+		require.NoError(t, keys.RevokeAPIKey(context.Background(), key.ID))
+		w := request(router, "/whoami", "Bearer "+secret)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
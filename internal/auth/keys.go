@@ -0,0 +1,49 @@
+// internal/auth/keys.go
+
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/drerr0r/url-shortener/internal/utils"
+)
+
+// Формат ключа: usk_ и 40 символов base62 (около 238 бит случайности)
+const (
+	keyScheme       = "usk_"
+	keySecretLength = 40
+	keyPrefixLength = len(keyScheme) + 8
+)
+
+// HashKey возвращает SHA-256 хэш ключа в hex. У ключа достаточно энтропии,
+// поэтому медленный хэш вроде bcrypt не нужен, и ключ можно искать по хэшу индексом
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsKeyFormat проверяет, похожа ли строка на API ключ, чтобы не ходить в базу за мусором
+func IsKeyFormat(key string) bool {
+	return len(key) == len(keyScheme)+keySecretLength && strings.HasPrefix(key, keyScheme)
+}
+
+// CreateKey генерирует ключ с заданной ролью, сохраняет его хэш и возвращает ключ в открытом виде.
+// Открытый ключ больше нигде не хранится и показывается только один раз
+func CreateKey(ctx context.Context, keys storage.APIKeyStorage, name string, role models.Role) (*models.APIKey, string, error) {
+	secret := keyScheme + utils.GenerateRandomString(keySecretLength)
+	key := &models.APIKey{
+		Name:    name,
+		Prefix:  secret[:keyPrefixLength],
+		KeyHash: HashKey(secret),
+		Role:    role,
+	}
+	if err := keys.CreateAPIKey(ctx, key); err != nil {
+		return nil, "", err
+	}
+	return key, secret, nil
+}
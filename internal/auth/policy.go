@@ -0,0 +1,80 @@
+// internal/auth/policy.go
+
+package auth
+
+import (
+	"net/http"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Action действие, право на которое проверяет политика доступа
+type Action string
+
+// Действия с ссылками. Административные действия относятся к любым ссылкам, а не только к своим
+const (
+	ActionCreateLink   Action = "link.create"
+	ActionViewStats    Action = "link.stats"
+	ActionDeleteLink   Action = "link.delete"
+	ActionAdminList    Action = "admin.list"
+	ActionAdminView    Action = "admin.view"
+	ActionAdminDelete  Action = "admin.delete"
+	ActionAdminDisable Action = "admin.disable"
+	ActionAdminEnable  Action = "admin.enable"
+	ActionAdminAudit   Action = "admin.audit"
+	ActionAdminDebug   Action = "admin.debug" // Внутренние метрики /debug/vars
+)
+
+// rule минимальная роль для действия и доступно ли оно анонимному клиенту
+type rule struct {
+	role      models.Role
+	anonymous bool
+}
+
+// policy правила доступа. Действие, которого нет в таблице, запрещено всем
+var policy = map[Action]rule{
+	ActionCreateLink:   {role: models.RoleEditor, anonymous: true},
+	ActionViewStats:    {role: models.RoleViewer, anonymous: true},
+	ActionDeleteLink:   {role: models.RoleEditor},
+	ActionAdminList:    {role: models.RoleAdmin},
+	ActionAdminView:    {role: models.RoleAdmin},
+	ActionAdminDelete:  {role: models.RoleAdmin},
+	ActionAdminDisable: {role: models.RoleAdmin},
+	ActionAdminEnable:  {role: models.RoleAdmin},
+	ActionAdminAudit:   {role: models.RoleAdmin},
+	ActionAdminDebug:   {role: models.RoleAdmin},
+}
+
+// Allowed сообщает, может ли клиент выполнить действие. nil - анонимный клиент
+func Allowed(p *Principal, action Action) bool {
+	r, ok := policy[action]
+	if !ok {
+		return false
+	}
+	if p == nil {
+		return r.anonymous
+	}
+	return p.Role.AtLeast(r.role)
+}
+
+// Authorize пропускает запрос, если политика разрешает клиенту действие.
+// Анонимному клиенту отказ - 401, аутентифицированному с недостаточной ролью - 403
+func Authorize(action Action) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := FromContext(c)
+		if !Allowed(principal, action) {
+			deny(c, principal)
+			return
+		}
+		c.Next()
+	}
+}
+
+func deny(c *gin.Context, principal *Principal) {
+	if principal == nil {
+		unauthorized(c, "Authentication required")
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient role"})
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowed(t *testing.T) {
+	viewer := &Principal{UserID: 1, Role: models.RoleViewer}
+	editor := &Principal{KeyID: 2, Role: models.RoleEditor}
+	admin := &Principal{UserID: 3, Role: models.RoleAdmin}
+	noRole := &Principal{KeyID: 4}
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		action    Action
+		want      bool
+	}{
+		{"Anonymous creates link", nil, ActionCreateLink, true},
+		{"Viewer creates link", viewer, ActionCreateLink, false},
+		{"Editor creates link", editor, ActionCreateLink, true},
+		{"Viewer views stats", viewer, ActionViewStats, true},
+		{"Anonymous deletes link", nil, ActionDeleteLink, false},
+		{"Editor deletes link", editor, ActionDeleteLink, true},
+		{"Editor lists all links", editor, ActionAdminList, false},
+		{"Admin lists all links", admin, ActionAdminList, true},
+		{"Admin disables link", admin, ActionAdminDisable, true},
+		{"Anonymous disables link", nil, ActionAdminDisable, false},
+		{"Anonymous reads debug vars", nil, ActionAdminDebug, false},
+		{"Admin reads debug vars", admin, ActionAdminDebug, true},
+		{"Principal without role", noRole, ActionViewStats, false},
+		{"Unknown action", admin, Action("unknown"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Allowed(tt.principal, tt.action))
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(principal *Principal) int {
+		router := gin.New()
+		router.GET("/admin", func(c *gin.Context) {
+			if principal != nil {
+				c.Set(principalKey, principal)
+			}
+		}, Authorize(ActionAdminList), func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve(nil))
+	assert.Equal(t, http.StatusForbidden, serve(&Principal{UserID: 1, Role: models.RoleEditor}))
+	assert.Equal(t, http.StatusNoContent, serve(&Principal{UserID: 1, Role: models.RoleAdmin}))
+}
@@ -0,0 +1,185 @@
+// internal/auth/sessions.go
+
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/drerr0r/url-shortener/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// Cookie и поля, через которые передаются сессия и CSRF токен
+const (
+	sessionCookie = "session"
+	csrfCookie    = "csrf_token" // Токен для форм, отправляемых до входа (вход, регистрация)
+	csrfField     = "csrf_token"
+	csrfHeader    = "X-CSRF-Token"
+	tokenLength   = 40
+)
+
+// SessionOptions задает параметры cookie сессии
+type SessionOptions struct {
+	TTL    time.Duration // Время жизни сессии
+	Secure bool          // Передавать cookie только по HTTPS
+}
+
+// authenticateSession находит пользователя по cookie сессии. Возвращает false,
+// если запрос прерван: ошибка хранилища или неверный CSRF токен
+func (a *Authenticator) authenticateSession(c *gin.Context) bool {
+	token, err := c.Cookie(sessionCookie)
+	if err != nil || token == "" {
+		return true
+	}
+
+	session, err := a.users.GetSession(c.Request.Context(), HashKey(token))
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && session.IsExpired(a.now())) {
+		a.ClearCookie(c, sessionCookie)
+		return true
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to look up session")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return false
+	}
+
+	user, err := a.users.GetUserByID(c.Request.Context(), session.UserID)
+	if errors.Is(err, storage.ErrNotFound) {
+		a.ClearCookie(c, sessionCookie)
+		return true
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to look up session user")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return false
+	}
+
+	c.Set(principalKey, &Principal{UserID: user.ID, Email: user.Email, Role: user.Role, session: session})
+
+	// Cookie браузер отправляет и с чужих сайтов, поэтому изменяющий запрос
+	// должен доказать, что пришел со страницы сервиса
+	if !isSafeMethod(c.Request.Method) && !checkCSRF(c, session.CSRFToken) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Invalid CSRF token"})
+		return false
+	}
+	return true
+}
+
+// Login создает сессию пользователя и выставляет cookie. Текущая сессия, если она есть,
+// удаляется, чтобы заранее подброшенный идентификатор сессии не стал действительным
+func (a *Authenticator) Login(c *gin.Context, user *models.User) error {
+	if err := a.endSession(c); err != nil {
+		return err
+	}
+
+	token := utils.GenerateRandomString(tokenLength)
+	session := &models.Session{
+		ID:        HashKey(token),
+		UserID:    user.ID,
+		CSRFToken: utils.GenerateRandomString(tokenLength),
+		ExpiresAt: a.now().Add(a.sessions.TTL),
+	}
+	if err := a.users.CreateSession(c.Request.Context(), session); err != nil {
+		return err
+	}
+
+	a.SetCookie(c, sessionCookie, token, a.sessions.TTL)
+	a.ClearCookie(c, csrfCookie)
+	c.Set(principalKey, &Principal{UserID: user.ID, Email: user.Email, Role: user.Role, session: session})
+	return nil
+}
+
+// Logout удаляет текущую сессию и ее cookie
+func (a *Authenticator) Logout(c *gin.Context) error {
+	if err := a.endSession(c); err != nil {
+		return err
+	}
+	a.ClearCookie(c, sessionCookie)
+	return nil
+}
+
+// endSession удаляет сессию текущего запроса из хранилища
+func (a *Authenticator) endSession(c *gin.Context) error {
+	if principal := FromContext(c); principal != nil && principal.session != nil {
+		if err := a.users.DeleteSession(c.Request.Context(), principal.session.ID); err != nil {
+			return err
+		}
+		c.Set(principalKey, (*Principal)(nil))
+	}
+	return nil
+}
+
+// CSRFToken возвращает токен для форм и запросов страницы: токен сессии,
+// а до входа - токен из cookie, который создается при первом обращении
+func (a *Authenticator) CSRFToken(c *gin.Context) string {
+	if principal := FromContext(c); principal != nil && principal.session != nil {
+		return principal.session.CSRFToken
+	}
+	if token, err := c.Cookie(csrfCookie); err == nil && len(token) == tokenLength {
+		return token
+	}
+	token := utils.GenerateRandomString(tokenLength)
+	a.SetCookie(c, csrfCookie, token, 0)
+	return token
+}
+
+// RequireCSRF защищает формы, доступные до входа (вход, регистрация): токен формы
+// должен совпадать с токеном из cookie. Запросы сессии уже проверены в Middleware
+func RequireCSRF() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if principal := FromContext(c); principal != nil && principal.session != nil {
+			c.Next()
+			return
+		}
+		expected, err := c.Cookie(csrfCookie)
+		if err != nil || expected == "" || !checkCSRF(c, expected) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Invalid CSRF token"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// checkCSRF сравнивает токен из заголовка X-CSRF-Token или поля формы с ожидаемым
+func checkCSRF(c *gin.Context, expected string) bool {
+	token := c.GetHeader(csrfHeader)
+	if token == "" {
+		token = c.PostForm(csrfField)
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// SetCookie выставляет cookie, недоступную из JavaScript, с атрибутами cookie сессии. maxAge 0 - до закрытия браузера
+func (a *Authenticator) SetCookie(c *gin.Context, name, value string, maxAge time.Duration) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(maxAge / time.Second),
+		Secure:   a.sessions.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ClearCookie удаляет cookie, выставленную SetCookie
+func (a *Authenticator) ClearCookie(c *gin.Context, name string) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   a.sessions.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
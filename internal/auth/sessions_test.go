@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSessionRouter(t *testing.T) (*gin.Engine, *Authenticator) {
+	mock := storage.NewMockStorage()
+	require.NoError(t, mock.CreateUser(context.Background(), &models.User{Email: "user@example.com", PasswordHash: "hash"}))
+
+	gin.SetMode(gin.TestMode)
+	a := New(mock, WithSessions(mock, SessionOptions{TTL: time.Hour}))
+	router := gin.New()
+	router.Use(a.Middleware())
+	router.GET("/whoami", func(c *gin.Context) {
+		principal := FromContext(c)
+		if principal == nil {
+			c.String(http.StatusOK, "anonymous")
+			return
+		}
+		c.String(http.StatusOK, principal.Email)
+	})
+	router.GET("/csrf", func(c *gin.Context) {
+		c.String(http.StatusOK, a.CSRFToken(c))
+	})
+	router.POST("/login", RequireCSRF(), func(c *gin.Context) {
+		user, err := mock.GetUserByEmail(c.Request.Context(), "user@example.com")
+		require.NoError(t, err)
+		require.NoError(t, a.Login(c, user))
+		c.Status(http.StatusNoContent)
+	})
+	router.POST("/logout", func(c *gin.Context) {
+		require.NoError(t, a.Logout(c))
+		c.Status(http.StatusNoContent)
+	})
+	router.POST("/action", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return router, a
+}
+
+// send выполняет запрос с cookie и необязательным CSRF заголовком
+func send(router *gin.Engine, method, path string, cookies []*http.Cookie, csrf string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	if csrf != "" {
+		req.Header.Set(csrfHeader, csrf)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func cookie(w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestSessions(t *testing.T) {
+	router, a := newSessionRouter(t)
+
+	// Токен для формы входа выдается вместе с cookie
+	w := send(router, http.MethodGet, "/csrf", nil, "")
+	anonymousToken := w.Body.String()
+	csrf := cookie(w, csrfCookie)
+	require.NotNil(t, csrf)
+	assert.Equal(t, anonymousToken, csrf.Value)
+	assert.True(t, csrf.HttpOnly)
+
+	t.Run("Login without CSRF token", func(t *testing.T) {
+		w := send(router, http.MethodPost, "/login", []*http.Cookie{csrf}, "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = send(router, http.MethodPost, "/login", nil, anonymousToken)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	w = send(router, http.MethodPost, "/login", []*http.Cookie{csrf}, anonymousToken)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	session := cookie(w, sessionCookie)
+	require.NotNil(t, session)
+	assert.True(t, session.HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, session.SameSite)
+
+	t.Run("Session principal", func(t *testing.T) {
+		w := send(router, http.MethodGet, "/whoami", []*http.Cookie{session}, "")
+		assert.Equal(t, "user@example.com", w.Body.String())
+	})
+
+	w = send(router, http.MethodGet, "/csrf", []*http.Cookie{session}, "")
+	sessionToken := w.Body.String()
+	assert.NotEqual(t, anonymousToken, sessionToken)
+
+	t.Run("Unsafe request requires CSRF token", func(t *testing.T) {
+		w := send(router, http.MethodPost, "/action", []*http.Cookie{session}, "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = send(router, http.MethodPost, "/action", []*http.Cookie{session}, anonymousToken)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = send(router, http.MethodPost, "/action", []*http.Cookie{session}, sessionToken)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("Anonymous request needs no token", func(t *testing.T) {
+		w := send(router, http.MethodPost, "/action", nil, "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("Unknown session", func(t *testing.T) {
+		forged := &http.Cookie{Name: sessionCookie, Value: strings.Repeat("x", tokenLength)}
+		w := send(router, http.MethodGet, "/whoami", []*http.Cookie{forged}, "")
+		assert.Equal(t, "anonymous", w.Body.String())
+		assert.Equal(t, -1, cookie(w, sessionCookie).MaxAge)
+	})
+
+	t.Run("Expired session", func(t *testing.T) {
+		a.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+		defer func() { a.now = time.Now }()
+
+		w := send(router, http.MethodGet, "/whoami", []*http.Cookie{session}, "")
+		assert.Equal(t, "anonymous", w.Body.String())
+	})
+
+	t.Run("Logout", func(t *testing.T) {
+		w := send(router, http.MethodPost, "/logout", []*http.Cookie{session}, sessionToken)
+		require.Equal(t, http.StatusNoContent, w.Code)
+
+		w = send(router, http.MethodGet, "/whoami", []*http.Cookie{session}, "")
+		assert.Equal(t, "anonymous", w.Body.String())
+	})
+}
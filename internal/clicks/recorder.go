@@ -0,0 +1,204 @@
+// internal/clicks/recorder.go
+
+package clicks
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// Recorder учитывает переходы по коротким ссылкам: увеличивает счетчик
+// и сохраняет событие перехода в журнал кликов
+type Recorder interface {
+	// Record регистрирует один переход по короткой ссылке
+	Record(click *models.Click)
+	// Close завершает работу и сбрасывает накопленные данные в хранилище
+	Close() error
+}
+
+// Stats содержит метрики работы асинхронного регистратора кликов
+type Stats struct {
+	QueueDepth  int   `json:"queue_depth"`  // Количество событий, ожидающих агрегации
+	Recorded    int64 `json:"recorded"`     // Принято событий
+	Dropped     int64 `json:"dropped"`      // Потеряно событий (переполнение очереди или ошибка записи)
+	Flushed     int64 `json:"flushed"`      // Записано событий в хранилище
+	FlushErrors int64 `json:"flush_errors"` // Количество неудачных сбросов батча
+}
+
+// Options задает параметры асинхронного регистратора
+type Options struct {
+	QueueSize     int           // Размер очереди событий
+	BatchSize     int           // Количество событий, после которого батч сбрасывается досрочно
+	FlushInterval time.Duration // Максимальный интервал между сбросами
+}
+
+// SyncRecorder синхронно увеличивает счетчик при каждом переходе.
+// Подходит для тестов с MockStorage и для окружений без нагрузки.
+type SyncRecorder struct {
+	storage storage.Storage
+}
+
+func NewSyncRecorder(storage storage.Storage) *SyncRecorder {
+	return &SyncRecorder{storage: storage}
+}
+
+// Record сразу записывает клик в хранилище
+func (r *SyncRecorder) Record(click *models.Click) {
+	if !click.Counted {
+		if err := r.storage.IncrementClickCount(context.Background(), click.ShortCode); err != nil {
+			log.Error().Err(err).Str("short_code", click.ShortCode).Msg("Failed to increment click count")
+			return
+		}
+	}
+	if err := r.storage.SaveClicks(context.Background(), []*models.Click{click}); err != nil {
+		log.Error().Err(err).Str("short_code", click.ShortCode).Msg("Failed to save click event")
+	}
+}
+
+// Close ничего не делает: синхронный регистратор не буферизует данные
+func (r *SyncRecorder) Close() error {
+	return nil
+}
+
+// BufferedRecorder агрегирует клики в памяти по коротким кодам и периодически
+// сбрасывает счетчики и журнал событий в хранилище батчами, не задерживая редирект
+type BufferedRecorder struct {
+	storage storage.Storage
+	opts    Options
+
+	events chan *models.Click
+	done   chan struct{}
+	exited chan struct{}
+	once   sync.Once
+	closed atomic.Bool
+
+	recorded    atomic.Int64
+	dropped     atomic.Int64
+	flushed     atomic.Int64
+	flushErrors atomic.Int64
+}
+
+// NewBufferedRecorder создает регистратор и запускает фоновую горутину сброса
+func NewBufferedRecorder(storage storage.Storage, opts Options) *BufferedRecorder {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 10000
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+
+	r := &BufferedRecorder{
+		storage: storage,
+		opts:    opts,
+		events:  make(chan *models.Click, opts.QueueSize),
+		done:    make(chan struct{}),
+		exited:  make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// Record ставит клик в очередь. Если очередь переполнена, событие отбрасывается,
+// чтобы редирект никогда не ждал хранилище
+func (r *BufferedRecorder) Record(click *models.Click) {
+	if r.closed.Load() {
+		r.dropped.Add(1)
+		return
+	}
+
+	select {
+	case r.events <- click:
+		r.recorded.Add(1)
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+// Close останавливает прием событий, дожидается сброса очереди и возвращает управление
+func (r *BufferedRecorder) Close() error {
+	r.once.Do(func() {
+		r.closed.Store(true)
+		close(r.done)
+	})
+	<-r.exited
+	return nil
+}
+
+// Stats возвращает текущие метрики регистратора
+func (r *BufferedRecorder) Stats() Stats {
+	return Stats{
+		QueueDepth:  len(r.events),
+		Recorded:    r.recorded.Load(),
+		Dropped:     r.dropped.Load(),
+		Flushed:     r.flushed.Load(),
+		FlushErrors: r.flushErrors.Load(),
+	}
+}
+
+func (r *BufferedRecorder) run() {
+	defer close(r.exited)
+
+	ticker := time.NewTicker(r.opts.FlushInterval)
+	defer ticker.Stop()
+
+	counts := make(map[string]int64)
+	var pending []*models.Click
+
+	add := func(click *models.Click) {
+		// Клики по ссылкам с лимитом уже засчитаны при редиректе, сохраняем только событие
+		if !click.Counted {
+			counts[click.ShortCode]++
+		}
+		pending = append(pending, click)
+	}
+
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		events := int64(len(pending))
+		// Счетчики и журнал пишутся одной транзакцией: при ошибке не сохраняется ни то ни другое,
+		// и все события батча действительно потеряны
+		if err := r.storage.SaveClickBatch(context.Background(), counts, pending); err != nil {
+			r.flushErrors.Add(1)
+			r.dropped.Add(events)
+			log.Error().Err(err).Int64("events", events).Msg("Failed to flush clicks")
+		} else {
+			r.flushed.Add(events)
+		}
+		counts = make(map[string]int64)
+		pending = nil
+	}
+
+	for {
+		select {
+		case click := <-r.events:
+			add(click)
+			if len(pending) >= r.opts.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-r.done:
+			// Дочитываем все, что успело попасть в очередь до закрытия
+			for {
+				select {
+				case click := <-r.events:
+					add(click)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package clicks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingStorage задерживает сброс батча, чтобы можно было переполнить очередь
+type blockingStorage struct {
+	*storage.MockStorage
+	release chan struct{}
+}
+
+func (s *blockingStorage) SaveClickBatch(ctx context.Context, counts map[string]int64, clicks []*models.Click) error {
+	<-s.release
+	return s.MockStorage.SaveClickBatch(context.Background(), counts, clicks)
+}
+
+// failingStorage всегда возвращает ошибку при сбросе батча
+type failingStorage struct {
+	*storage.MockStorage
+}
+
+func (s *failingStorage) SaveClickBatch(ctx context.Context, counts map[string]int64, clicks []*models.Click) error {
+	return errors.New("database unavailable")
+}
+
+func newStorageWithURL(t *testing.T, shortCode string) *storage.MockStorage {
+	mockStorage := storage.NewMockStorage()
+	err := mockStorage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: shortCode})
+	assert.NoError(t, err)
+	return mockStorage
+}
+
+func clickCount(t *testing.T, s storage.Storage, shortCode string) int64 {
+	url, err := s.GetURL(context.Background(), shortCode)
+	assert.NoError(t, err)
+	return url.ClickCount
+}
+
+func TestSyncRecorder(t *testing.T) {
+	mockStorage := newStorageWithURL(t, "abc123")
+	recorder := NewSyncRecorder(mockStorage)
+
+	recorder.Record(&models.Click{ShortCode: "abc123"})
+	recorder.Record(&models.Click{ShortCode: "abc123"})
+	recorder.Record(&models.Click{ShortCode: "unknown"})
+
+	assert.Equal(t, int64(2), clickCount(t, mockStorage, "abc123"))
+	events, err := mockStorage.GetClicksCount(context.Background(), "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, events)
+	assert.NoError(t, recorder.Close())
+}
+
+func TestBufferedRecorder_FlushOnClose(t *testing.T) {
+	mockStorage := newStorageWithURL(t, "abc123")
+	recorder := NewBufferedRecorder(mockStorage, Options{FlushInterval: time.Hour})
+
+	for i := 0; i < 5; i++ {
+		recorder.Record(&models.Click{ShortCode: "abc123"})
+	}
+	assert.NoError(t, recorder.Close())
+
+	assert.Equal(t, int64(5), clickCount(t, mockStorage, "abc123"))
+	events, err := mockStorage.GetClicksCount(context.Background(), "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, events)
+
+	stats := recorder.Stats()
+	assert.Equal(t, int64(5), stats.Recorded)
+	assert.Equal(t, int64(5), stats.Flushed)
+	assert.Equal(t, int64(0), stats.Dropped)
+
+	// После закрытия события не принимаются
+	recorder.Record(&models.Click{ShortCode: "abc123"})
+	assert.Equal(t, int64(1), recorder.Stats().Dropped)
+}
+
+func TestBufferedRecorder_FlushOnBatchSize(t *testing.T) {
+	mockStorage := newStorageWithURL(t, "abc123")
+	recorder := NewBufferedRecorder(mockStorage, Options{BatchSize: 3, FlushInterval: time.Hour})
+	defer recorder.Close()
+
+	for i := 0; i < 3; i++ {
+		recorder.Record(&models.Click{ShortCode: "abc123"})
+	}
+
+	assert.Eventually(t, func() bool {
+		return clickCount(t, mockStorage, "abc123") == 3
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestBufferedRecorder_FlushOnInterval(t *testing.T) {
+	mockStorage := newStorageWithURL(t, "abc123")
+	recorder := NewBufferedRecorder(mockStorage, Options{FlushInterval: 20 * time.Millisecond})
+	defer recorder.Close()
+
+	recorder.Record(&models.Click{ShortCode: "abc123"})
+
+	assert.Eventually(t, func() bool {
+		return clickCount(t, mockStorage, "abc123") == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestBufferedRecorder_DropsWhenQueueIsFull(t *testing.T) {
+	blocking := &blockingStorage{
+		MockStorage: newStorageWithURL(t, "abc123"),
+		release:     make(chan struct{}),
+	}
+	recorder := NewBufferedRecorder(blocking, Options{QueueSize: 1, BatchSize: 1, FlushInterval: time.Hour})
+
+	// Первое событие забирает воркер и зависает на сбросе, второе занимает очередь
+	recorder.Record(&models.Click{ShortCode: "abc123"})
+	assert.Eventually(t, func() bool {
+		return recorder.Stats().QueueDepth == 0
+	}, time.Second, time.Millisecond)
+	recorder.Record(&models.Click{ShortCode: "abc123"})
+	recorder.Record(&models.Click{ShortCode: "abc123"})
+
+	stats := recorder.Stats()
+	assert.Equal(t, 1, stats.QueueDepth)
+	assert.Equal(t, int64(1), stats.Dropped)
+
+	close(blocking.release)
+	assert.NoError(t, recorder.Close())
+	assert.Equal(t, int64(2), clickCount(t, blocking, "abc123"))
+}
+
+func TestBufferedRecorder_FlushError(t *testing.T) {
+	failing := &failingStorage{MockStorage: newStorageWithURL(t, "abc123")}
+	recorder := NewBufferedRecorder(failing, Options{FlushInterval: time.Hour})
+
+	recorder.Record(&models.Click{ShortCode: "abc123"})
+	recorder.Record(&models.Click{ShortCode: "abc123"})
+	assert.NoError(t, recorder.Close())
+
+	stats := recorder.Stats()
+	assert.Equal(t, int64(1), stats.FlushErrors)
+	assert.Equal(t, int64(2), stats.Dropped)
+	assert.Equal(t, int64(0), stats.Flushed)
+
+	// Потерянный батч не оставляет ни счетчиков, ни событий
+	assert.Equal(t, int64(0), clickCount(t, failing, "abc123"))
+	events, err := failing.GetClicksCount(context.Background(), "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, events)
+}
+
+func TestRecorders_SkipCountedClicks(t *testing.T) {
+	mockStorage := newStorageWithURL(t, "abc123")
+	syncRecorder := NewSyncRecorder(mockStorage)
+	buffered := NewBufferedRecorder(mockStorage, Options{FlushInterval: time.Hour})
+
+	syncRecorder.Record(&models.Click{ShortCode: "abc123", Counted: true})
+	buffered.Record(&models.Click{ShortCode: "abc123", Counted: true})
+	buffered.Record(&models.Click{ShortCode: "abc123"})
+	assert.NoError(t, buffered.Close())
+
+	assert.Equal(t, int64(1), clickCount(t, mockStorage, "abc123"))
+	events, err := mockStorage.GetClicksCount(context.Background(), "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, events)
+}
@@ -2,7 +2,11 @@ package config
 
 import (
 	"fmt"
-	"os"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,106 +17,329 @@ type Config struct {
 	ServerWriteTimeout time.Duration `mapstructure:"SERVER_WRITE_TIMEOUT"`
 	ServerIdleTimeout  time.Duration `mapstructure:"SERVER_IDLE_TIMEOUT"`
 
-	DBHost            string        `mapstructure:"DB_HOST"`
-	DBPort            string        `mapstructure:"DB_PORT"`
-	DBName            string        `mapstructure:"DB_NAME"`
-	DBUser            string        `mapstructure:"DB_USER"`
-	DBPassword        string        `mapstructure:"DB_PASSWORD"`
-	DBSSLMode         string        `mapstructure:"DB_SSLMODE"`
-	DBMaxOpenConns    int           `mapstructure:"DB_MAX_OPEN_CONNS"`
-	DBMaxIdleConns    int           `mapstructure:"DB_MAX_IDLE_CONNS"`
-	DBConnMaxLifetime time.Duration `mapstructure:"DB_CONN_MAX_LIFETIME"`
+	// Остановка сервера
+	ServerShutdownTimeout time.Duration `mapstructure:"SERVER_SHUTDOWN_TIMEOUT"` // Сколько ждать завершения текущих запросов
+	ServerDrainDelay      time.Duration `mapstructure:"SERVER_DRAIN_DELAY"`      // Пауза между снятием готовности и закрытием listener
+
+	// Адреса и подсети прокси через запятую, которым доверяется X-Forwarded-For; пусто - никому
+	TrustedProxies string `mapstructure:"TRUSTED_PROXIES"`
+
+	DBHost             string        `mapstructure:"DB_HOST"`
+	DBPort             string        `mapstructure:"DB_PORT"`
+	DBName             string        `mapstructure:"DB_NAME"`
+	DBUser             string        `mapstructure:"DB_USER"`
+	DBPassword         string        `mapstructure:"DB_PASSWORD"`
+	DBSSLMode          string        `mapstructure:"DB_SSLMODE"`
+	DBSSLRootCert      string        `mapstructure:"DB_SSLROOTCERT"`
+	DBSSLCert          string        `mapstructure:"DB_SSLCERT"`
+	DBSSLKey           string        `mapstructure:"DB_SSLKEY"`
+	DBApplicationName  string        `mapstructure:"DB_APPLICATION_NAME"`
+	DBConnectTimeout   time.Duration `mapstructure:"DB_CONNECT_TIMEOUT"`
+	DBStatementTimeout time.Duration `mapstructure:"DB_STATEMENT_TIMEOUT"` // 0 - без ограничения
+	DBMaxOpenConns     int           `mapstructure:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns     int           `mapstructure:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime  time.Duration `mapstructure:"DB_CONN_MAX_LIFETIME"`
+	MigrateOnStart     bool          `mapstructure:"MIGRATE_ON_START"` // Применять миграции при запуске сервера
 
 	AppBaseURL         string `mapstructure:"APP_BASE_URL"`
 	AppShortCodeLength int    `mapstructure:"APP_SHORT_CODE_LENGTH"`
+
+	AppShortCodeMaxLength    int           `mapstructure:"APP_SHORT_CODE_MAX_LENGTH"`
+	AppShortCodeMaxAttempts  int           `mapstructure:"APP_SHORT_CODE_MAX_ATTEMPTS"`
+	AppShortCodeRetryBackoff time.Duration `mapstructure:"APP_SHORT_CODE_RETRY_BACKOFF"`
+	AppShortCodeStrategy     string        `mapstructure:"APP_SHORT_CODE_STRATEGY"`
+	AppShortCodeSequenceKey  int           `mapstructure:"APP_SHORT_CODE_SEQUENCE_KEY"`
+
+	ClickRecorderMode  string        `mapstructure:"CLICK_RECORDER_MODE"`
+	ClickQueueSize     int           `mapstructure:"CLICK_QUEUE_SIZE"`
+	ClickBatchSize     int           `mapstructure:"CLICK_BATCH_SIZE"`
+	ClickFlushInterval time.Duration `mapstructure:"CLICK_FLUSH_INTERVAL"`
+	ClickIPSalt        string        `mapstructure:"CLICK_IP_SALT"`
+
+	JanitorEnabled   bool          `mapstructure:"JANITOR_ENABLED"`
+	JanitorInterval  time.Duration `mapstructure:"JANITOR_INTERVAL"`
+	JanitorMode      string        `mapstructure:"JANITOR_MODE"`
+	JanitorBatchSize int           `mapstructure:"JANITOR_BATCH_SIZE"`
+	AuditRetention   time.Duration `mapstructure:"AUDIT_RETENTION"` // Сколько хранить журнал аудита; 0 - без ограничения
+
+	AuthRequireAPIKey bool `mapstructure:"AUTH_REQUIRE_API_KEY"` // Запрещать анонимное сокращение ссылок
+
+	SessionTTL          time.Duration `mapstructure:"SESSION_TTL"`
+	SessionCookieSecure bool          `mapstructure:"SESSION_COOKIE_SECURE"` // Отдавать cookie сессии только по HTTPS
+	SSOOnly             bool          `mapstructure:"SSO_ONLY"`              // Отключить вход и регистрацию по паролю
+
+	// Вход через корпоративный OIDC провайдер; пустой OIDC_ISSUER - SSO выключен
+	OIDCIssuer       string `mapstructure:"OIDC_ISSUER"`
+	OIDCClientID     string `mapstructure:"OIDC_CLIENT_ID"`
+	OIDCClientSecret string `mapstructure:"OIDC_CLIENT_SECRET"` // Пусто - публичный клиент, защищенный только PKCE
+	OIDCRedirectURL  string `mapstructure:"OIDC_REDIRECT_URL"`  // Пусто - APP_BASE_URL + /login/sso/callback
+	OIDCScopes       string `mapstructure:"OIDC_SCOPES"`
+	OIDCRolesClaim   string `mapstructure:"OIDC_ROLES_CLAIM"`  // Claim со списком групп, вложенный - через точку
+	OIDCRoleMapping  string `mapstructure:"OIDC_ROLE_MAPPING"` // group=role через запятую
+	OIDCDefaultRole  string `mapstructure:"OIDC_DEFAULT_ROLE"` // Роль без подходящей группы; none - вход запрещен
+
+	PasswordMaxAttempts   int           `mapstructure:"PASSWORD_MAX_ATTEMPTS"`
+	PasswordAttemptWindow time.Duration `mapstructure:"PASSWORD_ATTEMPT_WINDOW"`
+
+	RedisHost     string `mapstructure:"REDIS_HOST"`
+	RedisPort     string `mapstructure:"REDIS_PORT"`
+	RedisPassword string `mapstructure:"REDIS_PASSWORD"`
+	RedisDB       int    `mapstructure:"REDIS_DB"`
+
+	CacheEnabled     bool          `mapstructure:"CACHE_ENABLED"`
+	CacheTTL         time.Duration `mapstructure:"CACHE_TTL"`
+	CacheNegativeTTL time.Duration `mapstructure:"CACHE_NEGATIVE_TTL"`
+
+	MemoryCacheEnabled     bool          `mapstructure:"MEMORY_CACHE_ENABLED"`
+	MemoryCacheSize        int           `mapstructure:"MEMORY_CACHE_SIZE"`
+	MemoryCacheTTL         time.Duration `mapstructure:"MEMORY_CACHE_TTL"`
+	MemoryCacheNegativeTTL time.Duration `mapstructure:"MEMORY_CACHE_NEGATIVE_TTL"`
+
+	TracingExporter    string  `mapstructure:"TRACING_EXPORTER"`
+	TracingEndpoint    string  `mapstructure:"TRACING_OTLP_ENDPOINT"`
+	TracingInsecure    bool    `mapstructure:"TRACING_OTLP_INSECURE"`
+	TracingServiceName string  `mapstructure:"TRACING_SERVICE_NAME"`
+	TracingSampleRatio float64 `mapstructure:"TRACING_SAMPLE_RATIO"`
+
+	HealthCacheTTL     time.Duration `mapstructure:"HEALTH_CACHE_TTL"`
+	HealthCheckTimeout time.Duration `mapstructure:"HEALTH_CHECK_TIMEOUT"`
+	HealthDebug        bool          `mapstructure:"HEALTH_DEBUG"`
+
+	// Настройки, которые применяются без перезапуска (SIGHUP или изменение CONFIG_FILE)
+	LogLevel              string        `mapstructure:"LOG_LEVEL"`
+	BlockedDomains        string        `mapstructure:"BLOCKED_DOMAINS"`         // Домены через запятую, поддомены тоже блокируются
+	SettingsWatchInterval time.Duration `mapstructure:"SETTINGS_WATCH_INTERVAL"` // Период проверки CONFIG_FILE, 0 - только по SIGHUP
 }
 
-// LoadConfig загружает конфигурацию из переменных окружения
-func LoadConfig() (*Config, error) {
-	cfg := &Config{
-		ServerPort:         getEnv("SERVER_PORT", "8080"),
-		ServerReadTimeout:  getEnvAsDuration("SERVER_READ_TIMEOUT", 10*time.Second),
-		ServerWriteTimeout: getEnvAsDuration("SERVER_WRITE_TIMEOUT", 10*time.Second),
-		ServerIdleTimeout:  getEnvAsDuration("SERVER_IDLE_TIMEOUT", 60*time.Second),
-
-		DBHost:            getEnv("DB_HOST", "localhost"),
-		DBPort:            getEnv("DB_PORT", "5432"),
-		DBName:            getEnv("DB_NAME", "urlshortener"),
-		DBUser:            getEnv("DB_USER", "postgres"),
-		DBPassword:        getEnv("DB_PASSWORD", "password"),
-		DBSSLMode:         getEnv("DB_SSLMODE", "disable"),
-		DBMaxOpenConns:    getEnvAsInt("DB_MAX_OPEN_CONNS", 25),
-		DBMaxIdleConns:    getEnvAsInt("DB_MAX_IDLE_CONNS", 25),
-		DBConnMaxLifetime: getEnvAsDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
-
-		AppBaseURL:         getEnv("APP_BASE_URL", "http://localhost:8080"),
-		AppShortCodeLength: getEnvAsInt("APP_SHORT_CODE_LENGTH", 6),
-	}
+// Default возвращает конфигурацию по умолчанию, поверх которой накладываются файл, окружение и флаги
+func Default() *Config {
+	return &Config{
+		ServerPort:         "8080",
+		ServerReadTimeout:  10 * time.Second,
+		ServerWriteTimeout: 10 * time.Second,
+		ServerIdleTimeout:  60 * time.Second,
+
+		ServerShutdownTimeout: 20 * time.Second,
+		ServerDrainDelay:      5 * time.Second,
+
+		DBHost:            "localhost",
+		DBPort:            "5432",
+		DBName:            "urlshortener",
+		DBUser:            "postgres",
+		DBPassword:        "password",
+		DBSSLMode:         "disable",
+		DBApplicationName: "url-shortener",
+		DBMaxOpenConns:    25,
+		DBMaxIdleConns:    25,
+		DBConnMaxLifetime: 5 * time.Minute,
+		MigrateOnStart:    true,
+
+		AppBaseURL:         "http://localhost:8080",
+		AppShortCodeLength: 6,
+
+		AppShortCodeMaxLength:    12,
+		AppShortCodeMaxAttempts:  5,
+		AppShortCodeRetryBackoff: 10 * time.Millisecond,
+		AppShortCodeStrategy:     "random",
+
+		ClickRecorderMode:  "async",
+		ClickQueueSize:     10000,
+		ClickBatchSize:     500,
+		ClickFlushInterval: time.Second,
+
+		JanitorEnabled:   true,
+		JanitorInterval:  time.Hour,
+		JanitorMode:      "purge",
+		JanitorBatchSize: 1000,
+		AuditRetention:   90 * 24 * time.Hour,
+
+		SessionTTL:          7 * 24 * time.Hour,
+		SessionCookieSecure: true,
+
+		OIDCScopes:      "openid,email,profile",
+		OIDCRolesClaim:  "groups",
+		OIDCDefaultRole: "viewer",
+
+		PasswordMaxAttempts:   5,
+		PasswordAttemptWindow: 15 * time.Minute,
+
+		RedisHost: "localhost",
+		RedisPort: "6379",
+
+		CacheTTL:         10 * time.Minute,
+		CacheNegativeTTL: 30 * time.Second,
+
+		MemoryCacheEnabled:     true,
+		MemoryCacheSize:        10000,
+		MemoryCacheTTL:         5 * time.Second,
+		MemoryCacheNegativeTTL: 5 * time.Second,
+
+		TracingExporter:    "none",
+		TracingServiceName: "url-shortener",
+		TracingSampleRatio: 1,
+
+		HealthCacheTTL:     time.Second,
+		HealthCheckTimeout: 2 * time.Second,
 
-	if err := validateConfig(cfg); err != nil {
-		return nil, fmt.Errorf("invalid config: %w", err)
+		LogLevel:              "info",
+		SettingsWatchInterval: 5 * time.Second,
 	}
+}
 
-	return cfg, nil
+// LoadConfig загружает конфигурацию из файла CONFIG_FILE (если задан) и переменных окружения
+func LoadConfig() (*Config, error) {
+	return Load(nil)
 }
 
-// GetDSN возвращает строку подключения к PostgreSQL в формате DSN
-func (c *Config) GetDSN() string {
-	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		c.DBHost, c.DBPort, c.DBUser, c.DBPassword, c.DBName, c.DBSSLMode)
+// GetRedisAddr возвращает адрес Redis в формате host:port
+func (c *Config) GetRedisAddr() string {
+	return net.JoinHostPort(c.RedisHost, c.RedisPort)
 }
 
-// getEnv получает значение переменной окружения или возвращает значение по умолчанию
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// validateConfig проверяет валидность конфигурации и возвращает все найденные ошибки сразу
+func validateConfig(cfg *Config) error {
+	var problems []string
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
 	}
-	return defaultValue
-}
+	oneOf := func(value string, allowed ...string) bool {
+		return value == "" || slices.Contains(allowed, value)
+	}
+
+	check(cfg.ServerPort != "", "SERVER_PORT is required")
+	check(cfg.ServerPort == "" || isPort(cfg.ServerPort), "SERVER_PORT must be a port number between 1 and 65535")
+	check(cfg.ServerShutdownTimeout >= 0 && cfg.ServerDrainDelay >= 0, "SERVER_SHUTDOWN_TIMEOUT and SERVER_DRAIN_DELAY must not be negative")
+	// Без соли HMAC от IP перебирается по всему пространству адресов за минуты
+	check(cfg.ClickIPSalt != "", "CLICK_IP_SALT is required")
+	check(isProxyList(cfg.TrustedProxies), "TRUSTED_PROXIES must be a comma-separated list of IP addresses or CIDR subnets")
+
+	check(cfg.DBHost != "", "DB_HOST is required")
+	check(cfg.DBPort == "" || isPort(cfg.DBPort), "DB_PORT must be a port number between 1 and 65535")
+	check(cfg.DBName != "", "DB_NAME is required")
+	check(cfg.DBUser != "", "DB_USER is required")
+	check(oneOf(cfg.DBSSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full"),
+		"DB_SSLMODE must be one of disable, allow, prefer, require, verify-ca, verify-full")
+	// 0 у database/sql означает отсутствие ограничения
+	check(cfg.DBMaxOpenConns >= 0, "DB_MAX_OPEN_CONNS must not be negative")
+	check(cfg.DBMaxIdleConns >= 0, "DB_MAX_IDLE_CONNS must not be negative")
+	check(cfg.DBMaxOpenConns == 0 || cfg.DBMaxIdleConns <= cfg.DBMaxOpenConns, "DB_MAX_IDLE_CONNS must not exceed DB_MAX_OPEN_CONNS")
+
+	// Длина кода в тех же пределах, что и в utils.IsValidShortCode; 0 - значение по умолчанию
+	check(cfg.AppShortCodeLength == 0 || isCodeLength(cfg.AppShortCodeLength), "APP_SHORT_CODE_LENGTH must be between %d and %d", minCodeLength, maxCodeLength)
+	check(cfg.AppShortCodeMaxLength == 0 || isCodeLength(cfg.AppShortCodeMaxLength), "APP_SHORT_CODE_MAX_LENGTH must be between %d and %d", minCodeLength, maxCodeLength)
+	check(cfg.AppShortCodeMaxLength == 0 || cfg.AppShortCodeMaxLength >= cfg.AppShortCodeLength, "APP_SHORT_CODE_MAX_LENGTH must not be less than APP_SHORT_CODE_LENGTH")
+	check(cfg.AppShortCodeMaxAttempts >= 0, "APP_SHORT_CODE_MAX_ATTEMPTS must not be negative")
+	check(oneOf(cfg.AppShortCodeStrategy, "random", "sequential", "hash", "words"), "APP_SHORT_CODE_STRATEGY must be one of random, sequential, hash, words")
+	// Пара слов с цифрой занимает до maxCodeLength символов, меньший предел стратегия words не соблюдет
+	check(cfg.AppShortCodeStrategy != "words" || cfg.AppShortCodeMaxLength == 0 || cfg.AppShortCodeMaxLength == maxCodeLength,
+		"APP_SHORT_CODE_MAX_LENGTH must be %d for the words strategy", maxCodeLength)
 
-// getEnvAsInt получает переменную окружения как integer
-func getEnvAsInt(key string, defaultValue int) int {
-	valueStr := getEnv(key, "")
-	if valueStr == "" {
-		return defaultValue
+	check(oneOf(cfg.ClickRecorderMode, "async", "sync"), "CLICK_RECORDER_MODE must be async or sync")
+	check(cfg.ClickQueueSize >= 0 && cfg.ClickBatchSize >= 0, "CLICK_QUEUE_SIZE and CLICK_BATCH_SIZE must not be negative")
+	check(oneOf(cfg.JanitorMode, "purge", "archive"), "JANITOR_MODE must be purge or archive")
+	check(cfg.JanitorBatchSize >= 0, "JANITOR_BATCH_SIZE must not be negative")
+	check(cfg.AuditRetention >= 0, "AUDIT_RETENTION must not be negative")
+	// Журнал аудита чистит janitor: без него срок хранения молча не соблюдался бы
+	check(cfg.JanitorEnabled || cfg.AuditRetention == 0, "AUDIT_RETENTION requires JANITOR_ENABLED, set AUDIT_RETENTION=0 to keep the audit log without the janitor")
+
+	check(cfg.RedisPort == "" || isPort(cfg.RedisPort), "REDIS_PORT must be a port number between 1 and 65535")
+	check(cfg.RedisDB >= 0, "REDIS_DB must not be negative")
+	check(cfg.MemoryCacheSize >= 0, "MEMORY_CACHE_SIZE must not be negative")
+
+	check(oneOf(cfg.TracingExporter, "none", "stdout", "otlp"), "TRACING_EXPORTER must be one of none, stdout, otlp")
+	check(cfg.TracingSampleRatio >= 0 && cfg.TracingSampleRatio <= 1, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+
+	check(oneOf(cfg.LogLevel, "trace", "debug", "info", "warn", "error"), "LOG_LEVEL must be one of trace, debug, info, warn, error")
+	check(cfg.SettingsWatchInterval >= 0, "SETTINGS_WATCH_INTERVAL must not be negative")
+	check(cfg.SessionTTL >= 0, "SESSION_TTL must not be negative")
+	check(!cfg.SSOOnly || cfg.OIDCIssuer != "", "SSO_ONLY requires OIDC_ISSUER, otherwise nobody can log in")
+	if cfg.OIDCIssuer != "" {
+		check(isHTTPURL(cfg.OIDCIssuer), "OIDC_ISSUER must be an http(s) URL")
+		check(cfg.OIDCClientID != "", "OIDC_CLIENT_ID is required when OIDC_ISSUER is set")
+		check(cfg.OIDCRedirectURL == "" || isHTTPURL(cfg.OIDCRedirectURL), "OIDC_REDIRECT_URL must be an http(s) URL")
+		check(slices.Contains(splitList(cfg.OIDCScopes), "openid"), "OIDC_SCOPES must include openid")
 	}
+	check(oneOf(cfg.OIDCDefaultRole, "viewer", "editor", "admin", "none"), "OIDC_DEFAULT_ROLE must be one of viewer, editor, admin, none")
+	check(isRoleMapping(cfg.OIDCRoleMapping), "OIDC_ROLE_MAPPING must be a comma-separated list of group=viewer|editor|admin")
+	check(cfg.PasswordMaxAttempts >= 0 && cfg.PasswordAttemptWindow >= 0, "PASSWORD_MAX_ATTEMPTS and PASSWORD_ATTEMPT_WINDOW must not be negative")
+	check(cfg.CacheTTL >= 0 && cfg.CacheNegativeTTL >= 0, "CACHE_TTL and CACHE_NEGATIVE_TTL must not be negative")
+	check(cfg.MemoryCacheTTL >= 0 && cfg.MemoryCacheNegativeTTL >= 0, "MEMORY_CACHE_TTL and MEMORY_CACHE_NEGATIVE_TTL must not be negative")
 
-	var value int
-	_, err := fmt.Sscanf(valueStr, "%d", &value)
-	if err != nil {
-		return defaultValue
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
 	}
-	return value
+	return nil
 }
 
-// getEnvAsDuration получает переменную окружения как time.Duration
-func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
-	valueStr := getEnv(key, "")
-	if valueStr == "" {
-		return defaultValue
-	}
+// Пределы длины короткого кода, совпадают с utils.IsValidShortCode
+const (
+	minCodeLength = 4
+	maxCodeLength = 12
+)
 
-	value, err := time.ParseDuration(valueStr)
-	if err != nil {
-		return defaultValue
-	}
-	return value
+func isCodeLength(n int) bool {
+	return n >= minCodeLength && n <= maxCodeLength
 }
 
-// validateConfig проверяет валидность конфигурации
-func validateConfig(cfg *Config) error {
-	if cfg.ServerPort == "" {
-		return fmt.Errorf("SERVER_PORT is required")
+func isHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func isRoleMapping(value string) bool {
+	for _, rule := range splitList(value) {
+		group, role, ok := strings.Cut(rule, "=")
+		if !ok || strings.TrimSpace(group) == "" || !slices.Contains([]string{"viewer", "editor", "admin"}, strings.TrimSpace(role)) {
+			return false
+		}
 	}
-	if cfg.DBHost == "" {
-		return fmt.Errorf("DB_HOST is required")
+	return true
+}
+
+// isProxyList проверяет список IP адресов и подсетей CIDR через запятую
+func isProxyList(value string) bool {
+	for _, item := range splitList(value) {
+		if net.ParseIP(item) == nil {
+			if _, _, err := net.ParseCIDR(item); err != nil {
+				return false
+			}
+		}
 	}
-	if cfg.DBName == "" {
-		return fmt.Errorf("DB_NAME is required")
+	return true
+}
+
+// TrustedProxyList возвращает TRUSTED_PROXIES списком; nil - не доверять ни одному прокси
+func (c *Config) TrustedProxyList() []string {
+	return splitList(c.TrustedProxies)
+}
+
+// splitList разбирает список через запятую, пропуская пустые элементы
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
-	if cfg.DBUser == "" {
-		return fmt.Errorf("DB_USER is required")
+	return items
+}
+
+// OIDCScopeList возвращает OIDC_SCOPES списком
+func (c *Config) OIDCScopeList() []string {
+	return splitList(c.OIDCScopes)
+}
+
+// OIDCCallbackURL возвращает адрес возврата с провайдера: OIDC_REDIRECT_URL или адрес на APP_BASE_URL
+func (c *Config) OIDCCallbackURL() string {
+	if c.OIDCRedirectURL != "" {
+		return c.OIDCRedirectURL
 	}
+	return strings.TrimSuffix(c.AppBaseURL, "/") + "/login/sso/callback"
+}
 
-	return nil
+func isPort(value string) bool {
+	port, err := strconv.Atoi(value)
+	return err == nil && port >= 1 && port <= 65535
 }
@@ -0,0 +1,363 @@
+// internal/config/loader.go
+
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Ключи, которые не соответствуют полям Config
+const (
+	keyConfigFile  = "CONFIG_FILE" // Путь к файлу конфигурации (только окружение и флаг --config)
+	keyDatabaseURL = "DB_URL"      // Строка подключения, раскладывается на поля DB_*
+)
+
+// secretKeys задаются только окружением или файлом: аргументы командной строки видны
+// всем пользователям машины в списке процессов
+var secretKeys = map[string]bool{
+	"DB_PASSWORD":        true,
+	keyDatabaseURL:       true,
+	"REDIS_PASSWORD":     true,
+	"CLICK_IP_SALT":      true,
+	"OIDC_CLIENT_SECRET": true,
+}
+
+// Источники значений в порядке возрастания приоритета
+const (
+	sourceFile = "file"
+	sourceEnv  = "env"
+	sourceFlag = "flag"
+)
+
+// ValidationError перечисляет все некорректные ключи конфигурации
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// layer значения одного источника конфигурации
+type layer struct {
+	source string
+	values map[string]string
+}
+
+// Load собирает конфигурацию по слоям: значения по умолчанию, файл (--config или CONFIG_FILE),
+// переменные окружения, флаги из args. Каждый следующий слой переопределяет предыдущий.
+// Ошибки разбора и проверки всех ключей возвращаются вместе в *ValidationError
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+	fields := fieldsByKey()
+
+	flags, configFile, err := parseFlags(args, fields)
+	if err != nil {
+		return nil, err
+	}
+	if configFile == "" {
+		configFile = os.Getenv(keyConfigFile)
+	}
+
+	var problems []string
+	var layers []layer
+	if configFile != "" {
+		values, err := readFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, layer{source: sourceFile, values: values})
+	}
+	layers = append(layers,
+		layer{source: sourceEnv, values: readEnv(fields)},
+		layer{source: sourceFlag, values: flags},
+	)
+
+	// DB_URL из самого приоритетного слоя задает параметры подключения. Отдельные DB_* ключи
+	// переопределяют его части, только если заданы в том же или более приоритетном слое:
+	// DB_HOST из файла не должен перекрывать DB_URL из окружения
+	var databaseURL layer
+	urlLayer := -1
+	for i, l := range layers {
+		if raw, ok := l.values[keyDatabaseURL]; ok {
+			databaseURL = layer{source: l.source, values: map[string]string{keyDatabaseURL: raw}}
+			urlLayer = i
+		}
+	}
+	if raw := databaseURL.values[keyDatabaseURL]; raw != "" {
+		if err := applyDatabaseURL(cfg, raw); err != nil {
+			problems = append(problems, fmt.Sprintf("%s (%s): %v", keyDatabaseURL, databaseURL.source, err))
+		}
+	}
+
+	for i, l := range layers {
+		for key, raw := range l.values {
+			if key == keyDatabaseURL || (i < urlLayer && databaseURLKeys[key]) {
+				continue
+			}
+			field, ok := fields[key]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s (%s): unknown key", key, l.source))
+				continue
+			}
+			if err := setField(reflect.ValueOf(cfg).Elem().FieldByIndex(field.Index), raw); err != nil {
+				problems = append(problems, fmt.Sprintf("%s (%s): %v", key, l.source, err))
+			}
+		}
+	}
+
+	if err := validateConfig(cfg); err != nil {
+		var validation *ValidationError
+		if errors.As(err, &validation) {
+			problems = append(problems, validation.Problems...)
+		}
+	}
+	if len(problems) > 0 {
+		// Порядок обхода map случаен, сортируем для воспроизводимого вывода
+		slices.Sort(problems)
+		return nil, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// FilePath возвращает путь к файлу конфигурации из --config или CONFIG_FILE.
+// Пустая строка означает, что файл не используется
+func FilePath(args []string) (string, error) {
+	_, configFile, err := parseFlags(args, fieldsByKey())
+	if err != nil {
+		return "", err
+	}
+	if configFile == "" {
+		configFile = os.Getenv(keyConfigFile)
+	}
+	return configFile, nil
+}
+
+// fieldsByKey сопоставляет ключам конфигурации поля Config по тегу mapstructure
+func fieldsByKey() map[string]reflect.StructField {
+	t := reflect.TypeOf(Config{})
+	fields := make(map[string]reflect.StructField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if key := t.Field(i).Tag.Get("mapstructure"); key != "" {
+			fields[key] = t.Field(i)
+		}
+	}
+	return fields
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setField разбирает строковое значение по типу поля
+func setField(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
+
+// formatField возвращает значение поля в виде, который принимает setField
+func formatField(v reflect.Value) string {
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	if v.Kind() == reflect.Float64 {
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// readEnv возвращает заданные переменные окружения. Пустая переменная считается незаданной
+func readEnv(fields map[string]reflect.StructField) map[string]string {
+	values := make(map[string]string)
+	for key := range fields {
+		if value := os.Getenv(key); value != "" {
+			values[key] = value
+		}
+	}
+	if value := os.Getenv(keyDatabaseURL); value != "" {
+		values[keyDatabaseURL] = value
+	}
+	return values
+}
+
+// readFile читает YAML или TOML файл. Ключи совпадают с переменными окружения
+// без учета регистра; вложенные секции склеиваются через "_":
+//
+//	server:
+//	  port: 8080   # SERVER_PORT
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	raw := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q, expected .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	if err := flatten(values, "", raw); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+func flatten(values map[string]string, prefix string, raw map[string]any) error {
+	for name, value := range raw {
+		key := strings.ToUpper(name)
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+
+		switch v := value.(type) {
+		case map[string]any:
+			if err := flatten(values, key, v); err != nil {
+				return err
+			}
+		case string:
+			values[key] = v
+		case bool, int, int64, uint64:
+			values[key] = fmt.Sprint(v)
+		case float64:
+			values[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case nil:
+			values[key] = ""
+		case []any:
+			// Списки (например, blocked_domains) передаются как значения через запятую
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				switch item.(type) {
+				case map[string]any, []any:
+					return fmt.Errorf("%s: nested lists are not supported", key)
+				}
+				items = append(items, fmt.Sprint(item))
+			}
+			values[key] = strings.Join(items, ",")
+		default:
+			return fmt.Errorf("%s: unsupported value of type %T", key, value)
+		}
+	}
+	return nil
+}
+
+// flagName имя флага для ключа: SERVER_PORT -> --server-port
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
+
+// parseFlags разбирает флаги командной строки. Возвращает только явно указанные значения
+// и путь к файлу конфигурации из --config
+func parseFlags(args []string, fields map[string]reflect.StructField) (map[string]string, string, error) {
+	fs := flag.NewFlagSet("url-shortener", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	configFile := fs.String("config", "", "path to YAML or TOML config file")
+	byName := make(map[string]string, len(fields)+1)
+	for _, key := range append(keys(fields), keyDatabaseURL) {
+		name := flagName(key)
+		byName[name] = key
+		fs.String(name, "", key)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, "", fmt.Errorf("invalid flags: %w", err)
+	}
+	if fs.NArg() > 0 {
+		return nil, "", fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	values := make(map[string]string)
+	var secrets []string
+	fs.Visit(func(f *flag.Flag) {
+		key, ok := byName[f.Name]
+		switch {
+		case !ok:
+		case secretKeys[key]:
+			secrets = append(secrets, "--"+f.Name)
+		default:
+			values[key] = f.Value.String()
+		}
+	})
+	if len(secrets) > 0 {
+		return nil, "", fmt.Errorf("secrets cannot be passed as flags, use environment or config file: %s", strings.Join(secrets, ", "))
+	}
+	return values, *configFile, nil
+}
+
+func keys(fields map[string]reflect.StructField) []string {
+	result := make([]string, 0, len(fields))
+	for key := range fields {
+		result = append(result, key)
+	}
+	return result
+}
+
+// Print выводит действующую конфигурацию в формате KEY=value в порядке полей Config.
+// Пароли и соль заменены, поэтому вывод можно прикладывать к обращениям и логам
+func Print(w io.Writer, cfg *Config) error {
+	redactedCfg := cfg.Redacted()
+	v := reflect.ValueOf(redactedCfg)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("mapstructure")
+		if key == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", key, formatField(v.Field(i))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
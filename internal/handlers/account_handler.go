@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/auth"
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/oidc"
+	"github.com/drerr0r/url-shortener/internal/ratelimit"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/drerr0r/url-shortener/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// Ограничения для учетных записей и страницы "Мои ссылки"
+const (
+	minUserPasswordLength = 8
+	linksPageSize         = 50
+)
+
+// dummyPasswordHash хэш, с которым сравнивается пароль при входе под несуществующим email,
+// чтобы время ответа не выдавало, зарегистрирован ли адрес
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := utils.HashPassword("dummy password")
+	if err != nil {
+		panic("failed to hash dummy password: " + err.Error())
+	}
+	return hash
+})
+
+// AccountHandler обслуживает страницы веб-интерфейса: главную, регистрацию, вход, выход и список ссылок пользователя
+type AccountHandler struct {
+	users        storage.UserStorage
+	urls         storage.Storage
+	auth         *auth.Authenticator
+	loginLimiter *ratelimit.AttemptLimiter
+
+	sso           *oidc.Provider // nil - вход через SSO выключен
+	roles         oidc.RoleMapping
+	passwordLogin bool
+}
+
+// AccountOption настраивает дополнительные зависимости AccountHandler
+type AccountOption func(*AccountHandler)
+
+// WithLoginLimiter задает ограничитель неудачных попыток входа.
+// По умолчанию допускается 5 ошибок за 15 минут для пары email + IP и 50 для email
+func WithLoginLimiter(limiter *ratelimit.AttemptLimiter) AccountOption {
+	return func(h *AccountHandler) {
+		h.loginLimiter = limiter
+	}
+}
+
+// WithSSO включает вход через OIDC провайдер; роль пользователя определяется по группам из ID токена
+func WithSSO(provider *oidc.Provider, roles oidc.RoleMapping) AccountOption {
+	return func(h *AccountHandler) {
+		h.sso = provider
+		h.roles = roles
+	}
+}
+
+// WithPasswordLogin включает или выключает вход и регистрацию по паролю. По умолчанию включены
+func WithPasswordLogin(enabled bool) AccountOption {
+	return func(h *AccountHandler) {
+		h.passwordLogin = enabled
+	}
+}
+
+func NewAccountHandler(users storage.UserStorage, urls storage.Storage, authenticator *auth.Authenticator, opts ...AccountOption) *AccountHandler {
+	h := &AccountHandler{users: users, urls: urls, auth: authenticator, passwordLogin: true}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.loginLimiter == nil {
+		h.loginLimiter = ratelimit.NewAttemptLimiter(5, 15*time.Minute)
+	}
+	return h
+}
+
+// HomeHandler показывает форму сокращения ссылок
+func (h *AccountHandler) HomeHandler(c *gin.Context) {
+	c.HTML(http.StatusOK, "index.html", h.page(c, gin.H{}))
+}
+
+// SignupPageHandler показывает форму регистрации
+func (h *AccountHandler) SignupPageHandler(c *gin.Context) {
+	if auth.FromContext(c) != nil {
+		c.Redirect(http.StatusSeeOther, "/my/links")
+		return
+	}
+	if !h.passwordLogin {
+		c.Redirect(http.StatusSeeOther, "/login")
+		return
+	}
+	h.renderAccountForm(c, http.StatusOK, true, "", "")
+}
+
+// SignupHandler регистрирует пользователя и сразу выполняет вход
+func (h *AccountHandler) SignupHandler(c *gin.Context) {
+	if !h.passwordLogin {
+		h.renderAccountForm(c, http.StatusForbidden, false, "", "Регистрация по паролю отключена, войдите через SSO")
+		return
+	}
+
+	email := normalizeEmail(c.PostForm("email"))
+	password := c.PostForm("password")
+
+	if email == "" {
+		h.renderAccountForm(c, http.StatusBadRequest, true, c.PostForm("email"), "Некорректный email")
+		return
+	}
+	if len(password) < minUserPasswordLength || len(password) > utils.MaxPasswordLength {
+		h.renderAccountForm(c, http.StatusBadRequest, true, email, "Пароль должен быть от 8 до 72 символов")
+		return
+	}
+
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to hash password")
+		h.renderAccountForm(c, http.StatusInternalServerError, true, email, "Внутренняя ошибка, попробуйте позже")
+		return
+	}
+
+	user := &models.User{Email: email, PasswordHash: hash}
+	if err := h.users.CreateUser(c.Request.Context(), user); err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			h.renderAccountForm(c, http.StatusConflict, true, email, "Пользователь с таким email уже зарегистрирован")
+			return
+		}
+		log.Error().Err(err).Msg("Failed to create user")
+		h.renderAccountForm(c, http.StatusInternalServerError, true, email, "Внутренняя ошибка, попробуйте позже")
+		return
+	}
+
+	h.login(c, user)
+}
+
+// LoginPageHandler показывает форму входа
+func (h *AccountHandler) LoginPageHandler(c *gin.Context) {
+	if auth.FromContext(c) != nil {
+		c.Redirect(http.StatusSeeOther, "/my/links")
+		return
+	}
+	h.renderAccountForm(c, http.StatusOK, false, "", "")
+}
+
+// LoginHandler проверяет email и пароль. Неудачные попытки ограничиваются для пары email + IP
+func (h *AccountHandler) LoginHandler(c *gin.Context) {
+	if !h.passwordLogin {
+		h.renderAccountForm(c, http.StatusForbidden, false, "", "Вход по паролю отключен, войдите через SSO")
+		return
+	}
+
+	email := normalizeEmail(c.PostForm("email"))
+	password := c.PostForm("password")
+
+	// Общий лимит на email не зависит от IP: перебор пароля с разных адресов тоже упирается в него
+	target, client := "login|"+email, c.ClientIP()
+	if allowed, retryAfter := h.loginLimiter.Allow(target, client); !allowed {
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		h.renderAccountForm(c, http.StatusTooManyRequests, false, email, "Слишком много попыток, попробуйте позже")
+		return
+	}
+
+	user, err := h.users.GetUserByEmail(c.Request.Context(), email)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Error().Err(err).Msg("Failed to look up user")
+		h.renderAccountForm(c, http.StatusInternalServerError, false, email, "Внутренняя ошибка, попробуйте позже")
+		return
+	}
+
+	passwordHash := dummyPasswordHash()
+	if user != nil {
+		passwordHash = user.PasswordHash
+	}
+	if !utils.CheckPassword(passwordHash, password) || user == nil {
+		h.loginLimiter.Fail(target, client)
+		h.renderAccountForm(c, http.StatusUnauthorized, false, email, "Неверный email или пароль")
+		return
+	}
+
+	h.loginLimiter.Reset(target, client)
+	h.login(c, user)
+}
+
+// LogoutHandler завершает сессию
+func (h *AccountHandler) LogoutHandler(c *gin.Context) {
+	if err := h.auth.Logout(c); err != nil {
+		log.Error().Err(err).Msg("Failed to delete session")
+	}
+	c.Redirect(http.StatusSeeOther, "/")
+}
+
+// MyLinksHandler показывает постраничный список ссылок пользователя
+func (h *AccountHandler) MyLinksHandler(c *gin.Context) {
+	principal := auth.FromContext(c)
+	if principal == nil || principal.UserID == 0 {
+		c.Redirect(http.StatusSeeOther, "/login")
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	owner := principal.Owner()
+	total, err := h.urls.GetURLsCountByOwner(c.Request.Context(), owner)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to count user links")
+		c.String(http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	links, err := h.urls.GetURLsByOwner(c.Request.Context(), owner, linksPageSize, (page-1)*linksPageSize)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get user links")
+		c.String(http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.HTML(http.StatusOK, "links.html", h.page(c, gin.H{
+		"Links":    links,
+		"Total":    total,
+		"Page":     page,
+		"PrevPage": page - 1,
+		"NextPage": page + 1,
+		"HasNext":  page*linksPageSize < total,
+	}))
+}
+
+// login создает сессию и переводит пользователя к его ссылкам
+func (h *AccountHandler) login(c *gin.Context, user *models.User) {
+	if err := h.auth.Login(c, user); err != nil {
+		log.Error().Err(err).Msg("Failed to create session")
+		h.renderAccountForm(c, http.StatusInternalServerError, false, user.Email, "Внутренняя ошибка, попробуйте позже")
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/my/links")
+}
+
+func (h *AccountHandler) renderAccountForm(c *gin.Context, status int, signup bool, email, message string) {
+	c.Header("Cache-Control", "no-store")
+	c.HTML(status, "account.html", h.page(c, gin.H{
+		"Signup": signup,
+		"Email":  email,
+		"Error":  message,
+	}))
+}
+
+// page дополняет данные шаблона текущим пользователем, CSRF токеном для форм и доступными способами входа
+func (h *AccountHandler) page(c *gin.Context, data gin.H) gin.H {
+	data["SSO"] = h.sso != nil
+	data["PasswordLogin"] = h.passwordLogin
+	if principal := auth.FromContext(c); principal != nil && principal.UserID != 0 {
+		data["User"] = principal.Email
+	}
+	data["CSRFToken"] = h.auth.CSRFToken(c)
+	return data
+}
+
+// normalizeEmail приводит email к нижнему регистру. Некорректный адрес - пустая строка
+func normalizeEmail(raw string) string {
+	address, err := mail.ParseAddress(strings.TrimSpace(raw))
+	if err != nil || address.Name != "" {
+		return ""
+	}
+	return strings.ToLower(address.Address)
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/auth"
+	"github.com/drerr0r/url-shortener/internal/ratelimit"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+var csrfPattern = regexp.MustCompile(`(?:name="csrf_token" value|name="csrf-token" content)="([^"]+)"`)
+
+// browser хранит cookie между запросами, как это делает браузер
+type browser struct {
+	t       *testing.T
+	router  *gin.Engine
+	cookies map[string]*http.Cookie
+}
+
+func (b *browser) do(req *http.Request) *httptest.ResponseRecorder {
+	for _, cookie := range b.cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	b.router.ServeHTTP(w, req)
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.MaxAge < 0 {
+			delete(b.cookies, cookie.Name)
+		} else {
+			b.cookies[cookie.Name] = cookie
+		}
+	}
+	return w
+}
+
+func (b *browser) get(path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	return b.do(req)
+}
+
+func (b *browser) post(path string, form url.Values) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return b.do(req)
+}
+
+// csrfToken открывает страницу и достает из нее CSRF токен
+func (b *browser) csrfToken(path string) string {
+	b.t.Helper()
+	match := csrfPattern.FindStringSubmatch(b.get(path).Body.String())
+	if match == nil {
+		b.t.Fatalf("CSRF token not found on %s", path)
+	}
+	return match[1]
+}
+
+func (b *browser) shorten(target string) *httptest.ResponseRecorder {
+	token := b.csrfToken("/")
+	req, _ := http.NewRequest("POST", "/api/v1/shorten", strings.NewReader(`{"url": "`+target+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-CSRF-Token", token)
+	return b.do(req)
+}
+
+func newAccountRouter(mockStorage *storage.MockStorage, opts ...AccountOption) *gin.Engine {
+	authenticator := auth.New(mockStorage, auth.WithSessions(mockStorage, auth.SessionOptions{}))
+	urlHandler := NewURLHandler(mockStorage)
+	accountHandler := NewAccountHandler(mockStorage, mockStorage, authenticator, opts...)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.LoadHTMLGlob("../../templates/*")
+	web := router.Group("/", authenticator.Middleware())
+	web.GET("/", accountHandler.HomeHandler)
+	web.GET("/signup", accountHandler.SignupPageHandler)
+	web.POST("/signup", auth.RequireCSRF(), accountHandler.SignupHandler)
+	web.GET("/login", accountHandler.LoginPageHandler)
+	web.POST("/login", auth.RequireCSRF(), accountHandler.LoginHandler)
+	web.POST("/logout", accountHandler.LogoutHandler)
+	web.GET("/my/links", accountHandler.MyLinksHandler)
+	web.GET("/login/sso", accountHandler.SSOLoginHandler)
+	web.GET("/login/sso/callback", accountHandler.SSOCallbackHandler)
+	router.POST("/api/v1/shorten", authenticator.Middleware(), urlHandler.ShortenURLHandler)
+	return router
+}
+
+// TestAccountFlow проверяет регистрацию, вход, выход и страницу "Мои ссылки"
+func TestAccountFlow(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	router := newAccountRouter(mockStorage)
+	user := &browser{t: t, router: router, cookies: map[string]*http.Cookie{}}
+	stranger := &browser{t: t, router: router, cookies: map[string]*http.Cookie{}}
+
+	form := url.Values{"email": {"User@Example.com"}, "password": {"correct horse"}}
+
+	// Форма без CSRF токена отклоняется
+	if w := user.post("/signup", form); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 without CSRF token, got %d", w.Code)
+	}
+
+	form.Set("csrf_token", user.csrfToken("/signup"))
+	short := url.Values{"email": {"user@example.com"}, "password": {"short"}, "csrf_token": form["csrf_token"]}
+	if w := user.post("/signup", short); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a short password, got %d", w.Code)
+	}
+
+	w := user.post("/signup", form)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/my/links" {
+		t.Fatalf("Expected redirect to /my/links, got %d %q. Body: %s", w.Code, w.Header().Get("Location"), w.Body.String())
+	}
+	if stored, err := mockStorage.GetUserByEmail(t.Context(), "user@example.com"); err != nil || stored.PasswordHash == "correct horse" {
+		t.Errorf("Expected a user with a hashed password, got %+v, %v", stored, err)
+	}
+
+	if w := user.get("/my/links"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "user@example.com") {
+		t.Errorf("Expected links page for the user, got %d", w.Code)
+	}
+
+	w = user.shorten("https://example.com/mine")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var response ShortenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if w := stranger.shorten("https://example.com/theirs"); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	// Запрос сессии к API без токена - подделка с чужого сайта
+	req, _ := http.NewRequest("POST", "/api/v1/shorten", strings.NewReader(`{"url": "https://example.com/forged"}`))
+	req.Header.Set("Content-Type", "application/json")
+	if w := user.do(req); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for session request without CSRF token, got %d", w.Code)
+	}
+
+	body := user.get("/my/links").Body.String()
+	if !strings.Contains(body, "/"+response.ShortURL) || !strings.Contains(body, "https://example.com/mine") {
+		t.Errorf("Expected the user's link on the page, got: %s", body)
+	}
+	if strings.Contains(body, "https://example.com/theirs") || strings.Contains(body, "forged") {
+		t.Errorf("Expected only the user's links on the page, got: %s", body)
+	}
+
+	// Повторная регистрация с тем же email
+	other := &browser{t: t, router: router, cookies: map[string]*http.Cookie{}}
+	form.Set("csrf_token", other.csrfToken("/signup"))
+	if w := other.post("/signup", form); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a taken email, got %d", w.Code)
+	}
+
+	w = user.post("/logout", url.Values{"csrf_token": {user.csrfToken("/my/links")}})
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("Expected redirect after logout, got %d", w.Code)
+	}
+	if w := user.get("/my/links"); w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login" {
+		t.Errorf("Expected redirect to /login after logout, got %d", w.Code)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     int
+	}{
+		{"Wrong password", "wrong password", http.StatusUnauthorized},
+		{"Correct password", "correct horse", http.StatusSeeOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			login := url.Values{
+				"email":      {"user@example.com"},
+				"password":   {tt.password},
+				"csrf_token": {user.csrfToken("/login")},
+			}
+			if w := user.post("/login", login); w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d. Body: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if w := user.get("/my/links"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), response.ShortURL) {
+		t.Errorf("Expected links page after login, got %d", w.Code)
+	}
+}
+
+// TestLoginSpoofedForwardedFor проверяет, что перебор пароля с подменой X-Forwarded-For
+// упирается в общий лимит ошибок для email
+func TestLoginSpoofedForwardedFor(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	router := newAccountRouter(mockStorage, WithLoginLimiter(ratelimit.NewAttemptLimiter(2, time.Minute)))
+	user := &browser{t: t, router: router, cookies: map[string]*http.Cookie{}}
+
+	form := url.Values{"email": {"user@example.com"}, "password": {"correct horse"}, "csrf_token": {user.csrfToken("/signup")}}
+	if w := user.post("/signup", form); w.Code != http.StatusSeeOther {
+		t.Fatalf("Expected redirect after signup, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	login := func(password string, i int) *httptest.ResponseRecorder {
+		attacker := &browser{t: t, router: router, cookies: map[string]*http.Cookie{}}
+		form := url.Values{"email": {"user@example.com"}, "password": {password}, "csrf_token": {attacker.csrfToken("/login")}}
+		req, _ := http.NewRequest("POST", "/login", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i))
+		req.RemoteAddr = "192.0.2.1:12345"
+		return attacker.do(req)
+	}
+
+	for i := 0; i < 2*ratelimit.TargetFactor; i++ {
+		if w := login("wrong password", i); w.Code != http.StatusUnauthorized {
+			t.Fatalf("Attempt %d: expected status 401, got %d", i+1, w.Code)
+		}
+	}
+	if w := login("correct horse", 250); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 for the email after failures from many addresses, got %d", w.Code)
+	}
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/audit"
+	"github.com/drerr0r/url-shortener/internal/auth"
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/drerr0r/url-shortener/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// Размер страницы списка ссылок в API администратора
+const (
+	defaultAdminLimit = 50
+	maxAdminLimit     = 500
+)
+
+// AdminHandler обслуживает API администратора: просмотр, поиск, удаление и отключение любых ссылок
+// и журнал аудита их изменений
+type AdminHandler struct {
+	storage storage.Storage
+	events  storage.AuditStorage
+	audit   audit.Recorder
+}
+
+// NewAdminHandler создает обработчик. Без recorder обращения к API администратора пишутся в лог
+func NewAdminHandler(storage storage.Storage, events storage.AuditStorage, recorder audit.Recorder) *AdminHandler {
+	if recorder == nil {
+		recorder = audit.NewLogRecorder()
+	}
+	return &AdminHandler{storage: storage, events: events, audit: recorder}
+}
+
+// AdminURLsResponse страница списка ссылок
+type AdminURLsResponse struct {
+	URLs   []*models.URL `json:"urls"`
+	Total  int           `json:"total"`
+	Limit  int           `json:"limit"`
+	Offset int           `json:"offset"`
+}
+
+// AuditEventsResponse страница журнала аудита
+type AuditEventsResponse struct {
+	Events []*models.AuditEvent `json:"events"`
+	Total  int                  `json:"total"`
+	Limit  int                  `json:"limit"`
+	Offset int                  `json:"offset"`
+}
+
+// Guard проверяет право на действие по политике доступа и записывает в журнал аудита
+// и выполненное действие, и отказ. Ставится перед обработчиком маршрута
+func (h *AdminHandler) Guard(action auth.Action) gin.HandlerFunc {
+	authorize := auth.Authorize(action)
+	return func(c *gin.Context) {
+		// При успешной проверке authorize сам вызывает остальные обработчики цепочки
+		authorize(c)
+
+		principal := auth.FromContext(c)
+		event := audit.Event{
+			Time:      time.Now(),
+			Actor:     principal.Actor(),
+			Action:    string(action),
+			ShortCode: c.Param("shortCode"),
+			ClientIP:  c.ClientIP(),
+			RequestID: audit.OriginFromContext(c.Request.Context()).RequestID,
+			Status:    c.Writer.Status(),
+		}
+		if principal != nil {
+			event.ActorRole = string(principal.Role)
+		}
+		h.audit.Record(c.Request.Context(), event)
+	}
+}
+
+// ListURLsHandler возвращает постраничный список всех ссылок; с параметром q - только найденные
+// по короткому коду или исходному URL
+func (h *AdminHandler) ListURLsHandler(c *gin.Context) {
+	limit, offset, ok := parsePagination(c, defaultAdminLimit, maxAdminLimit)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+
+	var (
+		urls  []*models.URL
+		total int
+		err   error
+	)
+	ctx := c.Request.Context()
+	if query := strings.TrimSpace(c.Query("q")); query != "" {
+		if total, err = h.storage.SearchURLsCount(ctx, query); err == nil {
+			urls, err = h.storage.SearchURLs(ctx, query, limit, offset)
+		}
+	} else {
+		if total, err = h.storage.GetURLsCount(ctx); err == nil {
+			urls, err = h.storage.GetURLs(ctx, limit, offset)
+		}
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list URLs")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if urls == nil {
+		urls = []*models.URL{}
+	}
+
+	c.JSON(http.StatusOK, AdminURLsResponse{URLs: urls, Total: total, Limit: limit, Offset: offset})
+}
+
+// GetURLHandler возвращает ссылку вместе с адресом назначения, даже если она защищена паролем
+func (h *AdminHandler) GetURLHandler(c *gin.Context) {
+	url, ok := h.loadURL(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, url)
+}
+
+// DeleteURLHandler удаляет любую ссылку вместе с журналом переходов
+func (h *AdminHandler) DeleteURLHandler(c *gin.Context) {
+	url, ok := h.loadURL(c)
+	if !ok {
+		return
+	}
+
+	if err := h.storage.DeleteURL(c.Request.Context(), url.ShortCode); err != nil {
+		log.Error().Err(err).Str("short_code", url.ShortCode).Msg("Failed to delete URL")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// DisableURLHandler отключает ссылку: переход по ней возвращает 410, данные сохраняются
+func (h *AdminHandler) DisableURLHandler(c *gin.Context) {
+	h.setDisabled(c, true)
+}
+
+// EnableURLHandler снова включает отключенную ссылку
+func (h *AdminHandler) EnableURLHandler(c *gin.Context) {
+	h.setDisabled(c, false)
+}
+
+func (h *AdminHandler) setDisabled(c *gin.Context, disabled bool) {
+	shortCode := c.Param("shortCode")
+	if !utils.IsValidShortCode(shortCode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid short code format"})
+		return
+	}
+
+	if err := h.storage.SetURLDisabled(c.Request.Context(), shortCode, disabled); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
+			return
+		}
+		log.Error().Err(err).Str("short_code", shortCode).Msg("Failed to change URL state")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	url, ok := h.loadURL(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, url)
+}
+
+// AuditEventsHandler возвращает журнал изменений ссылок, новые события первыми.
+// Параметры short_code и actor отбирают события одной ссылки или одного исполнителя
+func (h *AdminHandler) AuditEventsHandler(c *gin.Context) {
+	limit, offset, ok := parsePagination(c, defaultAdminLimit, maxAdminLimit)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+
+	filter := models.AuditFilter{ShortCode: c.Query("short_code"), Actor: c.Query("actor")}
+	if filter.ShortCode != "" && !utils.IsValidShortCode(filter.ShortCode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid short code format"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	total, err := h.events.GetAuditEventsCount(ctx, filter)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to count audit events")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	events, err := h.events.GetAuditEvents(ctx, filter, limit, offset)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get audit events")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if events == nil {
+		events = []*models.AuditEvent{}
+	}
+
+	c.JSON(http.StatusOK, AuditEventsResponse{Events: events, Total: total, Limit: limit, Offset: offset})
+}
+
+// loadURL загружает ссылку по коду из пути. При ошибке ответ уже отправлен клиенту
+func (h *AdminHandler) loadURL(c *gin.Context) (*models.URL, bool) {
+	shortCode := c.Param("shortCode")
+	if !utils.IsValidShortCode(shortCode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid short code format"})
+		return nil, false
+	}
+
+	url, err := h.storage.GetURL(c.Request.Context(), shortCode)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
+			return nil, false
+		}
+		log.Error().Err(err).Str("short_code", shortCode).Msg("Failed to get URL")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	return url, true
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/drerr0r/url-shortener/internal/audit"
+	"github.com/drerr0r/url-shortener/internal/auth"
+	"github.com/drerr0r/url-shortener/internal/middleware"
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// newAdminRouter собирает API администратора так же, как main, и выпускает ключи с каждой ролью
+func newAdminRouter(t *testing.T, mockStorage *storage.MockStorage, recorder audit.Recorder) (*gin.Engine, map[models.Role]string) {
+	t.Helper()
+
+	keys := make(map[models.Role]string)
+	for _, role := range []models.Role{models.RoleViewer, models.RoleEditor, models.RoleAdmin} {
+		_, secret, err := auth.CreateKey(context.Background(), mockStorage, string(role), role)
+		if err != nil {
+			t.Fatalf("Failed to create API key: %v", err)
+		}
+		keys[role] = secret
+	}
+
+	urlHandler := NewURLHandler(mockStorage)
+	adminHandler := NewAdminHandler(mockStorage, mockStorage, recorder)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	// Как в main без TRUSTED_PROXIES: X-Forwarded-For не учитывается
+	if err := router.SetTrustedProxies(nil); err != nil {
+		t.Fatalf("Failed to set trusted proxies: %v", err)
+	}
+	router.Use(middleware.RequestID())
+	router.GET("/:shortCode", urlHandler.RedirectHandler)
+	admin := router.Group("/api/v1/admin", auth.New(mockStorage).Middleware(), middleware.AuditOrigin())
+	admin.GET("/urls", adminHandler.Guard(auth.ActionAdminList), adminHandler.ListURLsHandler)
+	admin.GET("/urls/:shortCode", adminHandler.Guard(auth.ActionAdminView), adminHandler.GetURLHandler)
+	admin.DELETE("/urls/:shortCode", adminHandler.Guard(auth.ActionAdminDelete), adminHandler.DeleteURLHandler)
+	admin.POST("/urls/:shortCode/disable", adminHandler.Guard(auth.ActionAdminDisable), adminHandler.DisableURLHandler)
+	admin.POST("/urls/:shortCode/enable", adminHandler.Guard(auth.ActionAdminEnable), adminHandler.EnableURLHandler)
+	admin.GET("/audit", adminHandler.Guard(auth.ActionAdminAudit), adminHandler.AuditEventsHandler)
+	router.GET("/debug/vars", auth.New(mockStorage).Middleware(), adminHandler.Guard(auth.ActionAdminDebug), gin.WrapH(expvar.Handler()))
+	return router, keys
+}
+
+func sendWithKey(router *gin.Engine, method, path, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminAccess(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	recorder := audit.NewMemoryRecorder()
+	router, keys := newAdminRouter(t, mockStorage, recorder)
+
+	tests := []struct {
+		name string
+		key  string
+		want int
+	}{
+		{"Anonymous", "", http.StatusUnauthorized},
+		{"Viewer", keys[models.RoleViewer], http.StatusForbidden},
+		{"Editor", keys[models.RoleEditor], http.StatusForbidden},
+		{"Admin", keys[models.RoleAdmin], http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := sendWithKey(router, "GET", "/api/v1/admin/urls", tt.key)
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d. Body: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// Отказы тоже попадают в аудит
+	events := recorder.Events()
+	if len(events) != len(tests) {
+		t.Fatalf("Expected %d audit events, got %d", len(tests), len(events))
+	}
+	if events[0].Actor != "anonymous" || events[0].Status != http.StatusUnauthorized {
+		t.Errorf("Unexpected audit event for anonymous request: %+v", events[0])
+	}
+	if events[1].ActorRole != string(models.RoleViewer) || events[1].Status != http.StatusForbidden {
+		t.Errorf("Unexpected audit event for viewer: %+v", events[1])
+	}
+	if events[3].Action != string(auth.ActionAdminList) || events[3].ActorRole != string(models.RoleAdmin) {
+		t.Errorf("Unexpected audit event for admin: %+v", events[3])
+	}
+}
+
+func TestAdminDebugVars(t *testing.T) {
+	router, keys := newAdminRouter(t, storage.NewMockStorage(), audit.NewMemoryRecorder())
+
+	if w := sendWithKey(router, "GET", "/debug/vars", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for anonymous request, got %d", w.Code)
+	}
+	if w := sendWithKey(router, "GET", "/debug/vars", keys[models.RoleEditor]); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for editor, got %d", w.Code)
+	}
+	if w := sendWithKey(router, "GET", "/debug/vars", keys[models.RoleAdmin]); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "cmdline") {
+		t.Errorf("Expected expvar output for admin, got %d", w.Code)
+	}
+}
+
+// TestAdminAuditSpoofedForwardedFor проверяет, что подмененный X-Forwarded-For не попадает
+// ни в аудит обращений к API администратора, ни в журнал изменений ссылок
+func TestAdminAuditSpoofedForwardedFor(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	recorder := audit.NewMemoryRecorder()
+	router, keys := newAdminRouter(t, mockStorage, recorder)
+
+	if err := mockStorage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "abc123"}); err != nil {
+		t.Fatalf("Failed to create test URL: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/api/v1/admin/urls/abc123/disable", nil)
+	req.RemoteAddr = "192.0.2.1:12345"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	req.Header.Set("Authorization", "Bearer "+keys[models.RoleAdmin])
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	if events := recorder.Events(); len(events) != 1 || events[0].ClientIP != "192.0.2.1" {
+		t.Errorf("Expected connection address in admin audit event, got %+v", events)
+	}
+	// Новые события первыми: отключение, затем создание
+	changes, err := mockStorage.GetAuditEvents(context.Background(), models.AuditFilter{ShortCode: "abc123"}, 1, 0)
+	if err != nil || len(changes) != 1 || changes[0].Action != models.AuditLinkDisable || changes[0].ClientIP != "192.0.2.1" {
+		t.Errorf("Expected connection address in link audit event, got %+v, %v", changes, err)
+	}
+}
+
+func TestAdminListAndSearch(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	router, keys := newAdminRouter(t, mockStorage, audit.NewMemoryRecorder())
+	admin := keys[models.RoleAdmin]
+
+	userID := int64(42)
+	for _, url := range []*models.URL{
+		{OriginalURL: "https://example.com/docs", ShortCode: "docs1", UserID: &userID},
+		{OriginalURL: "https://example.com/blog", ShortCode: "blog1"},
+	} {
+		if err := mockStorage.SaveURL(context.Background(), url); err != nil {
+			t.Fatalf("Failed to create test URL: %v", err)
+		}
+	}
+
+	w := sendWithKey(router, "GET", "/api/v1/admin/urls", admin)
+	var response AdminURLsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Total != 2 || len(response.URLs) != 2 {
+		t.Errorf("Expected all 2 links, got total %d, %d links", response.Total, len(response.URLs))
+	}
+
+	w = sendWithKey(router, "GET", "/api/v1/admin/urls?q=DOCS", admin)
+	response = AdminURLsResponse{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Total != 1 || len(response.URLs) != 1 || response.URLs[0].ShortCode != "docs1" {
+		t.Errorf("Expected only docs1 in search results, got %+v", response)
+	}
+
+	w = sendWithKey(router, "GET", "/api/v1/admin/urls?limit=0", admin)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid limit, got %d", w.Code)
+	}
+}
+
+func TestAdminDisableEnableDelete(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	recorder := audit.NewMemoryRecorder()
+	router, keys := newAdminRouter(t, mockStorage, recorder)
+	admin := keys[models.RoleAdmin]
+
+	userID := int64(42)
+	if err := mockStorage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "abc123", UserID: &userID}); err != nil {
+		t.Fatalf("Failed to create test URL: %v", err)
+	}
+
+	w := sendWithKey(router, "POST", "/api/v1/admin/urls/abc123/disable", admin)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	if w := sendWithKey(router, "GET", "/abc123", ""); w.Code != http.StatusGone {
+		t.Errorf("Expected status 410 for disabled link, got %d", w.Code)
+	}
+
+	if w := sendWithKey(router, "POST", "/api/v1/admin/urls/abc123/enable", admin); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	if w := sendWithKey(router, "GET", "/abc123", ""); w.Code != http.StatusFound {
+		t.Errorf("Expected redirect after enabling, got %d", w.Code)
+	}
+
+	if w := sendWithKey(router, "POST", "/api/v1/admin/urls/missing/disable", admin); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown link, got %d", w.Code)
+	}
+
+	// Админ удаляет чужую ссылку
+	if w := sendWithKey(router, "DELETE", "/api/v1/admin/urls/abc123", admin); w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", w.Code)
+	}
+	if mockStorage.GetURLCount() != 0 {
+		t.Error("Link should be deleted")
+	}
+
+	events := recorder.Events()
+	if len(events) != 4 {
+		t.Fatalf("Expected 4 audit events, got %d", len(events))
+	}
+	last := events[len(events)-1]
+	if last.Action != string(auth.ActionAdminDelete) || last.ShortCode != "abc123" || last.Status != http.StatusNoContent {
+		t.Errorf("Unexpected audit event for delete: %+v", last)
+	}
+}
+
+func TestAdminAuditEvents(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	router, keys := newAdminRouter(t, mockStorage, audit.NewMemoryRecorder())
+	admin := keys[models.RoleAdmin]
+
+	for _, code := range []string{"abc123", "other1"} {
+		if err := mockStorage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: code}); err != nil {
+			t.Fatalf("Failed to create test URL: %v", err)
+		}
+	}
+	sendWithKey(router, "POST", "/api/v1/admin/urls/abc123/disable", admin)
+	sendWithKey(router, "DELETE", "/api/v1/admin/urls/abc123", admin)
+
+	get := func(path string) AuditEventsResponse {
+		t.Helper()
+		w := sendWithKey(router, "GET", path, admin)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+		}
+		var response AuditEventsResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		return response
+	}
+
+	byLink := get("/api/v1/admin/audit?short_code=abc123")
+	if byLink.Total != 3 || len(byLink.Events) != 3 {
+		t.Fatalf("Expected 3 events for abc123, got %+v", byLink)
+	}
+	deleted := byLink.Events[0]
+	if deleted.Action != models.AuditLinkDelete || deleted.After != nil || deleted.Before == nil {
+		t.Errorf("Unexpected delete event: %+v", deleted)
+	}
+	if deleted.RequestID == "" || deleted.ClientIP == "" {
+		t.Errorf("Expected request ID and client IP in the event, got %+v", deleted)
+	}
+	if byLink.Events[2].Actor != audit.SystemActor {
+		t.Errorf("Expected link created outside a request to be attributed to system, got %q", byLink.Events[2].Actor)
+	}
+
+	byActor := get("/api/v1/admin/audit?actor=" + deleted.Actor + "&limit=1")
+	if byActor.Total != 2 || len(byActor.Events) != 1 || byActor.Events[0].ID != deleted.ID {
+		t.Errorf("Expected the newest of 2 events by %s, got %+v", deleted.Actor, byActor)
+	}
+
+	if w := sendWithKey(router, "GET", "/api/v1/admin/audit", keys[models.RoleEditor]); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for editor, got %d", w.Code)
+	}
+	if w := sendWithKey(router, "GET", "/api/v1/admin/audit?short_code=bad%20code", admin); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid short code, got %d", w.Code)
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/oidc"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// Cookie с state, nonce и PKCE verifier, живущая, пока пользователь на странице провайдера
+const (
+	ssoFlowCookie = "sso_flow"
+	ssoFlowTTL    = 10 * time.Minute
+)
+
+// Ошибки сопоставления учетной записи провайдера с пользователем
+var (
+	errSSONoEmail    = errors.New("id token has no verified email")
+	errSSOEmailTaken = errors.New("email belongs to another account")
+)
+
+// SSOLoginHandler перенаправляет на страницу входа провайдера
+func (h *AccountHandler) SSOLoginHandler(c *gin.Context) {
+	if h.sso == nil {
+		c.Redirect(http.StatusSeeOther, "/login")
+		return
+	}
+
+	state, nonce, verifier := oidc.RandomToken(), oidc.RandomToken(), oidc.RandomToken()
+	target, err := h.sso.AuthCodeURL(c.Request.Context(), state, nonce, verifier)
+	if err != nil {
+		log.Error().Err(err).Msg("OIDC provider is unavailable")
+		h.renderAccountForm(c, http.StatusBadGateway, false, "", "Сервис входа недоступен, попробуйте позже")
+		return
+	}
+
+	h.auth.SetCookie(c, ssoFlowCookie, state+"."+nonce+"."+verifier, ssoFlowTTL)
+	c.Redirect(http.StatusFound, target)
+}
+
+// SSOCallbackHandler принимает пользователя, вернувшегося от провайдера: проверяет state,
+// обменивает код на ID токен, находит или создает пользователя и выполняет вход
+func (h *AccountHandler) SSOCallbackHandler(c *gin.Context) {
+	if h.sso == nil {
+		c.Redirect(http.StatusSeeOther, "/login")
+		return
+	}
+
+	// Cookie одноразовая: повтор того же ответа провайдера не пройдет
+	flow, _ := c.Cookie(ssoFlowCookie)
+	h.auth.ClearCookie(c, ssoFlowCookie)
+	parts := strings.Split(flow, ".")
+	if len(parts) != 3 || subtle.ConstantTimeCompare([]byte(parts[0]), []byte(c.Query("state"))) != 1 {
+		h.renderAccountForm(c, http.StatusBadRequest, false, "", "Сессия входа истекла, попробуйте снова")
+		return
+	}
+	nonce, verifier := parts[1], parts[2]
+
+	if reason := c.Query("error"); reason != "" {
+		log.Info().Str("error", reason).Str("description", c.Query("error_description")).Msg("OIDC login was not completed")
+		h.renderAccountForm(c, http.StatusUnauthorized, false, "", "Вход через SSO не выполнен")
+		return
+	}
+
+	token, err := h.sso.Exchange(c.Request.Context(), c.Query("code"), verifier, nonce)
+	if err != nil {
+		log.Warn().Err(err).Msg("OIDC code exchange failed")
+		h.renderAccountForm(c, http.StatusUnauthorized, false, "", "Вход через SSO не выполнен")
+		return
+	}
+
+	role, ok := h.roles.Role(token)
+	if !ok {
+		log.Info().Str("subject", token.Subject).Msg("OIDC user has no role")
+		h.renderAccountForm(c, http.StatusForbidden, false, "", "У вашей учетной записи нет доступа к сервису")
+		return
+	}
+
+	user, err := h.ssoUser(c.Request.Context(), token, role)
+	switch {
+	case errors.Is(err, errSSONoEmail):
+		h.renderAccountForm(c, http.StatusForbidden, false, "", "Провайдер не передал подтвержденный email, вход невозможен")
+		return
+	case errors.Is(err, errSSOEmailTaken):
+		h.renderAccountForm(c, http.StatusConflict, false, "", "Пользователь с таким email уже зарегистрирован")
+		return
+	case err != nil:
+		log.Error().Err(err).Msg("Failed to save OIDC user")
+		h.renderAccountForm(c, http.StatusInternalServerError, false, "", "Внутренняя ошибка, попробуйте позже")
+		return
+	}
+
+	h.login(c, user)
+}
+
+// ssoUser находит пользователя по issuer + sub или создает его. Email учитывается, только
+// если провайдер его подтвердил: неподтвержденный email нельзя ни сохранить новому пользователю,
+// ни записать существующему, ни использовать для привязки учетной записи. Подтвержденный email
+// и роль обновляются при каждом входе: источник правды - провайдер
+func (h *AccountHandler) ssoUser(ctx context.Context, token *oidc.IDToken, role models.Role) (*models.User, error) {
+	var email string
+	if token.EmailVerified {
+		email = strings.ToLower(token.Email)
+	}
+	issuer, subject := h.sso.Issuer(), token.Subject
+
+	user, err := h.users.GetUserByOIDCSubject(ctx, issuer, subject)
+	if errors.Is(err, storage.ErrNotFound) {
+		if email == "" {
+			return nil, errSSONoEmail
+		}
+		user, err = h.users.GetUserByEmail(ctx, email)
+		if errors.Is(err, storage.ErrNotFound) {
+			user = &models.User{Email: email, Role: role, OIDCIssuer: &issuer, OIDCSubject: &subject}
+			if err := h.users.CreateUser(ctx, user); err != nil {
+				if errors.Is(err, storage.ErrAlreadyExists) {
+					return nil, errSSOEmailTaken
+				}
+				return nil, err
+			}
+			log.Info().Int64("user_id", user.ID).Str("role", string(role)).Msg("OIDC user created")
+			return user, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if user.OIDCSubject != nil {
+			return nil, errSSOEmailTaken
+		}
+		user.OIDCIssuer, user.OIDCSubject = &issuer, &subject
+		log.Info().Int64("user_id", user.ID).Msg("Local account linked to OIDC user")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if email != "" {
+		user.Email = email
+	}
+	if user.Role != role {
+		log.Info().Int64("user_id", user.ID).Str("from", string(user.Role)).Str("to", string(role)).Msg("OIDC user role changed")
+		user.Role = role
+	}
+	if err := h.users.UpdateUser(ctx, user); err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			return nil, errSSOEmailTaken
+		}
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/oidc"
+	"github.com/drerr0r/url-shortener/internal/oidc/oidctest"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/drerr0r/url-shortener/internal/utils"
+)
+
+func newSSORouterOptions(t *testing.T, idp *oidctest.Server, defaultRole string) []AccountOption {
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       idp.Issuer(),
+		ClientID:     idp.ClientID,
+		ClientSecret: idp.ClientSecret,
+		RedirectURL:  "http://shortener.test/login/sso/callback",
+		Scopes:       []string{"openid", "email", "profile"},
+	})
+	roles, err := oidc.ParseRoleMapping("groups", "shortener-admins=admin,staff=editor", defaultRole)
+	if err != nil {
+		t.Fatalf("Failed to parse role mapping: %v", err)
+	}
+	return []AccountOption{WithSSO(provider, roles)}
+}
+
+// ssoLogin проходит вход через провайдер: редирект на /authorize и возврат на callback
+func (b *browser) ssoLogin() *httptest.ResponseRecorder {
+	b.t.Helper()
+	w := b.get("/login/sso")
+	if w.Code != http.StatusFound {
+		b.t.Fatalf("Expected redirect to provider, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(w.Header().Get("Location"))
+	if err != nil {
+		b.t.Fatalf("Provider request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || callback.Path != "/login/sso/callback" {
+		b.t.Fatalf("Expected redirect to callback, got %q", resp.Header.Get("Location"))
+	}
+	return b.get(callback.RequestURI())
+}
+
+// TestSSOLogin проверяет вход через OIDC провайдер и сопоставление групп ролям
+func TestSSOLogin(t *testing.T) {
+	idp := oidctest.NewServer("shortener", "client-secret")
+	defer idp.Close()
+	mockStorage := storage.NewMockStorage()
+	router := newAccountRouter(mockStorage, newSSORouterOptions(t, idp, "viewer")...)
+	user := &browser{t: t, router: router, cookies: map[string]*http.Cookie{}}
+
+	if body := user.get("/login").Body.String(); !strings.Contains(body, `href="/login/sso"`) {
+		t.Errorf("Expected SSO button on the login page")
+	}
+
+	idp.SetUser(map[string]any{"sub": "emp-1", "email": "Alice@Corp.example", "email_verified": true, "groups": []string{"staff", "shortener-admins"}})
+	w := user.ssoLogin()
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/my/links" {
+		t.Fatalf("Expected redirect to /my/links, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	if _, ok := user.cookies[ssoFlowCookie]; ok {
+		t.Errorf("Expected the flow cookie to be cleared")
+	}
+	if body := user.get("/my/links").Body.String(); !strings.Contains(body, "alice@corp.example") {
+		t.Errorf("Expected the SSO user to be logged in, got: %s", body)
+	}
+
+	stored, err := mockStorage.GetUserByOIDCSubject(t.Context(), idp.Issuer(), "emp-1")
+	if err != nil {
+		t.Fatalf("Expected the SSO user to be created: %v", err)
+	}
+	if stored.Role != models.RoleAdmin || stored.Email != "alice@corp.example" || stored.PasswordHash != "" {
+		t.Errorf("Unexpected SSO user: %+v", stored)
+	}
+
+	// Роль пересчитывается при каждом входе, а неподтвержденный email не перезаписывает сохраненный
+	idp.SetUser(map[string]any{"sub": "emp-1", "email": "mallory@evil.example", "groups": []string{"finance"}})
+	other := &browser{t: t, router: router, cookies: map[string]*http.Cookie{}}
+	if w := other.ssoLogin(); w.Code != http.StatusSeeOther {
+		t.Fatalf("Expected second login to succeed, got %d", w.Code)
+	}
+	stored, _ = mockStorage.GetUserByID(t.Context(), stored.ID)
+	if stored.Role != models.RoleViewer {
+		t.Errorf("Expected role to be downgraded to viewer, got %q", stored.Role)
+	}
+	if stored.Email != "alice@corp.example" {
+		t.Errorf("Expected unverified email to be ignored, got %q", stored.Email)
+	}
+}
+
+// TestSSOAccountLinking проверяет привязку существующей учетной записи по email
+func TestSSOAccountLinking(t *testing.T) {
+	idp := oidctest.NewServer("shortener", "")
+	defer idp.Close()
+	mockStorage := storage.NewMockStorage()
+	router := newAccountRouter(mockStorage, newSSORouterOptions(t, idp, "editor")...)
+
+	hash, _ := utils.HashPassword("correct horse")
+	local := &models.User{Email: "bob@corp.example", PasswordHash: hash}
+	if err := mockStorage.CreateUser(t.Context(), local); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		verified bool
+		want     int
+	}{
+		{"Unverified email is not linked", false, http.StatusForbidden},
+		{"Verified email is linked", true, http.StatusSeeOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp.SetUser(map[string]any{"sub": "emp-2", "email": "bob@corp.example", "email_verified": tt.verified})
+			b := &browser{t: t, router: router, cookies: map[string]*http.Cookie{}}
+			if w := b.ssoLogin(); w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d. Body: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	linked, err := mockStorage.GetUserByOIDCSubject(t.Context(), idp.Issuer(), "emp-2")
+	if err != nil || linked.ID != local.ID || linked.PasswordHash != hash {
+		t.Errorf("Expected the local account to be linked, got %+v, %v", linked, err)
+	}
+}
+
+// TestSSOLoginErrors проверяет отказ во входе
+func TestSSOLoginErrors(t *testing.T) {
+	idp := oidctest.NewServer("shortener", "client-secret")
+	defer idp.Close()
+	router := newAccountRouter(storage.NewMockStorage(), newSSORouterOptions(t, idp, "none")...)
+
+	t.Run("No matching group", func(t *testing.T) {
+		idp.SetUser(map[string]any{"sub": "emp-3", "email": "eve@corp.example", "groups": []string{"finance"}})
+		b := &browser{t: t, router: router, cookies: map[string]*http.Cookie{}}
+		if w := b.ssoLogin(); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", w.Code)
+		}
+	})
+
+	t.Run("Missing email", func(t *testing.T) {
+		idp.SetUser(map[string]any{"sub": "emp-4", "groups": []string{"staff"}})
+		b := &browser{t: t, router: router, cookies: map[string]*http.Cookie{}}
+		if w := b.ssoLogin(); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", w.Code)
+		}
+	})
+
+	t.Run("Unverified email", func(t *testing.T) {
+		idp.SetUser(map[string]any{"sub": "emp-5", "email": "ceo@corp.example", "email_verified": false, "groups": []string{"staff"}})
+		b := &browser{t: t, router: router, cookies: map[string]*http.Cookie{}}
+		if w := b.ssoLogin(); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", w.Code)
+		}
+	})
+
+	t.Run("User cancelled login", func(t *testing.T) {
+		idp.SetUser(nil)
+		b := &browser{t: t, router: router, cookies: map[string]*http.Cookie{}}
+		if w := b.ssoLogin(); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401, got %d", w.Code)
+		}
+	})
+
+	t.Run("State mismatch", func(t *testing.T) {
+		b := &browser{t: t, router: router, cookies: map[string]*http.Cookie{}}
+		b.get("/login/sso")
+		if w := b.get("/login/sso/callback?code=x&state=forged"); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("Callback without flow cookie", func(t *testing.T) {
+		b := &browser{t: t, router: router, cookies: map[string]*http.Cookie{}}
+		if w := b.get("/login/sso/callback?code=x&state=y"); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", w.Code)
+		}
+	})
+}
+
+// TestSSOOnly проверяет, что при SSO_ONLY вход и регистрация по паролю отключены
+func TestSSOOnly(t *testing.T) {
+	idp := oidctest.NewServer("shortener", "client-secret")
+	defer idp.Close()
+	router := newAccountRouter(storage.NewMockStorage(), append(newSSORouterOptions(t, idp, "viewer"), WithPasswordLogin(false))...)
+	b := &browser{t: t, router: router, cookies: map[string]*http.Cookie{}}
+
+	if body := b.get("/login").Body.String(); strings.Contains(body, `name="password"`) {
+		t.Errorf("Expected no password form on the login page")
+	}
+	if w := b.get("/signup"); w.Code != http.StatusSeeOther {
+		t.Errorf("Expected signup page to redirect, got %d", w.Code)
+	}
+
+	form := url.Values{"email": {"user@example.com"}, "password": {"correct horse"}, "csrf_token": {b.csrfToken("/")}}
+	if w := b.post("/signup", form); w.Code != http.StatusForbidden {
+		t.Errorf("Expected password signup to be rejected, got %d", w.Code)
+	}
+	if w := b.post("/login", form); w.Code != http.StatusForbidden {
+		t.Errorf("Expected password login to be rejected, got %d", w.Code)
+	}
+}
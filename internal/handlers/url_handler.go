@@ -1,26 +1,130 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 
+	"github.com/drerr0r/url-shortener/internal/auth"
+	"github.com/drerr0r/url-shortener/internal/clicks"
+	"github.com/drerr0r/url-shortener/internal/metrics"
 	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/ratelimit"
+	"github.com/drerr0r/url-shortener/internal/settings"
+	"github.com/drerr0r/url-shortener/internal/shortcode"
 	"github.com/drerr0r/url-shortener/internal/storage"
 	"github.com/drerr0r/url-shortener/internal/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
 )
 
+// Ограничения на длину сохраняемых заголовков и размер страницы журнала кликов
+const (
+	maxHeaderValueLength = 1024
+	defaultClicksLimit   = 50
+	maxClicksLimit       = 1000
+	maxTimeSeriesBuckets = 2000
+)
+
+// countryHeaders заголовки, в которых CDN/прокси передают код страны клиента
+var countryHeaders = []string{"CF-IPCountry", "X-Country-Code"}
+
 type URLHandler struct {
-	storage storage.Storage
+	storage         storage.Storage
+	stats           storage.Storage // Хранилище без кэша для статистики
+	recorder        clicks.Recorder
+	allocator       *shortcode.Allocator
+	ipSalt          string
+	passwordLimiter *ratelimit.AttemptLimiter
+	metrics         *metrics.Metrics
+	settings        *settings.Store
+}
+
+// Option настраивает дополнительные зависимости URLHandler
+type Option func(*URLHandler)
+
+// WithClickRecorder задает регистратор кликов, используемый при редиректе.
+// По умолчанию клики записываются синхронно через clicks.SyncRecorder
+func WithClickRecorder(recorder clicks.Recorder) Option {
+	return func(h *URLHandler) {
+		h.recorder = recorder
+	}
 }
 
-func NewURLHandler(storage storage.Storage) *URLHandler {
-	return &URLHandler{storage: storage}
+// WithAllocator задает генератор коротких кодов.
+// По умолчанию используются коды длиной 6 символов
+func WithAllocator(allocator *shortcode.Allocator) Option {
+	return func(h *URLHandler) {
+		h.allocator = allocator
+	}
+}
+
+// WithIPHashSalt задает соль для хэширования IP адресов в журнале кликов
+func WithIPHashSalt(salt string) Option {
+	return func(h *URLHandler) {
+		h.ipSalt = salt
+	}
+}
+
+// WithPasswordLimiter задает ограничитель неудачных попыток ввода пароля.
+// По умолчанию допускается 5 ошибок за 15 минут для пары код + IP и 50 для кода
+func WithPasswordLimiter(limiter *ratelimit.AttemptLimiter) Option {
+	return func(h *URLHandler) {
+		h.passwordLimiter = limiter
+	}
+}
+
+// WithStatsStorage задает хранилище, из которого читается статистика ссылки. Кэш хранит ссылку
+// с устаревшим click_count, поэтому сюда передается хранилище без кэшей. По умолчанию - основное
+func WithStatsStorage(stats storage.Storage) Option {
+	return func(h *URLHandler) {
+		h.stats = stats
+	}
+}
+
+// WithMetrics включает учет запросов на сокращение и переход в метриках Prometheus
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(h *URLHandler) {
+		h.metrics = m
+	}
+}
+
+// WithSettings подключает настройки, меняющиеся без перезапуска (список заблокированных доменов)
+func WithSettings(store *settings.Store) Option {
+	return func(h *URLHandler) {
+		h.settings = store
+	}
+}
+
+func NewURLHandler(storage storage.Storage, opts ...Option) *URLHandler {
+	h := &URLHandler{storage: storage}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.recorder == nil {
+		h.recorder = clicks.NewSyncRecorder(storage)
+	}
+	if h.allocator == nil {
+		h.allocator = shortcode.NewAllocator(storage, shortcode.Options{Length: 6})
+	}
+	if h.passwordLimiter == nil {
+		h.passwordLimiter = ratelimit.NewAttemptLimiter(5, 15*time.Minute)
+	}
+	if h.stats == nil {
+		h.stats = storage
+	}
+	return h
 }
 
 type ShortenRequest struct {
-	URL string `json:"url" binding:"required"`
+	URL        string     `json:"url" binding:"required"`
+	Alias      string     `json:"alias"`       // Необязательный пользовательский короткий код
+	ExpiresAt  *time.Time `json:"expires_at"`  // Абсолютное время истечения (RFC3339)
+	TTLSeconds *int64     `json:"ttl_seconds"` // Время жизни ссылки в секундах
+	MaxClicks  *int64     `json:"max_clicks"`  // Максимальное количество переходов
+	Password   string     `json:"password"`    // Пароль, который нужно ввести перед переходом
 }
 
 type ShortenResponse struct {
@@ -29,6 +133,7 @@ type ShortenResponse struct {
 
 // ShortenURLHandler обрабатывает запрос на сокращение URL
 func (h *URLHandler) ShortenURLHandler(c *gin.Context) {
+	defer func() { h.metrics.ShortenServed(c.Writer.Status()) }()
 	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 1024)
 
 	var req ShortenRequest
@@ -42,56 +147,269 @@ func (h *URLHandler) ShortenURLHandler(c *gin.Context) {
 		return
 	}
 
-	existingURL, err := h.storage.GetURLByOriginal(req.URL)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to check existing URL")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	if h.isBlocked(req.URL) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Domain is blocked"})
 		return
 	}
 
-	if existingURL != nil {
-		c.JSON(http.StatusOK, ShortenResponse{ShortURL: existingURL.ShortCode})
+	expiresAt, err := resolveExpiry(req, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	shortCode := utils.GenerateRandomString(6)
+	if req.MaxClicks != nil && *req.MaxClicks <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_clicks must be positive"})
+		return
+	}
 
-	if !utils.IsValidShortCode(shortCode) {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate valid short code"})
+	if len(req.Password) > utils.MaxPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password is too long"})
 		return
 	}
 
+	owner := auth.FromContext(c).Owner()
 	urlModel := &models.URL{
 		OriginalURL: req.URL,
-		ShortCode:   shortCode,
+		ExpiresAt:   expiresAt,
+		MaxClicks:   req.MaxClicks,
+		OwnerID:     owner.KeyID,
+		UserID:      owner.UserID,
+	}
+
+	if req.Password != "" {
+		hash, err := utils.HashPassword(req.Password)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to hash password")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		urlModel.PasswordHash = hash
+	}
+
+	if req.Alias != "" {
+		h.saveAlias(c, urlModel, req.Alias)
+		return
+	}
+
+	// Существующую ссылку того же владельца переиспользуем только для запросов без дополнительных параметров
+	if isPlainLink(urlModel) {
+		existingURL, err := h.storage.GetURLByOriginal(c.Request.Context(), req.URL, owner)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to check existing URL")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		if existingURL != nil && isPlainLink(existingURL) {
+			c.JSON(http.StatusOK, ShortenResponse{ShortURL: existingURL.ShortCode})
+			return
+		}
 	}
 
-	if err := h.storage.SaveURL(urlModel); err != nil {
+	if err := h.allocator.Allocate(c.Request.Context(), urlModel); err != nil {
+		if errors.Is(err, shortcode.ErrExhausted) {
+			log.Error().Err(err).Msg("Failed to generate unique short code")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to generate unique short code, try again later"})
+			return
+		}
 		log.Error().Err(err).Msg("Failed to save URL")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save URL"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, ShortenResponse{ShortURL: shortCode})
+	c.JSON(http.StatusCreated, ShortenResponse{ShortURL: urlModel.ShortCode})
+}
+
+// saveAlias сохраняет ссылку с пользовательским коротким кодом.
+// Дедупликация по оригинальному URL не выполняется: на один URL можно завести несколько алиасов
+func (h *URLHandler) saveAlias(c *gin.Context, urlModel *models.URL, alias string) {
+	if !utils.IsValidShortCode(alias) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alias format"})
+		return
+	}
+
+	if utils.IsReservedShortCode(alias) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Alias is reserved"})
+		return
+	}
+
+	exists, err := h.storage.URLExists(c.Request.Context(), alias)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to check alias")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if exists {
+		c.JSON(http.StatusConflict, gin.H{"error": "Alias is already taken"})
+		return
+	}
+
+	urlModel.ShortCode = alias
+	if err := h.storage.SaveURL(c.Request.Context(), urlModel); err != nil {
+		// Алиас могли занять между проверкой и вставкой
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Alias is already taken"})
+			return
+		}
+		log.Error().Err(err).Msg("Failed to save URL")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save URL"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ShortenResponse{ShortURL: alias})
+}
+
+// resolveExpiry вычисляет время истечения ссылки из expires_at или ttl_seconds
+func resolveExpiry(req ShortenRequest, now time.Time) (*time.Time, error) {
+	if req.ExpiresAt != nil && req.TTLSeconds != nil {
+		return nil, errors.New("specify either expires_at or ttl_seconds, not both")
+	}
+
+	if req.TTLSeconds != nil {
+		if *req.TTLSeconds <= 0 {
+			return nil, errors.New("ttl_seconds must be positive")
+		}
+		expiresAt := now.Add(time.Duration(*req.TTLSeconds) * time.Second).UTC()
+		return &expiresAt, nil
+	}
+
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(now) {
+			return nil, errors.New("expires_at must be in the future")
+		}
+		expiresAt := req.ExpiresAt.UTC()
+		return &expiresAt, nil
+	}
+
+	return nil, nil
+}
+
+// isPlainLink проверяет, что у ссылки нет ограничений (срока действия, лимита переходов, пароля),
+// и ее можно безопасно выдать повторно для того же URL
+func isPlainLink(url *models.URL) bool {
+	return url.ExpiresAt == nil && url.MaxClicks == nil && !url.IsProtected()
 }
 
 // 🟡 ИСПРАВЛЕНО: Переименовали метод для соответствия вызовам в main.go
-// RedirectHandler обрабатывает перенаправление по короткому URL
+// RedirectHandler обрабатывает перенаправление по короткому URL.
+// Для защищенных ссылок вместо редиректа показывается форма ввода пароля
 func (h *URLHandler) RedirectHandler(c *gin.Context) {
+	defer func() { h.metrics.RedirectServed(c.Writer.Status()) }()
+	url, ok := h.activeURL(c)
+	if !ok {
+		return
+	}
+
+	if url.IsProtected() {
+		renderPasswordPrompt(c, http.StatusOK, url.ShortCode, "")
+		return
+	}
+
+	h.redirect(c, url, http.StatusFound)
+}
+
+// UnlockHandler проверяет пароль, отправленный из формы, и выполняет редирект.
+// Неудачные попытки ограничиваются для пары короткий код + IP и для кода в целом
+func (h *URLHandler) UnlockHandler(c *gin.Context) {
+	defer func() { h.metrics.RedirectServed(c.Writer.Status()) }()
+	url, ok := h.activeURL(c)
+	if !ok {
+		return
+	}
+
+	if !url.IsProtected() {
+		h.redirect(c, url, http.StatusSeeOther)
+		return
+	}
+
+	client := c.ClientIP()
+	if allowed, retryAfter := h.passwordLimiter.Allow(url.ShortCode, client); !allowed {
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		renderPasswordPrompt(c, http.StatusTooManyRequests, url.ShortCode, "Слишком много попыток, попробуйте позже")
+		return
+	}
+
+	if !utils.CheckPassword(url.PasswordHash, c.PostForm("password")) {
+		h.passwordLimiter.Fail(url.ShortCode, client)
+		renderPasswordPrompt(c, http.StatusUnauthorized, url.ShortCode, "Неверный пароль")
+		return
+	}
+
+	h.passwordLimiter.Reset(url.ShortCode, client)
+	h.redirect(c, url, http.StatusSeeOther)
+}
+
+// activeURL загружает ссылку по коду из пути и проверяет, что по ней еще можно перейти.
+// При ошибке ответ уже отправлен клиенту
+func (h *URLHandler) activeURL(c *gin.Context) (*models.URL, bool) {
 	shortCode := c.Param("shortCode")
 
 	if !utils.IsValidShortCode(shortCode) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid short code format"})
-		return
+		return nil, false
 	}
 
-	url, err := h.storage.GetURL(shortCode)
+	url, err := h.storage.GetURL(c.Request.Context(), shortCode)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
-		return
+		return nil, false
+	}
+
+	if url.IsDisabled() {
+		c.JSON(http.StatusGone, gin.H{"error": "URL is disabled"})
+		return nil, false
+	}
+
+	if url.IsExpired(time.Now()) {
+		c.JSON(http.StatusGone, gin.H{"error": "URL has expired"})
+		return nil, false
+	}
+
+	if url.IsExhausted() {
+		c.JSON(http.StatusGone, gin.H{"error": "URL click limit reached"})
+		return nil, false
+	}
+
+	// Домен могли заблокировать уже после создания ссылки
+	if h.isBlocked(url.OriginalURL) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Domain is blocked"})
+		return nil, false
+	}
+
+	return url, true
+}
+
+// redirect засчитывает переход и перенаправляет клиента на оригинальный URL
+func (h *URLHandler) redirect(c *gin.Context, url *models.URL, status int) {
+	click := h.newClick(c, url.ShortCode)
+	if url.MaxClicks != nil {
+		// Лимит проверяется и списывается атомарно в хранилище, а не по прочитанному значению,
+		// чтобы параллельные переходы не превысили max_clicks
+		if err := h.storage.ConsumeClick(c.Request.Context(), url.ShortCode); err != nil {
+			if errors.Is(err, storage.ErrClickLimitReached) || errors.Is(err, storage.ErrNotFound) {
+				c.JSON(http.StatusGone, gin.H{"error": "URL click limit reached"})
+				return
+			}
+			log.Error().Err(err).Str("short_code", url.ShortCode).Msg("Failed to consume click")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		click.Counted = true
 	}
+	h.recorder.Record(click)
+
+	c.Redirect(status, url.OriginalURL)
+}
 
-	c.Redirect(http.StatusFound, url.OriginalURL)
+// renderPasswordPrompt показывает форму ввода пароля для защищенной ссылки
+func renderPasswordPrompt(c *gin.Context, status int, shortCode, message string) {
+	// Страницу с паролем не кэшируем, чтобы прокси не отдали ее вместо редиректа
+	c.Header("Cache-Control", "no-store")
+	c.HTML(status, "password.html", gin.H{
+		"ShortCode": shortCode,
+		"Error":     message,
+	})
 }
 
 // 🟡 ИСПРАВЛЕНО: Переименовали метод для соответствия вызовам в main.go
@@ -104,13 +422,296 @@ func (h *URLHandler) GetURLStatsHandler(c *gin.Context) {
 		return
 	}
 
-	url, err := h.storage.GetURL(shortCode)
+	// Мимо кэша: счетчик переходов в кэшированной ссылке отстает на время жизни записи
+	url, ok := h.ownedURLFrom(c, h.stats, shortCode, true)
+	if !ok {
+		return
+	}
+
+	// Адрес назначения защищенной ссылки не раскрываем без пароля
+	if url.IsProtected() {
+		url.OriginalURL = ""
+	}
+
+	c.JSON(http.StatusOK, url)
+}
+
+// DeleteURLHandler удаляет ссылку вместе с журналом переходов. Удалить можно только свою ссылку
+func (h *URLHandler) DeleteURLHandler(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+
+	if !utils.IsValidShortCode(shortCode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid short code format"})
+		return
+	}
+
+	if _, ok := h.ownedURL(c, shortCode, false); !ok {
+		return
+	}
+
+	if err := h.storage.DeleteURL(c.Request.Context(), shortCode); err != nil {
+		log.Error().Err(err).Msg("Failed to delete URL")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ownedURL загружает ссылку и проверяет, что клиент запроса - ее владелец.
+// Анонимные ссылки доступны всем при allowAnonymous, иначе никому.
+// При ошибке ответ уже отправлен клиенту
+func (h *URLHandler) ownedURL(c *gin.Context, shortCode string, allowAnonymous bool) (*models.URL, bool) {
+	return h.ownedURLFrom(c, h.storage, shortCode, allowAnonymous)
+}
+
+// ownedURLFrom то же, что ownedURL, но читает ссылку из заданного хранилища
+func (h *URLHandler) ownedURLFrom(c *gin.Context, from storage.Storage, shortCode string, allowAnonymous bool) (*models.URL, bool) {
+	url, err := from.GetURL(c.Request.Context(), shortCode)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
+		return nil, false
+	}
+
+	principal := auth.FromContext(c)
+	anonymous := url.Owner().IsAnonymous()
+	switch {
+	case anonymous && allowAnonymous:
+		return url, true
+	case !anonymous && principal == nil:
+		c.Header("WWW-Authenticate", `Bearer realm="url-shortener"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return nil, false
+	case !url.IsOwnedBy(principal.Owner()):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return nil, false
+	}
+	return url, true
+}
+
+// ClicksResponse представляет страницу журнала переходов по ссылке
+type ClicksResponse struct {
+	ShortCode string          `json:"short_code"`
+	Clicks    []*models.Click `json:"clicks"`
+	Total     int             `json:"total"`
+	Limit     int             `json:"limit"`
+	Offset    int             `json:"offset"`
+}
+
+// GetURLClicksHandler возвращает постраничный журнал переходов по ссылке
+func (h *URLHandler) GetURLClicksHandler(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+
+	if !utils.IsValidShortCode(shortCode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid short code format"})
 		return
 	}
 
-	c.JSON(http.StatusOK, url)
+	limit, offset, ok := parsePagination(c, defaultClicksLimit, maxClicksLimit)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+
+	// Журнал раскрывает referrer и user agent посетителей, поэтому доступен только владельцу:
+	// у анонимной ссылки владельца нет
+	if _, ok := h.ownedURL(c, shortCode, false); !ok {
+		return
+	}
+
+	total, err := h.storage.GetClicksCount(c.Request.Context(), shortCode)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to count clicks")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	events, err := h.storage.GetClicks(c.Request.Context(), shortCode, limit, offset)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get clicks")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ClicksResponse{
+		ShortCode: shortCode,
+		Clicks:    events,
+		Total:     total,
+		Limit:     limit,
+		Offset:    offset,
+	})
+}
+
+// TimeSeriesResponse представляет количество переходов по интервалам
+type TimeSeriesResponse struct {
+	ShortCode string               `json:"short_code"`
+	Interval  string               `json:"interval"`
+	Timezone  string               `json:"timezone"`
+	From      time.Time            `json:"from"`
+	To        time.Time            `json:"to"`
+	Total     int64                `json:"total"`
+	Buckets   []*models.TimeBucket `json:"buckets"`
+}
+
+// GetURLTimeSeriesHandler возвращает временной ряд переходов по ссылке.
+// Параметры: from и to (RFC3339 или YYYY-MM-DD), interval (hour|day|week), tz (имя зоны IANA).
+// Интервалы без переходов возвращаются с нулевым значением
+func (h *URLHandler) GetURLTimeSeriesHandler(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+
+	if !utils.IsValidShortCode(shortCode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid short code format"})
+		return
+	}
+
+	interval := c.DefaultQuery("interval", utils.IntervalDay)
+	if !utils.IsValidInterval(interval) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Interval must be one of hour, day, week"})
+		return
+	}
+
+	loc, err := time.LoadLocation(c.DefaultQuery("tz", "UTC"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+		return
+	}
+
+	to := time.Now().In(loc)
+	if value := c.Query("to"); value != "" {
+		if to, err = parseTimeParam(value, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'to' parameter"})
+			return
+		}
+	}
+
+	from := to.AddDate(0, 0, -7)
+	if value := c.Query("from"); value != "" {
+		if from, err = parseTimeParam(value, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'from' parameter"})
+			return
+		}
+	}
+
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'from' must be before 'to'"})
+		return
+	}
+
+	if _, ok := h.ownedURL(c, shortCode, true); !ok {
+		return
+	}
+
+	// Начало ряда выравниваем по границе интервала, чтобы первый бакет был полным
+	start := utils.TruncateTime(from, interval)
+	buckets, ok := emptyTimeSeries(start, to, interval)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Requested range contains too many buckets"})
+		return
+	}
+
+	series, err := h.storage.GetClickTimeSeries(c.Request.Context(), shortCode, start, to, interval, loc)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get click time series")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	var total int64
+	index := make(map[int64]*models.TimeBucket, len(buckets))
+	for _, bucket := range buckets {
+		index[bucket.Start.Unix()] = bucket
+	}
+	for _, point := range series {
+		if bucket, exists := index[point.Start.Unix()]; exists {
+			bucket.Count = point.Count
+			total += point.Count
+		}
+	}
+
+	c.JSON(http.StatusOK, TimeSeriesResponse{
+		ShortCode: shortCode,
+		Interval:  interval,
+		Timezone:  loc.String(),
+		From:      start,
+		To:        to,
+		Total:     total,
+		Buckets:   buckets,
+	})
+}
+
+// emptyTimeSeries строит ряд нулевых интервалов от start до to.
+// Возвращает false, если интервалов больше допустимого
+func emptyTimeSeries(start, to time.Time, interval string) ([]*models.TimeBucket, bool) {
+	buckets := []*models.TimeBucket{}
+	for t := start; t.Before(to); t = utils.NextBucket(t, interval) {
+		if len(buckets) >= maxTimeSeriesBuckets {
+			return nil, false
+		}
+		buckets = append(buckets, &models.TimeBucket{Start: t})
+	}
+	return buckets, true
+}
+
+// parseTimeParam разбирает время в формате RFC3339 или дату YYYY-MM-DD в часовом поясе loc
+func parseTimeParam(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc), nil
+	}
+	return time.ParseInLocation("2006-01-02", value, loc)
+}
+
+// newClick собирает событие перехода из заголовков запроса
+func (h *URLHandler) newClick(c *gin.Context, shortCode string) *models.Click {
+	click := &models.Click{
+		ShortCode:      shortCode,
+		ClickedAt:      time.Now().UTC(),
+		Referrer:       utils.TruncateString(c.Request.Referer(), maxHeaderValueLength),
+		UserAgent:      utils.TruncateString(c.Request.UserAgent(), maxHeaderValueLength),
+		IPHash:         utils.HashIP(c.ClientIP(), h.ipSalt),
+		AcceptLanguage: utils.TruncateString(c.GetHeader("Accept-Language"), maxHeaderValueLength),
+	}
+	for _, header := range countryHeaders {
+		if country := c.GetHeader(header); len(country) == 2 {
+			click.Country = country
+			break
+		}
+	}
+	return click
+}
+
+// parsePagination разбирает параметры limit и offset из query строки
+func parsePagination(c *gin.Context, defaultLimit, maxLimit int) (limit, offset int, ok bool) {
+	limit, offset = defaultLimit, 0
+
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return 0, 0, false
+		}
+		limit = min(parsed, maxLimit)
+	}
+
+	if value := c.Query("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return 0, 0, false
+		}
+		offset = parsed
+	}
+
+	return limit, offset, true
+}
+
+// isBlocked проверяет домен ссылки по текущему списку блокировки
+func (h *URLHandler) isBlocked(rawURL string) bool {
+	if h.settings == nil {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return h.settings.Current().IsBlocked(u.Hostname())
 }
 
 func isValidURL(urlStr string) bool {
@@ -2,13 +2,23 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/drerr0r/url-shortener/internal/auth"
 	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/ratelimit"
+	"github.com/drerr0r/url-shortener/internal/settings"
+	"github.com/drerr0r/url-shortener/internal/shortcode"
 	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/drerr0r/url-shortener/internal/utils"
 	"github.com/gin-gonic/gin"
 )
 
@@ -102,7 +112,7 @@ func TestRedirectHandler(t *testing.T) {
 		OriginalURL: "https://example.com",
 		ShortCode:   "abc123",
 	}
-	err := mockStorage.SaveURL(testURL)
+	err := mockStorage.SaveURL(context.Background(), testURL)
 	if err != nil {
 		t.Fatalf("Failed to create test URL: %v", err)
 	}
@@ -125,6 +135,48 @@ func TestRedirectHandler(t *testing.T) {
 	}
 }
 
+// TestRedirectHandlerCountsClicks проверяет, что каждый переход увеличивает счетчик кликов
+func TestRedirectHandlerCountsClicks(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	handler := NewURLHandler(mockStorage)
+
+	testURL := &models.URL{
+		OriginalURL: "https://example.com",
+		ShortCode:   "click123",
+	}
+	if err := mockStorage.SaveURL(context.Background(), testURL); err != nil {
+		t.Fatalf("Failed to create test URL: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/:shortCode", handler.RedirectHandler)
+	router.GET("/api/v1/stats/:shortCode", handler.GetURLStatsHandler)
+
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "/click123", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusFound {
+			t.Fatalf("Expected status 302, got %d. Body: %s", w.Code, w.Body.String())
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/api/v1/stats/click123", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var url models.URL
+	if err := json.Unmarshal(w.Body.Bytes(), &url); err != nil {
+		t.Fatalf("Failed to parse stats response: %v", err)
+	}
+
+	if url.ClickCount != 3 {
+		t.Errorf("Expected click count 3, got %d", url.ClickCount)
+	}
+}
+
 // TestRedirectHandlerNotFound проверяет обработку ситуации, когда короткий код не найден
 func TestRedirectHandlerNotFound(t *testing.T) {
 	mockStorage := storage.NewMockStorage()
@@ -170,7 +222,7 @@ func TestGetURLStatsHandler(t *testing.T) {
 		OriginalURL: "https://example.com",
 		ShortCode:   "stats123",
 	}
-	err := mockStorage.SaveURL(testURL)
+	err := mockStorage.SaveURL(context.Background(), testURL)
 	if err != nil {
 		t.Fatalf("Failed to create test URL: %v", err)
 	}
@@ -200,6 +252,41 @@ func TestGetURLStatsHandler(t *testing.T) {
 	}
 }
 
+// TestGetURLStatsHandlerBypassesCache проверяет, что статистика не отстает от счетчика в хранилище,
+// пока ссылка лежит в кэше
+func TestGetURLStatsHandlerBypassesCache(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	cache := storage.NewMemoryCache(mockStorage, storage.MemoryCacheOptions{TTL: time.Hour})
+	handler := NewURLHandler(cache, WithStatsStorage(mockStorage))
+
+	if err := mockStorage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "stats123"}); err != nil {
+		t.Fatalf("Failed to create test URL: %v", err)
+	}
+	// Ссылка попадает в кэш, затем асинхронный регистратор увеличивает счетчик прямо в базе
+	if _, err := cache.GetURL(context.Background(), "stats123"); err != nil {
+		t.Fatalf("Failed to warm cache: %v", err)
+	}
+	if err := mockStorage.SaveClickBatch(context.Background(), map[string]int64{"stats123": 3}, nil); err != nil {
+		t.Fatalf("Failed to count clicks: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/api/v1/stats/:shortCode", handler.GetURLStatsHandler)
+
+	req, _ := http.NewRequest("GET", "/api/v1/stats/stats123", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var url models.URL
+	if err := json.Unmarshal(w.Body.Bytes(), &url); err != nil {
+		t.Fatalf("Failed to parse stats response: %v", err)
+	}
+	if url.ClickCount != 3 {
+		t.Errorf("Expected fresh click count 3, got %d", url.ClickCount)
+	}
+}
+
 // TestGetURLStatsHandlerNotFound тестируем получение статистики для несуществующей ссылки
 func TestGetURLStatsHandlerNotFound(t *testing.T) {
 	mockStorage := storage.NewMockStorage()
@@ -283,3 +370,818 @@ func TestDuplicateURL(t *testing.T) {
 		t.Errorf("Should have only 1 URL in storage for duplicates, got %d", count)
 	}
 }
+
+// TestGetURLClicksHandler проверяет журнал переходов с метаданными запроса и пагинацией
+func TestGetURLClicksHandler(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	handler := NewURLHandler(mockStorage, WithIPHashSalt("test-salt"))
+
+	apiKey, key, err := auth.CreateKey(context.Background(), mockStorage, "owner", models.RoleEditor)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	for _, testURL := range []*models.URL{
+		{OriginalURL: "https://example.com", ShortCode: "events1", OwnerID: &apiKey.ID},
+		{OriginalURL: "https://example.com", ShortCode: "anon01"},
+	} {
+		if err := mockStorage.SaveURL(context.Background(), testURL); err != nil {
+			t.Fatalf("Failed to create test URL: %v", err)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/:shortCode", handler.RedirectHandler)
+	router.GET("/api/v1/stats/:shortCode/clicks", auth.New(mockStorage).Middleware(), handler.GetURLClicksHandler)
+
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "/events1", nil)
+		req.Header.Set("Referer", "https://news.example.org/post")
+		req.Header.Set("User-Agent", "test-agent")
+		req.Header.Set("Accept-Language", "ru-RU,ru;q=0.9")
+		req.Header.Set("CF-IPCountry", "DE")
+		req.RemoteAddr = "192.0.2.1:12345"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+	}
+
+	// Журнал анонимной ссылки не отдается никому
+	req, _ := http.NewRequest("GET", "/api/v1/stats/anon01/clicks", nil)
+	req.Header.Set("Authorization", "Bearer "+key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for anonymous link, got %d", w.Code)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/v1/stats/events1/clicks?limit=2", nil)
+	req.Header.Set("Authorization", "Bearer "+key)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "ip_hash") {
+		t.Errorf("IP hash should not be exposed: %s", w.Body.String())
+	}
+
+	var response ClicksResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse clicks response: %v", err)
+	}
+
+	if response.Total != 3 {
+		t.Errorf("Expected total 3, got %d", response.Total)
+	}
+	if len(response.Clicks) != 2 {
+		t.Fatalf("Expected 2 clicks on the page, got %d", len(response.Clicks))
+	}
+
+	click := response.Clicks[0]
+	if click.Referrer != "https://news.example.org/post" {
+		t.Errorf("Unexpected referrer %q", click.Referrer)
+	}
+	if click.UserAgent != "test-agent" {
+		t.Errorf("Unexpected user agent %q", click.UserAgent)
+	}
+	if click.AcceptLanguage != "ru-RU,ru;q=0.9" {
+		t.Errorf("Unexpected accept language %q", click.AcceptLanguage)
+	}
+	if click.Country != "DE" {
+		t.Errorf("Unexpected country %q", click.Country)
+	}
+
+	stored, _ := mockStorage.GetClicks(context.Background(), "events1", 1, 0)
+	if len(stored) != 1 || stored[0].IPHash == "" || stored[0].IPHash == "192.0.2.1" {
+		t.Errorf("IP should be stored hashed, got %+v", stored)
+	}
+}
+
+// TestGetURLClicksHandlerErrors проверяет ошибки валидации журнала переходов
+func TestGetURLClicksHandlerErrors(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	handler := NewURLHandler(mockStorage)
+
+	apiKey, key, err := auth.CreateKey(context.Background(), mockStorage, "owner", models.RoleEditor)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	if err := mockStorage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "events2", OwnerID: &apiKey.ID}); err != nil {
+		t.Fatalf("Failed to create test URL: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/api/v1/stats/:shortCode/clicks", auth.New(mockStorage).Middleware(), handler.GetURLClicksHandler)
+
+	tests := []struct {
+		name     string
+		path     string
+		expected int
+	}{
+		{"Unknown code", "/api/v1/stats/missing1/clicks", http.StatusNotFound},
+		{"Invalid code", "/api/v1/stats/inv@lid/clicks", http.StatusBadRequest},
+		{"Invalid limit", "/api/v1/stats/events2/clicks?limit=abc", http.StatusBadRequest},
+		{"Negative offset", "/api/v1/stats/events2/clicks?offset=-1", http.StatusBadRequest},
+		{"Empty log", "/api/v1/stats/events2/clicks", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+key)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d. Body: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+// TestGetURLTimeSeriesHandler проверяет агрегацию переходов по дням с заполнением пропусков нулями
+func TestGetURLTimeSeriesHandler(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	handler := NewURLHandler(mockStorage)
+
+	if err := mockStorage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "series1"}); err != nil {
+		t.Fatalf("Failed to create test URL: %v", err)
+	}
+
+	day := func(d, h int) time.Time {
+		return time.Date(2025, time.March, d, h, 0, 0, 0, time.UTC)
+	}
+	err := mockStorage.SaveClicks(context.Background(), []*models.Click{
+		{ShortCode: "series1", ClickedAt: day(1, 10)},
+		{ShortCode: "series1", ClickedAt: day(1, 23)},
+		{ShortCode: "series1", ClickedAt: day(3, 5)},
+		{ShortCode: "series1", ClickedAt: day(10, 5)}, // вне диапазона
+	})
+	if err != nil {
+		t.Fatalf("Failed to save clicks: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/api/v1/stats/:shortCode/timeseries", handler.GetURLTimeSeriesHandler)
+
+	req, _ := http.NewRequest("GET", "/api/v1/stats/series1/timeseries?from=2025-03-01&to=2025-03-04&interval=day", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	var response TimeSeriesResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse time series response: %v", err)
+	}
+
+	expected := []int64{2, 0, 1}
+	if len(response.Buckets) != len(expected) {
+		t.Fatalf("Expected %d buckets, got %d", len(expected), len(response.Buckets))
+	}
+	for i, count := range expected {
+		if response.Buckets[i].Count != count {
+			t.Errorf("Bucket %d: expected %d clicks, got %d", i, count, response.Buckets[i].Count)
+		}
+	}
+	if response.Total != 3 {
+		t.Errorf("Expected total 3, got %d", response.Total)
+	}
+
+	// Клик в 23:00 UTC 1 марта по Москве относится уже ко 2 марта
+	req, _ = http.NewRequest("GET", "/api/v1/stats/series1/timeseries?from=2025-03-01&to=2025-03-04&interval=day&tz=Europe/Moscow", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse time series response: %v", err)
+	}
+
+	expected = []int64{1, 1, 1}
+	for i, count := range expected {
+		if response.Buckets[i].Count != count {
+			t.Errorf("Moscow bucket %d: expected %d clicks, got %d", i, count, response.Buckets[i].Count)
+		}
+	}
+}
+
+// TestGetURLTimeSeriesHandlerErrors проверяет валидацию параметров временного ряда
+func TestGetURLTimeSeriesHandlerErrors(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	handler := NewURLHandler(mockStorage)
+
+	if err := mockStorage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "series2"}); err != nil {
+		t.Fatalf("Failed to create test URL: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/api/v1/stats/:shortCode/timeseries", handler.GetURLTimeSeriesHandler)
+
+	tests := []struct {
+		name     string
+		query    string
+		expected int
+	}{
+		{"Defaults", "", http.StatusOK},
+		{"Invalid interval", "?interval=month", http.StatusBadRequest},
+		{"Invalid timezone", "?tz=Mars/Olympus", http.StatusBadRequest},
+		{"Invalid from", "?from=yesterday", http.StatusBadRequest},
+		{"From after to", "?from=2025-03-05&to=2025-03-01", http.StatusBadRequest},
+		{"Too many buckets", "?from=2020-01-01&to=2025-01-01&interval=hour", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/api/v1/stats/series2/timeseries"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d. Body: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+// TestShortenURLHandlerAlias проверяет создание ссылки с пользовательским алиасом
+func TestShortenURLHandlerAlias(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	handler := NewURLHandler(mockStorage)
+
+	if err := mockStorage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://taken.example.com", ShortCode: "taken1"}); err != nil {
+		t.Fatalf("Failed to create test URL: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/v1/shorten", handler.ShortenURLHandler)
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"Valid alias", `{"url": "https://example.com", "alias": "my-promo"}`, http.StatusCreated},
+		{"Same URL with another alias", `{"url": "https://example.com", "alias": "promo_2"}`, http.StatusCreated},
+		{"Taken alias", `{"url": "https://example.com", "alias": "taken1"}`, http.StatusConflict},
+		{"Reserved alias", `{"url": "https://example.com", "alias": "Health"}`, http.StatusBadRequest},
+		{"Too short alias", `{"url": "https://example.com", "alias": "ab"}`, http.StatusBadRequest},
+		{"Invalid characters", `{"url": "https://example.com", "alias": "my promo"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/api/v1/shorten", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d. Body: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	url, err := mockStorage.GetURL(context.Background(), "my-promo")
+	if err != nil {
+		t.Fatalf("Alias should be stored: %v", err)
+	}
+	if url.OriginalURL != "https://example.com" {
+		t.Errorf("Expected original URL 'https://example.com', got '%s'", url.OriginalURL)
+	}
+
+	if count := mockStorage.GetURLCount(); count != 3 {
+		t.Errorf("Expected 3 URLs in storage, got %d", count)
+	}
+}
+
+// TestShortenURLHandlerConfiguredLength проверяет, что длина кода берется из настроек генератора
+func TestShortenURLHandlerConfiguredLength(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	allocator := shortcode.NewAllocator(mockStorage, shortcode.Options{Length: 9})
+	handler := NewURLHandler(mockStorage, WithAllocator(allocator))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/v1/shorten", handler.ShortenURLHandler)
+
+	req, _ := http.NewRequest("POST", "/api/v1/shorten", bytes.NewBufferString(`{"url": "https://example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	var response ShortenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.ShortURL) != 9 {
+		t.Errorf("Expected short code of length 9, got %q", response.ShortURL)
+	}
+}
+
+// TestShortenURLHandlerExpiration проверяет создание временных ссылок
+func TestShortenURLHandlerExpiration(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	handler := NewURLHandler(mockStorage)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/v1/shorten", handler.ShortenURLHandler)
+
+	future := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"TTL", `{"url": "https://example.com/ttl", "ttl_seconds": 3600}`, http.StatusCreated},
+		{"Absolute expiry", `{"url": "https://example.com/abs", "expires_at": "` + future + `"}`, http.StatusCreated},
+		{"Expiry in the past", `{"url": "https://example.com/past", "expires_at": "` + past + `"}`, http.StatusBadRequest},
+		{"Non-positive TTL", `{"url": "https://example.com/zero", "ttl_seconds": 0}`, http.StatusBadRequest},
+		{"Both TTL and expiry", `{"url": "https://example.com/both", "ttl_seconds": 60, "expires_at": "` + future + `"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/api/v1/shorten", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d. Body: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	url, err := mockStorage.GetURLByOriginal(context.Background(), "https://example.com/ttl", models.Owner{})
+	if err != nil || url == nil {
+		t.Fatalf("TTL link should be stored: %v", err)
+	}
+	if url.ExpiresAt == nil || time.Until(*url.ExpiresAt) < 59*time.Minute {
+		t.Errorf("Expected expiry in about an hour, got %v", url.ExpiresAt)
+	}
+}
+
+// TestShortenURLHandlerExpiringLinkIsNotReused проверяет, что временная ссылка не выдается
+// повторно на запрос бессрочной и наоборот
+func TestShortenURLHandlerExpiringLinkIsNotReused(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	handler := NewURLHandler(mockStorage)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/v1/shorten", handler.ShortenURLHandler)
+
+	bodies := []string{
+		`{"url": "https://example.com", "ttl_seconds": 60}`,
+		`{"url": "https://example.com"}`,
+		`{"url": "https://example.com", "ttl_seconds": 60}`,
+	}
+	for _, body := range bodies {
+		req, _ := http.NewRequest("POST", "/api/v1/shorten", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusCreated {
+			t.Errorf("Expected status 201 for %s, got %d. Body: %s", body, w.Code, w.Body.String())
+		}
+	}
+
+	if count := mockStorage.GetURLCount(); count != 3 {
+		t.Errorf("Expected 3 URLs in storage, got %d", count)
+	}
+}
+
+// TestRedirectHandlerExpired проверяет ответ 410 Gone для истекшей ссылки
+func TestRedirectHandlerExpired(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	handler := NewURLHandler(mockStorage)
+
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	urls := []*models.URL{
+		{OriginalURL: "https://example.com/old", ShortCode: "expired1", ExpiresAt: &past},
+		{OriginalURL: "https://example.com/new", ShortCode: "active1", ExpiresAt: &future},
+	}
+	for _, url := range urls {
+		if err := mockStorage.SaveURL(context.Background(), url); err != nil {
+			t.Fatalf("Failed to create test URL: %v", err)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/:shortCode", handler.RedirectHandler)
+
+	req, _ := http.NewRequest("GET", "/expired1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusGone {
+		t.Errorf("Expected status 410 for expired link, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/active1", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusFound {
+		t.Errorf("Expected status 302 for active link, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	expired, _ := mockStorage.GetURL(context.Background(), "expired1")
+	if expired.ClickCount != 0 {
+		t.Errorf("Expired link should not count clicks, got %d", expired.ClickCount)
+	}
+}
+
+// TestMaxClicks проверяет одноразовые и N-разовые ссылки
+func TestMaxClicks(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	handler := NewURLHandler(mockStorage)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/v1/shorten", handler.ShortenURLHandler)
+	router.GET("/:shortCode", handler.RedirectHandler)
+
+	for _, body := range []string{
+		`{"url": "https://example.com/doc", "max_clicks": 0}`,
+		`{"url": "https://example.com/doc", "max_clicks": -1}`,
+	} {
+		req, _ := http.NewRequest("POST", "/api/v1/shorten", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, w.Code)
+		}
+	}
+
+	req, _ := http.NewRequest("POST", "/api/v1/shorten", bytes.NewBufferString(`{"url": "https://example.com/doc", "max_clicks": 2}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var response ShortenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	expected := []int{http.StatusFound, http.StatusFound, http.StatusGone, http.StatusGone}
+	for i, status := range expected {
+		req, _ := http.NewRequest("GET", "/"+response.ShortURL, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != status {
+			t.Errorf("Click %d: expected status %d, got %d", i+1, status, w.Code)
+		}
+	}
+
+	url, _ := mockStorage.GetURL(context.Background(), response.ShortURL)
+	if url.ClickCount != 2 {
+		t.Errorf("Expected click count 2, got %d", url.ClickCount)
+	}
+	events, _ := mockStorage.GetClicksCount(context.Background(), response.ShortURL)
+	if events != 2 {
+		t.Errorf("Expected 2 click events, got %d", events)
+	}
+}
+
+// TestPasswordProtectedLink проверяет форму пароля, проверку пароля и ограничение попыток
+func TestPasswordProtectedLink(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	handler := NewURLHandler(mockStorage, WithPasswordLimiter(ratelimit.NewAttemptLimiter(2, time.Minute)))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.LoadHTMLGlob("../../templates/*")
+	router.POST("/api/v1/shorten", handler.ShortenURLHandler)
+	router.GET("/api/v1/stats/:shortCode", handler.GetURLStatsHandler)
+	router.GET("/:shortCode", handler.RedirectHandler)
+	router.POST("/:shortCode", handler.UnlockHandler)
+
+	req, _ := http.NewRequest("POST", "/api/v1/shorten", bytes.NewBufferString(`{"url": "https://example.com/secret", "password": "s3cret"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var response ShortenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	code := response.ShortURL
+
+	stored, _ := mockStorage.GetURL(context.Background(), code)
+	if stored.PasswordHash == "" || stored.PasswordHash == "s3cret" {
+		t.Fatalf("Password should be stored as a hash, got %q", stored.PasswordHash)
+	}
+
+	// Вместо редиректа показывается форма
+	req, _ = http.NewRequest("GET", "/"+code, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `name="password"`) {
+		t.Errorf("Expected password prompt, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	// Статистика не раскрывает адрес назначения
+	req, _ = http.NewRequest("GET", "/api/v1/stats/"+code, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if strings.Contains(w.Body.String(), "example.com/secret") {
+		t.Errorf("Stats should not expose protected URL: %s", w.Body.String())
+	}
+
+	unlock := func(password, ip string) *httptest.ResponseRecorder {
+		form := url.Values{"password": {password}}
+		req, _ := http.NewRequest("POST", "/"+code, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = ip + ":12345"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := unlock("wrong", "192.0.2.1"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for wrong password, got %d", w.Code)
+	}
+
+	w = unlock("s3cret", "192.0.2.1")
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "https://example.com/secret" {
+		t.Errorf("Expected redirect after correct password, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	// Успешный ввод сбрасывает счетчик, после двух ошибок подряд адрес блокируется
+	unlock("wrong", "192.0.2.1")
+	unlock("wrong", "192.0.2.1")
+	w = unlock("s3cret", "192.0.2.1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected status 429 with Retry-After, got %d", w.Code)
+	}
+
+	// Другой IP не заблокирован
+	if w := unlock("s3cret", "192.0.2.2"); w.Code != http.StatusSeeOther {
+		t.Errorf("Expected redirect for another IP, got %d", w.Code)
+	}
+
+	stored, _ = mockStorage.GetURL(context.Background(), code)
+	if stored.ClickCount != 2 {
+		t.Errorf("Expected 2 clicks, got %d", stored.ClickCount)
+	}
+}
+
+// TestPasswordProtectedLinkSpoofedForwardedFor проверяет, что смена X-Forwarded-For
+// не дает обойти ограничение попыток ввода пароля
+func TestPasswordProtectedLinkSpoofedForwardedFor(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		proxies []string
+	}{
+		{"No trusted proxies", nil},
+		// Даже если прокси настроены неверно и заголовку верят, срабатывает общий лимит ссылки
+		{"Trust all proxies", []string{"0.0.0.0/0"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := storage.NewMockStorage()
+			handler := NewURLHandler(mockStorage, WithPasswordLimiter(ratelimit.NewAttemptLimiter(2, time.Minute)))
+
+			gin.SetMode(gin.TestMode)
+			router := gin.Default()
+			if err := router.SetTrustedProxies(tt.proxies); err != nil {
+				t.Fatalf("Failed to set trusted proxies: %v", err)
+			}
+			router.LoadHTMLGlob("../../templates/*")
+			router.POST("/:shortCode", handler.UnlockHandler)
+
+			hash, _ := utils.HashPassword("s3cret")
+			if err := mockStorage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com/secret", ShortCode: "abc123", PasswordHash: hash}); err != nil {
+				t.Fatalf("Failed to create test URL: %v", err)
+			}
+
+			var w *httptest.ResponseRecorder
+			for i := 0; i <= 2*ratelimit.TargetFactor; i++ {
+				form := url.Values{"password": {"wrong"}}
+				req, _ := http.NewRequest("POST", "/abc123", strings.NewReader(form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i))
+				req.RemoteAddr = "192.0.2.1:12345"
+				w = httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				if w.Code == http.StatusTooManyRequests {
+					break
+				}
+			}
+			if w.Code != http.StatusTooManyRequests {
+				t.Errorf("Expected status 429 with rotating X-Forwarded-For, got %d", w.Code)
+			}
+		})
+	}
+}
+
+// TestPasswordProtectedLinkIsNotReused проверяет, что ссылка с паролем не выдается для обычного запроса
+func TestPasswordProtectedLinkIsNotReused(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	handler := NewURLHandler(mockStorage)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/v1/shorten", handler.ShortenURLHandler)
+
+	bodies := []string{
+		`{"url": "https://example.com", "password": "s3cret"}`,
+		`{"url": "https://example.com"}`,
+	}
+	for _, body := range bodies {
+		req, _ := http.NewRequest("POST", "/api/v1/shorten", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusCreated {
+			t.Errorf("Expected status 201 for %s, got %d", body, w.Code)
+		}
+	}
+}
+
+// TestBlockedDomains проверяет, что список блокировки применяется к новым и существующим ссылкам
+// и меняется без пересоздания обработчика
+func TestBlockedDomains(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	store := settings.NewStore(&settings.Settings{})
+	handler := NewURLHandler(mockStorage, WithSettings(store))
+
+	err := mockStorage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://www.evil.example/login", ShortCode: "abc123"})
+	if err != nil {
+		t.Fatalf("Failed to create test URL: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/v1/shorten", handler.ShortenURLHandler)
+	router.GET("/:shortCode", handler.RedirectHandler)
+
+	shorten := func() int {
+		req, _ := http.NewRequest("POST", "/api/v1/shorten", bytes.NewBufferString(`{"url": "https://evil.example/"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	redirect := func() int {
+		req, _ := http.NewRequest("GET", "/abc123", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := redirect(); code != http.StatusFound {
+		t.Errorf("Expected status 302 before blocking, got %d", code)
+	}
+
+	store.Update(&settings.Settings{BlockedDomains: []string{"evil.example"}})
+
+	if code := shorten(); code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for blocked domain, got %d", code)
+	}
+	if code := redirect(); code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for link to blocked domain, got %d", code)
+	}
+}
+
+// TestLinkOwnership проверяет, что ссылка, созданная с API ключом, принадлежит ключу:
+// статистика и удаление доступны только владельцу
+func TestLinkOwnership(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	handler := NewURLHandler(mockStorage)
+
+	_, owner, err := auth.CreateKey(context.Background(), mockStorage, "owner", models.RoleEditor)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	_, other, err := auth.CreateKey(context.Background(), mockStorage, "other", models.RoleEditor)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	api := router.Group("/api/v1", auth.New(mockStorage).Middleware())
+	api.POST("/shorten", handler.ShortenURLHandler)
+	api.GET("/stats/:shortCode", handler.GetURLStatsHandler)
+	api.GET("/stats/:shortCode/clicks", handler.GetURLClicksHandler)
+	api.DELETE("/urls/:shortCode", auth.RequireAuth(), handler.DeleteURLHandler)
+
+	send := func(method, path, body, key string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("POST", "/api/v1/shorten", `{"url": "https://example.com/owned"}`, owner)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var response ShortenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	code := response.ShortURL
+
+	// Анонимный клиент и другой ключ не получают чужую ссылку при дедупликации
+	w = send("POST", "/api/v1/shorten", `{"url": "https://example.com/owned"}`, other)
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected a new link for another key, got %d", w.Code)
+	}
+	w = send("POST", "/api/v1/shorten", `{"url": "https://example.com/owned"}`, owner)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), code) {
+		t.Errorf("Expected the owner's link to be reused, got %d: %s", w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		want   int
+	}{
+		{"Stats without key", "GET", "/api/v1/stats/" + code, "", http.StatusUnauthorized},
+		{"Stats with other key", "GET", "/api/v1/stats/" + code, other, http.StatusForbidden},
+		{"Clicks with other key", "GET", "/api/v1/stats/" + code + "/clicks", other, http.StatusForbidden},
+		{"Stats with owner key", "GET", "/api/v1/stats/" + code, owner, http.StatusOK},
+		{"Delete without key", "DELETE", "/api/v1/urls/" + code, "", http.StatusUnauthorized},
+		{"Delete with other key", "DELETE", "/api/v1/urls/" + code, other, http.StatusForbidden},
+		{"Delete with owner key", "DELETE", "/api/v1/urls/" + code, owner, http.StatusNoContent},
+		{"Stats after delete", "GET", "/api/v1/stats/" + code, owner, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := send(tt.method, tt.path, "", tt.key)
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d. Body: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+// TestDeleteAnonymousLink проверяет, что анонимную ссылку нельзя удалить: у нее нет владельца
+func TestDeleteAnonymousLink(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	handler := NewURLHandler(mockStorage)
+	_, key, err := auth.CreateKey(context.Background(), mockStorage, "ci", models.RoleEditor)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	if err := mockStorage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "abc123"}); err != nil {
+		t.Fatalf("Failed to create test URL: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.DELETE("/api/v1/urls/:shortCode", auth.New(mockStorage).Middleware(), auth.RequireAuth(), handler.DeleteURLHandler)
+
+	req, _ := http.NewRequest("DELETE", "/api/v1/urls/abc123", nil)
+	req.Header.Set("Authorization", "Bearer "+key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Code)
+	}
+	if mockStorage.GetURLCount() != 1 {
+		t.Error("Anonymous link should not be deleted")
+	}
+}
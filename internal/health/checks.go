@@ -0,0 +1,43 @@
+// internal/health/checks.go
+
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PostgresChecker проверяет соединение с базой данных
+func PostgresChecker(db *sql.DB) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	})
+}
+
+// MigrationChecker проверяет, что схема базы данных не старее миграций, с которыми собран сервис.
+// Более новая схема допустима: во время выкладки старые инстансы работают с уже обновленной базой
+func MigrationChecker(db *sql.DB, expected int64) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		var current int64
+		err := db.QueryRowContext(ctx,
+			`SELECT COALESCE(MAX(version_id), 0) FROM goose_db_version WHERE is_applied`,
+		).Scan(&current)
+		if err != nil {
+			return fmt.Errorf("failed to get schema version: %w", err)
+		}
+		if current < expected {
+			return fmt.Errorf("schema version %d is behind expected %d", current, expected)
+		}
+		return nil
+	})
+}
+
+// RedisChecker проверяет соединение с Redis
+func RedisChecker(client redis.UniversalClient) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	})
+}
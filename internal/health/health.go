@@ -0,0 +1,176 @@
+// internal/health/health.go
+
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// Статусы проверок
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusDegraded    = "degraded" // Недоступна необязательная зависимость, трафик принимается
+	StatusShutdown    = "shutting down"
+)
+
+// Checker проверяет одну зависимость сервиса
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc позволяет использовать обычную функцию как Checker
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Options задает параметры проверок готовности
+type Options struct {
+	CacheTTL time.Duration // Сколько переиспользовать результат проверок
+	Timeout  time.Duration // Ограничение на одну проверку
+	Debug    bool          // Показывать текст ошибок в ответе
+	Ready    func() bool   // Признак готовности сервера; false во время остановки
+}
+
+// CheckResult результат одной проверки
+type CheckResult struct {
+	Status     string  `json:"status"`
+	DurationMs float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// Report ответ /readyz
+type Report struct {
+	Status    string                  `json:"status"`
+	CheckedAt time.Time               `json:"checked_at"`
+	Checks    map[string]*CheckResult `json:"checks,omitempty"`
+}
+
+type check struct {
+	name     string
+	checker  Checker
+	optional bool
+}
+
+// Health выполняет проверки зависимостей и отдает liveness и readiness пробы
+type Health struct {
+	opts   Options
+	checks []check
+
+	mu     sync.Mutex
+	cached *Report
+}
+
+func New(opts Options) *Health {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 2 * time.Second
+	}
+	return &Health{opts: opts}
+}
+
+// Register добавляет проверку готовности. Вызывается до начала обслуживания запросов
+func (h *Health) Register(name string, checker Checker) {
+	h.checks = append(h.checks, check{name: name, checker: checker})
+}
+
+// RegisterOptional добавляет проверку зависимости, без которой сервис продолжает работать
+// (например, кэш с откатом на базу). Ее сбой переводит статус в degraded, но не снимает готовность
+func (h *Health) RegisterOptional(name string, checker Checker) {
+	h.checks = append(h.checks, check{name: name, checker: checker, optional: true})
+}
+
+// Check возвращает результат всех проверок. Проверки выполняются параллельно,
+// результат переиспользуется в течение CacheTTL, чтобы частые пробы не нагружали базу
+func (h *Health) Check(ctx context.Context) *Report {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cached != nil && time.Since(h.cached.CheckedAt) < h.opts.CacheTTL {
+		return h.cached
+	}
+
+	report := &Report{
+		Status:    StatusOK,
+		CheckedAt: time.Now(),
+		Checks:    make(map[string]*CheckResult, len(h.checks)),
+	}
+	results := make([]*CheckResult, len(h.checks))
+
+	var wg sync.WaitGroup
+	for i, c := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = h.run(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	for i, c := range h.checks {
+		report.Checks[c.name] = results[i]
+		switch {
+		case results[i].Status == StatusOK:
+		case !c.optional:
+			report.Status = StatusUnavailable
+		case report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+
+	h.cached = report
+	return report
+}
+
+// run выполняет одну проверку с таймаутом
+func (h *Health) run(ctx context.Context, c check) *CheckResult {
+	// Результат кэшируется для всех клиентов, поэтому отмена одного запроса не должна его портить
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), h.opts.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.checker.Check(ctx)
+	result := &CheckResult{
+		Status:     StatusOK,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("check", c.name).Msg("Health check failed")
+		result.Status = StatusUnavailable
+		if h.opts.Debug {
+			result.Error = err.Error()
+		}
+	}
+	return result
+}
+
+// LivenessHandler отвечает 200, пока процесс способен обслуживать запросы.
+// Зависимости не проверяются: их недоступность не лечится перезапуском
+func (h *Health) LivenessHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": StatusOK})
+}
+
+// ReadinessHandler отвечает 200, если сервер готов принимать трафик и все зависимости доступны,
+// иначе 503. Во время остановки проверки не выполняются
+func (h *Health) ReadinessHandler(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+
+	if h.opts.Ready != nil && !h.opts.Ready() {
+		c.JSON(http.StatusServiceUnavailable, Report{Status: StatusShutdown, CheckedAt: time.Now()})
+		return
+	}
+
+	report := h.Check(c.Request.Context())
+	status := http.StatusOK
+	if report.Status == StatusUnavailable {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
@@ -0,0 +1,145 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRouter(h *Health) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/livez", h.LivenessHandler)
+	router.GET("/readyz", h.ReadinessHandler)
+	return router
+}
+
+func getReport(t *testing.T, router *gin.Engine) (int, Report) {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+
+	var report Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	return w.Code, report
+}
+
+var errDatabase = errors.New("dial tcp 10.0.0.5:5432: connection refused")
+
+func TestReadiness(t *testing.T) {
+	t.Run("All checks pass", func(t *testing.T) {
+		h := New(Options{})
+		h.Register("postgres", CheckerFunc(func(context.Context) error { return nil }))
+		h.Register("migrations", CheckerFunc(func(context.Context) error { return nil }))
+
+		code, report := getReport(t, setupRouter(h))
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, StatusOK, report.Status)
+		require.Len(t, report.Checks, 2)
+		assert.Equal(t, StatusOK, report.Checks["postgres"].Status)
+		assert.GreaterOrEqual(t, report.Checks["postgres"].DurationMs, 0.0)
+	})
+
+	t.Run("Failed check hides error", func(t *testing.T) {
+		h := New(Options{})
+		h.Register("postgres", CheckerFunc(func(context.Context) error { return errDatabase }))
+
+		w := httptest.NewRecorder()
+		setupRouter(h).ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.NotContains(t, w.Body.String(), "10.0.0.5")
+	})
+
+	t.Run("Debug shows error", func(t *testing.T) {
+		h := New(Options{Debug: true})
+		h.Register("postgres", CheckerFunc(func(context.Context) error { return errDatabase }))
+
+		code, report := getReport(t, setupRouter(h))
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, StatusUnavailable, report.Status)
+		assert.Equal(t, errDatabase.Error(), report.Checks["postgres"].Error)
+	})
+
+	t.Run("Optional check degrades", func(t *testing.T) {
+		h := New(Options{})
+		h.Register("postgres", CheckerFunc(func(context.Context) error { return nil }))
+		h.RegisterOptional("redis", CheckerFunc(func(context.Context) error { return errDatabase }))
+
+		code, report := getReport(t, setupRouter(h))
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, StatusDegraded, report.Status)
+		assert.Equal(t, StatusUnavailable, report.Checks["redis"].Status)
+	})
+
+	t.Run("Slow check times out", func(t *testing.T) {
+		h := New(Options{Timeout: 20 * time.Millisecond})
+		h.Register("postgres", CheckerFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}))
+
+		code, _ := getReport(t, setupRouter(h))
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+	})
+
+	t.Run("Shutting down", func(t *testing.T) {
+		var calls atomic.Int32
+		h := New(Options{Ready: func() bool { return false }})
+		h.Register("postgres", CheckerFunc(func(context.Context) error {
+			calls.Add(1)
+			return nil
+		}))
+
+		code, report := getReport(t, setupRouter(h))
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, StatusShutdown, report.Status)
+		assert.Zero(t, calls.Load())
+	})
+}
+
+func TestReadinessCache(t *testing.T) {
+	var calls atomic.Int32
+	h := New(Options{CacheTTL: time.Hour})
+	h.Register("postgres", CheckerFunc(func(context.Context) error {
+		calls.Add(1)
+		return nil
+	}))
+
+	router := setupRouter(h)
+	for i := 0; i < 5; i++ {
+		code, _ := getReport(t, router)
+		assert.Equal(t, http.StatusOK, code)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestLiveness(t *testing.T) {
+	h := New(Options{Ready: func() bool { return false }})
+	h.Register("postgres", CheckerFunc(func(context.Context) error { return errDatabase }))
+
+	w := httptest.NewRecorder()
+	setupRouter(h).ServeHTTP(w, httptest.NewRequest("GET", "/livez", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRedisChecker(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	defer client.Close()
+
+	checker := RedisChecker(client)
+	assert.NoError(t, checker.Check(context.Background()))
+
+	mr.Close()
+	assert.Error(t, checker.Check(context.Background()))
+}
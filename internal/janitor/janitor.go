@@ -0,0 +1,152 @@
+// internal/janitor/janitor.go
+
+package janitor
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/audit"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// Режимы обработки истекших ссылок
+const (
+	ModePurge   = "purge"   // Удалить без следа
+	ModeArchive = "archive" // Перенести в urls_archive
+)
+
+// actor исполнитель изменений janitor в журнале аудита
+const actor = audit.SystemActorPrefix + "janitor"
+
+// Options задает параметры фоновой очистки
+type Options struct {
+	Interval  time.Duration // Период запуска очистки
+	Mode      string        // purge или archive
+	BatchSize int           // Максимум строк, удаляемых одним запросом
+
+	Audit          storage.AuditStorage // Журнал аудита; nil - не чистится
+	AuditRetention time.Duration        // Сколько хранить события аудита; 0 - бессрочно
+}
+
+// Janitor периодически удаляет или архивирует ссылки с истекшим сроком действия
+type Janitor struct {
+	storage storage.Storage
+	opts    Options
+
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	started atomic.Bool
+}
+
+func New(storage storage.Storage, opts Options) *Janitor {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Hour
+	}
+	if opts.Mode == "" {
+		opts.Mode = ModePurge
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+
+	return &Janitor{
+		storage: storage,
+		opts:    opts,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start запускает фоновую горутину очистки
+func (j *Janitor) Start() {
+	if j.started.CompareAndSwap(false, true) {
+		go j.run()
+	}
+}
+
+// Stop останавливает очистку и дожидается завершения текущего прохода
+func (j *Janitor) Stop() {
+	j.once.Do(func() {
+		close(j.stop)
+	})
+	if j.started.Load() {
+		<-j.done
+	}
+}
+
+// RunOnce обрабатывает все ссылки, истекшие к текущему моменту, батчами по BatchSize
+func (j *Janitor) RunOnce() (int64, error) {
+	now := time.Now()
+	archive := j.opts.Mode == ModeArchive
+
+	ctx := audit.WithOrigin(context.Background(), audit.Origin{Actor: actor})
+	return j.batches(func() (int64, error) {
+		return j.storage.PurgeExpiredURLs(ctx, now, j.opts.BatchSize, archive)
+	})
+}
+
+// RunAuditRetention удаляет события аудита старше AuditRetention батчами по BatchSize
+func (j *Janitor) RunAuditRetention() (int64, error) {
+	if j.opts.Audit == nil || j.opts.AuditRetention <= 0 {
+		return 0, nil
+	}
+	before := time.Now().Add(-j.opts.AuditRetention)
+	return j.batches(func() (int64, error) {
+		return j.opts.Audit.PurgeAuditEvents(context.Background(), before, j.opts.BatchSize)
+	})
+}
+
+// batches повторяет purge, пока он удаляет полные батчи
+func (j *Janitor) batches(purge func() (int64, error)) (int64, error) {
+	var total int64
+	for {
+		purged, err := purge()
+		total += purged
+		if err != nil {
+			return total, err
+		}
+		if purged < int64(j.opts.BatchSize) {
+			return total, nil
+		}
+
+		// Между батчами проверяем, не пора ли остановиться
+		select {
+		case <-j.stop:
+			return total, nil
+		default:
+		}
+	}
+}
+
+func (j *Janitor) run() {
+	defer close(j.done)
+
+	ticker := time.NewTicker(j.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			purged, err := j.RunOnce()
+			if err != nil {
+				log.Error().Err(err).Int64("purged", purged).Msg("Failed to clean up expired URLs")
+			} else if purged > 0 {
+				log.Info().Int64("purged", purged).Str("mode", j.opts.Mode).Msg("Expired URLs cleaned up")
+			}
+
+			purged, err = j.RunAuditRetention()
+			if err != nil {
+				log.Error().Err(err).Int64("purged", purged).Msg("Failed to purge old audit events")
+			} else if purged > 0 {
+				log.Info().Int64("purged", purged).Msg("Old audit events purged")
+			}
+		case <-j.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package janitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func seed(t *testing.T) *storage.MockStorage {
+	mockStorage := storage.NewMockStorage()
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	urls := []*models.URL{
+		{OriginalURL: "https://expired1.com", ShortCode: "expired1", ExpiresAt: &past},
+		{OriginalURL: "https://expired2.com", ShortCode: "expired2", ExpiresAt: &past},
+		{OriginalURL: "https://expired3.com", ShortCode: "expired3", ExpiresAt: &past},
+		{OriginalURL: "https://future.com", ShortCode: "future1", ExpiresAt: &future},
+		{OriginalURL: "https://forever.com", ShortCode: "forever1"},
+	}
+	for _, url := range urls {
+		assert.NoError(t, mockStorage.SaveURL(context.Background(), url))
+	}
+	return mockStorage
+}
+
+func TestJanitor_RunOncePurgesInBatches(t *testing.T) {
+	mockStorage := seed(t)
+	janitor := New(mockStorage, Options{Mode: ModePurge, BatchSize: 2})
+
+	purged, err := janitor.RunOnce()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), purged)
+
+	assert.Equal(t, 2, mockStorage.GetURLCount())
+	assert.Empty(t, mockStorage.GetArchivedURLs())
+
+	for _, code := range []string{"future1", "forever1"} {
+		exists, err := mockStorage.URLExists(context.Background(), code)
+		assert.NoError(t, err)
+		assert.True(t, exists, code)
+	}
+}
+
+func TestJanitor_RunOnceArchives(t *testing.T) {
+	mockStorage := seed(t)
+	janitor := New(mockStorage, Options{Mode: ModeArchive})
+
+	purged, err := janitor.RunOnce()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), purged)
+	assert.Len(t, mockStorage.GetArchivedURLs(), 3)
+	assert.Equal(t, 2, mockStorage.GetURLCount())
+}
+
+func TestJanitor_RunOnceRecordsAudit(t *testing.T) {
+	mockStorage := seed(t)
+	janitor := New(mockStorage, Options{Mode: ModeArchive})
+
+	_, err := janitor.RunOnce()
+	assert.NoError(t, err)
+
+	events, err := mockStorage.GetAuditEvents(context.Background(), models.AuditFilter{Actor: "system:janitor"}, 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, events, 3)
+	for _, event := range events {
+		assert.Equal(t, models.AuditLinkArchive, event.Action)
+		assert.NotNil(t, event.Before)
+		assert.Nil(t, event.After)
+	}
+}
+
+func TestJanitor_RunAuditRetention(t *testing.T) {
+	mockStorage := seed(t)
+
+	// Без срока хранения журнал не чистится
+	purged, err := New(mockStorage, Options{Audit: mockStorage}).RunAuditRetention()
+	assert.NoError(t, err)
+	assert.Zero(t, purged)
+
+	time.Sleep(10 * time.Millisecond)
+	janitor := New(mockStorage, Options{BatchSize: 2, Audit: mockStorage, AuditRetention: 5 * time.Millisecond})
+	purged, err = janitor.RunAuditRetention()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), purged)
+
+	count, err := mockStorage.GetAuditEventsCount(context.Background(), models.AuditFilter{})
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestJanitor_StartStop(t *testing.T) {
+	mockStorage := seed(t)
+	janitor := New(mockStorage, Options{Interval: 10 * time.Millisecond})
+	janitor.Start()
+
+	assert.Eventually(t, func() bool {
+		return mockStorage.GetURLCount() == 2
+	}, time.Second, 10*time.Millisecond)
+
+	janitor.Stop()
+	// Повторная остановка безопасна
+	janitor.Stop()
+}
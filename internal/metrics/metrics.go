@@ -0,0 +1,173 @@
+// internal/metrics/metrics.go
+
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/clicks"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace префикс всех метрик сервиса
+const namespace = "urlshortener"
+
+// Metrics хранит метрики сервиса в собственном реестре Prometheus.
+// Методы безопасно вызывать на nil: так обработчики и хранилище работают без метрик в тестах
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpDuration    *prometheus.HistogramVec
+	redirects       *prometheus.CounterVec
+	shortens        *prometheus.CounterVec
+	storageDuration *prometheus.HistogramVec
+	storageErrors   *prometheus.CounterVec
+}
+
+// New создает реестр с метриками сервиса, Go runtime и процесса
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Длительность обработки HTTP запросов по маршруту, методу и статусу",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		redirects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "redirects_total",
+			Help:      "Количество запросов на переход по короткой ссылке по статусу ответа",
+		}, []string{"status"}),
+		shortens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "shorten_requests_total",
+			Help:      "Количество запросов на сокращение ссылки по статусу ответа",
+		}, []string{"status"}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_operation_duration_seconds",
+			Help:      "Длительность операций хранилища по методу",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"method"}),
+		storageErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "storage_errors_total",
+			Help:      "Количество ошибок хранилища по методу (без ErrNotFound)",
+		}, []string{"method"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpDuration,
+		m.redirects,
+		m.shortens,
+		m.storageDuration,
+		m.storageErrors,
+	)
+	return m
+}
+
+// Registry возвращает реестр для регистрации дополнительных метрик
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// RegisterDBStats публикует статистику пула соединений sql.DB
+func (m *Metrics) RegisterDBStats(db *sql.DB, dbName string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// RegisterMemoryCacheStats публикует статистику LRU кэша ссылок в памяти
+func (m *Metrics) RegisterMemoryCacheStats(stats func() storage.MemoryCacheStats) {
+	m.registry.MustRegister(
+		gaugeFunc("memory_cache_entries", "Количество записей в кэше ссылок в памяти",
+			func() float64 { return float64(stats().Size) }),
+		counterFunc("memory_cache_hits_total", "Запросов ссылок, обслуженных кэшем в памяти",
+			func() float64 { return float64(stats().Hits) }),
+		counterFunc("memory_cache_misses_total", "Запросов ссылок, ушедших из кэша в памяти в хранилище",
+			func() float64 { return float64(stats().Misses) }),
+		counterFunc("memory_cache_collapsed_total", "Промахов кэша в памяти, присоединившихся к уже идущему запросу",
+			func() float64 { return float64(stats().Collapsed) }),
+		counterFunc("memory_cache_evictions_total", "Записей, вытесненных из кэша в памяти по размеру",
+			func() float64 { return float64(stats().Evictions) }),
+	)
+}
+
+// RegisterClickRecorderStats публикует статистику асинхронного регистратора кликов
+func (m *Metrics) RegisterClickRecorderStats(stats func() clicks.Stats) {
+	m.registry.MustRegister(
+		gaugeFunc("click_queue_depth", "Количество событий кликов, ожидающих записи",
+			func() float64 { return float64(stats().QueueDepth) }),
+		counterFunc("clicks_recorded_total", "Принято событий кликов",
+			func() float64 { return float64(stats().Recorded) }),
+		counterFunc("clicks_dropped_total", "Потеряно событий кликов (переполнение очереди или ошибка записи)",
+			func() float64 { return float64(stats().Dropped) }),
+		counterFunc("clicks_flushed_total", "Записано событий кликов в хранилище",
+			func() float64 { return float64(stats().Flushed) }),
+		counterFunc("click_flush_errors_total", "Количество неудачных записей батча кликов",
+			func() float64 { return float64(stats().FlushErrors) }),
+	)
+}
+
+func gaugeFunc(name, help string, value func() float64) prometheus.GaugeFunc {
+	return prometheus.NewGaugeFunc(prometheus.GaugeOpts{Namespace: namespace, Name: name, Help: help}, value)
+}
+
+func counterFunc(name, help string, value func() float64) prometheus.CounterFunc {
+	return prometheus.NewCounterFunc(prometheus.CounterOpts{Namespace: namespace, Name: name, Help: help}, value)
+}
+
+// Handler возвращает HTTP обработчик для /metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware измеряет длительность запросов. Маршрут берется из шаблона gin
+// (например, /:shortCode), чтобы короткие коды не раздували кардинальность меток
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.httpDuration.
+			WithLabelValues(route, c.Request.Method, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// RedirectServed учитывает обработанный запрос на переход
+func (m *Metrics) RedirectServed(status int) {
+	if m == nil {
+		return
+	}
+	m.redirects.WithLabelValues(strconv.Itoa(status)).Inc()
+}
+
+// ShortenServed учитывает обработанный запрос на сокращение
+func (m *Metrics) ShortenServed(status int) {
+	if m == nil {
+		return
+	}
+	m.shortens.WithLabelValues(strconv.Itoa(status)).Inc()
+}
+
+// observeStorage учитывает длительность и результат операции хранилища
+func (m *Metrics) observeStorage(method string, start time.Time, failed bool) {
+	m.storageDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if failed {
+		m.storageErrors.WithLabelValues(method).Inc()
+	}
+}
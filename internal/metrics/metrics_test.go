@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/drerr0r/url-shortener/internal/clicks"
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingStorage возвращает ошибку при сохранении ссылки
+type failingStorage struct {
+	*storage.MockStorage
+}
+
+func (s *failingStorage) SaveURL(ctx context.Context, url *models.URL) error {
+	return errors.New("database unavailable")
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := New()
+
+	router := gin.New()
+	router.Use(m.Middleware())
+	router.GET("/:shortCode", func(c *gin.Context) {
+		c.Status(http.StatusFound)
+	})
+	router.GET("/metrics", gin.WrapH(m.Handler()))
+
+	for _, path := range []string{"/abc123", "/xyz789", "/abc123/unknown"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	}
+
+	assert.Equal(t, 2, testutil.CollectAndCount(m.httpDuration))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `urlshortener_http_request_duration_seconds_count{method="GET",route="/:shortCode",status="302"} 2`)
+	assert.Contains(t, body, `route="unmatched",status="404"`)
+	assert.Contains(t, body, "go_goroutines")
+	assert.False(t, strings.Contains(body, "abc123"), "short codes must not leak into labels")
+}
+
+func TestCounters(t *testing.T) {
+	m := New()
+
+	m.RedirectServed(http.StatusFound)
+	m.RedirectServed(http.StatusFound)
+	m.RedirectServed(http.StatusGone)
+	m.ShortenServed(http.StatusCreated)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.redirects.WithLabelValues("302")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.redirects.WithLabelValues("410")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.shortens.WithLabelValues("201")))
+
+	// Без метрик вызовы ничего не делают
+	var disabled *Metrics
+	disabled.RedirectServed(http.StatusFound)
+	disabled.ShortenServed(http.StatusCreated)
+}
+
+func TestInstrumentedStorage(t *testing.T) {
+	m := New()
+	mockStorage := storage.NewMockStorage()
+	instrumented := NewInstrumentedStorage(mockStorage, m)
+
+	err := instrumented.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "abc123"})
+	assert.NoError(t, err)
+	_, err = instrumented.GetURL(context.Background(), "abc123")
+	assert.NoError(t, err)
+	_, err = instrumented.GetURL(context.Background(), "missing")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	failing := NewInstrumentedStorage(&failingStorage{MockStorage: mockStorage}, m)
+	assert.Error(t, failing.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "xyz789"}))
+
+	assert.Equal(t, 2, testutil.CollectAndCount(m.storageDuration))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.storageErrors.WithLabelValues("GetURL")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.storageErrors.WithLabelValues("SaveURL")))
+}
+
+func TestRegisterDBStats(t *testing.T) {
+	m := New()
+	m.RegisterDBStats(&sql.DB{}, "urlshortener")
+
+	count, err := testutil.GatherAndCount(m.Registry(), "go_sql_open_connections")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestRegisterCacheAndRecorderStats(t *testing.T) {
+	m := New()
+	m.RegisterMemoryCacheStats(func() storage.MemoryCacheStats {
+		return storage.MemoryCacheStats{Size: 3, Hits: 10, Misses: 4, Collapsed: 2, Evictions: 1}
+	})
+	m.RegisterClickRecorderStats(func() clicks.Stats {
+		return clicks.Stats{QueueDepth: 7, Recorded: 100, Dropped: 5, Flushed: 95, FlushErrors: 1}
+	})
+
+	expected := `
+# HELP urlshortener_click_queue_depth Количество событий кликов, ожидающих записи
+# TYPE urlshortener_click_queue_depth gauge
+urlshortener_click_queue_depth 7
+# HELP urlshortener_clicks_dropped_total Потеряно событий кликов (переполнение очереди или ошибка записи)
+# TYPE urlshortener_clicks_dropped_total counter
+urlshortener_clicks_dropped_total 5
+# HELP urlshortener_memory_cache_hits_total Запросов ссылок, обслуженных кэшем в памяти
+# TYPE urlshortener_memory_cache_hits_total counter
+urlshortener_memory_cache_hits_total 10
+# HELP urlshortener_memory_cache_misses_total Запросов ссылок, ушедших из кэша в памяти в хранилище
+# TYPE urlshortener_memory_cache_misses_total counter
+urlshortener_memory_cache_misses_total 4
+`
+	err := testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected),
+		"urlshortener_click_queue_depth", "urlshortener_clicks_dropped_total",
+		"urlshortener_memory_cache_hits_total", "urlshortener_memory_cache_misses_total")
+	assert.NoError(t, err)
+
+	count, err := testutil.GatherAndCount(m.Registry(), "urlshortener_click_flush_errors_total", "urlshortener_memory_cache_entries")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
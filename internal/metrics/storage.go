@@ -0,0 +1,180 @@
+// internal/metrics/storage.go
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/storage"
+)
+
+// InstrumentedStorage декоратор Storage, измеряющий длительность и ошибки каждого метода
+type InstrumentedStorage struct {
+	storage storage.Storage
+	metrics *Metrics
+}
+
+// NewInstrumentedStorage оборачивает хранилище сбором метрик
+func NewInstrumentedStorage(storage storage.Storage, metrics *Metrics) *InstrumentedStorage {
+	return &InstrumentedStorage{storage: storage, metrics: metrics}
+}
+
+// observe фиксирует результат операции. Ожидаемые ошибки предметной области
+// (ссылка не найдена, код занят, лимит исчерпан) сбоями хранилища не считаются
+func (s *InstrumentedStorage) observe(method string, start time.Time, err error) {
+	failed := err != nil &&
+		!errors.Is(err, storage.ErrNotFound) &&
+		!errors.Is(err, storage.ErrAlreadyExists) &&
+		!errors.Is(err, storage.ErrClickLimitReached)
+	s.metrics.observeStorage(method, start, failed)
+}
+
+func (s *InstrumentedStorage) SaveURL(ctx context.Context, url *models.URL) error {
+	start := time.Now()
+	err := s.storage.SaveURL(ctx, url)
+	s.observe("SaveURL", start, err)
+	return err
+}
+
+func (s *InstrumentedStorage) GetURL(ctx context.Context, shortCode string) (*models.URL, error) {
+	start := time.Now()
+	url, err := s.storage.GetURL(ctx, shortCode)
+	s.observe("GetURL", start, err)
+	return url, err
+}
+
+func (s *InstrumentedStorage) GetURLByOriginal(ctx context.Context, originalURL string, owner models.Owner) (*models.URL, error) {
+	start := time.Now()
+	url, err := s.storage.GetURLByOriginal(ctx, originalURL, owner)
+	s.observe("GetURLByOriginal", start, err)
+	return url, err
+}
+
+func (s *InstrumentedStorage) URLExists(ctx context.Context, shortCode string) (bool, error) {
+	start := time.Now()
+	exists, err := s.storage.URLExists(ctx, shortCode)
+	s.observe("URLExists", start, err)
+	return exists, err
+}
+
+func (s *InstrumentedStorage) DeleteURL(ctx context.Context, shortCode string) error {
+	start := time.Now()
+	err := s.storage.DeleteURL(ctx, shortCode)
+	s.observe("DeleteURL", start, err)
+	return err
+}
+
+func (s *InstrumentedStorage) GetURLs(ctx context.Context, limit, offset int) ([]*models.URL, error) {
+	start := time.Now()
+	urls, err := s.storage.GetURLs(ctx, limit, offset)
+	s.observe("GetURLs", start, err)
+	return urls, err
+}
+
+func (s *InstrumentedStorage) GetURLsCount(ctx context.Context) (int, error) {
+	start := time.Now()
+	count, err := s.storage.GetURLsCount(ctx)
+	s.observe("GetURLsCount", start, err)
+	return count, err
+}
+
+func (s *InstrumentedStorage) GetURLsByOwner(ctx context.Context, owner models.Owner, limit, offset int) ([]*models.URL, error) {
+	start := time.Now()
+	urls, err := s.storage.GetURLsByOwner(ctx, owner, limit, offset)
+	s.observe("GetURLsByOwner", start, err)
+	return urls, err
+}
+
+func (s *InstrumentedStorage) GetURLsCountByOwner(ctx context.Context, owner models.Owner) (int, error) {
+	start := time.Now()
+	count, err := s.storage.GetURLsCountByOwner(ctx, owner)
+	s.observe("GetURLsCountByOwner", start, err)
+	return count, err
+}
+
+func (s *InstrumentedStorage) SearchURLs(ctx context.Context, query string, limit, offset int) ([]*models.URL, error) {
+	start := time.Now()
+	urls, err := s.storage.SearchURLs(ctx, query, limit, offset)
+	s.observe("SearchURLs", start, err)
+	return urls, err
+}
+
+func (s *InstrumentedStorage) SearchURLsCount(ctx context.Context, query string) (int, error) {
+	start := time.Now()
+	count, err := s.storage.SearchURLsCount(ctx, query)
+	s.observe("SearchURLsCount", start, err)
+	return count, err
+}
+
+func (s *InstrumentedStorage) SetURLDisabled(ctx context.Context, shortCode string, disabled bool) error {
+	start := time.Now()
+	err := s.storage.SetURLDisabled(ctx, shortCode, disabled)
+	s.observe("SetURLDisabled", start, err)
+	return err
+}
+
+func (s *InstrumentedStorage) IncrementClickCount(ctx context.Context, shortCode string) error {
+	start := time.Now()
+	err := s.storage.IncrementClickCount(ctx, shortCode)
+	s.observe("IncrementClickCount", start, err)
+	return err
+}
+
+func (s *InstrumentedStorage) SaveClickBatch(ctx context.Context, counts map[string]int64, clicks []*models.Click) error {
+	start := time.Now()
+	err := s.storage.SaveClickBatch(ctx, counts, clicks)
+	s.observe("SaveClickBatch", start, err)
+	return err
+}
+
+func (s *InstrumentedStorage) ConsumeClick(ctx context.Context, shortCode string) error {
+	start := time.Now()
+	err := s.storage.ConsumeClick(ctx, shortCode)
+	s.observe("ConsumeClick", start, err)
+	return err
+}
+
+func (s *InstrumentedStorage) SaveClicks(ctx context.Context, clicks []*models.Click) error {
+	start := time.Now()
+	err := s.storage.SaveClicks(ctx, clicks)
+	s.observe("SaveClicks", start, err)
+	return err
+}
+
+func (s *InstrumentedStorage) GetClicks(ctx context.Context, shortCode string, limit, offset int) ([]*models.Click, error) {
+	start := time.Now()
+	clicks, err := s.storage.GetClicks(ctx, shortCode, limit, offset)
+	s.observe("GetClicks", start, err)
+	return clicks, err
+}
+
+func (s *InstrumentedStorage) GetClicksCount(ctx context.Context, shortCode string) (int, error) {
+	start := time.Now()
+	count, err := s.storage.GetClicksCount(ctx, shortCode)
+	s.observe("GetClicksCount", start, err)
+	return count, err
+}
+
+func (s *InstrumentedStorage) NextSequenceValue(ctx context.Context) (int64, error) {
+	start := time.Now()
+	value, err := s.storage.NextSequenceValue(ctx)
+	s.observe("NextSequenceValue", start, err)
+	return value, err
+}
+
+func (s *InstrumentedStorage) PurgeExpiredURLs(ctx context.Context, before time.Time, limit int, archive bool) (int64, error) {
+	start := time.Now()
+	purged, err := s.storage.PurgeExpiredURLs(ctx, before, limit, archive)
+	s.observe("PurgeExpiredURLs", start, err)
+	return purged, err
+}
+
+func (s *InstrumentedStorage) GetClickTimeSeries(ctx context.Context, shortCode string, from, to time.Time, interval string, loc *time.Location) ([]*models.TimeBucket, error) {
+	start := time.Now()
+	buckets, err := s.storage.GetClickTimeSeries(ctx, shortCode, from, to, interval, loc)
+	s.observe("GetClickTimeSeries", start, err)
+	return buckets, err
+}
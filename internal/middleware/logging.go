@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // LoggingMiddleware добавляет логирование всех входящих запросов
@@ -23,13 +24,25 @@ func LoggingMiddleware() gin.HandlerFunc {
 		// После обработки логируем информацию о запросе
 		duration := time.Since(start)
 
-		log.Info().
+		event := log.Info().
 			Str("method", c.Request.Method).
 			Str("path", c.Request.URL.Path).
 			Str("ip", c.ClientIP()).
 			Int("status", c.Writer.Status()).
 			Str("duration", duration.String()).
-			Str("user_agent", c.Request.UserAgent()).
-			Msg("request processed")
+			Str("user_agent", c.Request.UserAgent())
+
+		if id := RequestIDFromContext(c.Request.Context()); id != "" {
+			event = event.Str("request_id", id)
+		}
+
+		// Идентификаторы трейса позволяют найти запрос из лога в системе трассировки
+		if spanContext := trace.SpanContextFromContext(c.Request.Context()); spanContext.IsValid() {
+			event = event.
+				Str("trace_id", spanContext.TraceID().String()).
+				Str("span_id", spanContext.SpanID().String())
+		}
+
+		event.Msg("request processed")
 	}
 }
@@ -3,10 +3,16 @@
 package middleware
 
 import (
+	"bytes"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/drerr0r/url-shortener/internal/audit"
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestLoggingMiddleware(t *testing.T) {
@@ -27,3 +33,109 @@ func TestLoggingMiddleware(t *testing.T) {
 		t.Errorf("Expected status 200, got %d", w.Code)
 	}
 }
+
+func TestLoggingMiddlewareTraceID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	previous := log.Logger
+	log.Logger = zerolog.New(&buf)
+	defer func() { log.Logger = previous }()
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(trace.ContextWithSpanContext(c.Request.Context(), spanContext))
+		c.Next()
+	})
+	router.Use(LoggingMiddleware())
+	router.GET("/test", func(c *gin.Context) {
+		c.String(200, "test")
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	if !strings.Contains(buf.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`) {
+		t.Errorf("Expected trace_id in log, got %s", buf.String())
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/test", func(c *gin.Context) {
+		c.String(200, RequestIDFromContext(c.Request.Context()))
+	})
+
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{"Client ID is kept", "abc-123", "abc-123"},
+		{"Missing ID is generated", "", ""},
+		{"Unsafe ID is replaced", "bad id\n", ""},
+		{"Long ID is replaced", strings.Repeat("a", 65), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			id := w.Header().Get(RequestIDHeader)
+			if id == "" || id != w.Body.String() {
+				t.Errorf("Expected the same non-empty ID in header and context, got %q and %q", id, w.Body.String())
+			}
+			if tt.expected != "" && id != tt.expected {
+				t.Errorf("Expected ID %q, got %q", tt.expected, id)
+			}
+			if tt.expected == "" && id == tt.header {
+				t.Errorf("Expected a generated ID instead of %q", tt.header)
+			}
+		})
+	}
+}
+
+func TestAuditOriginClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		proxies  []string
+		expected string
+	}{
+		// Как в main по умолчанию: X-Forwarded-For от клиента не учитывается
+		{"Spoofed header is ignored", nil, "192.0.2.1"},
+		{"Header from trusted proxy is used", []string{"192.0.2.0/24"}, "203.0.113.9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			if err := router.SetTrustedProxies(tt.proxies); err != nil {
+				t.Fatalf("Failed to set trusted proxies: %v", err)
+			}
+			router.GET("/test", AuditOrigin(), func(c *gin.Context) {
+				c.String(200, audit.OriginFromContext(c.Request.Context()).ClientIP)
+			})
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.RemoteAddr = "192.0.2.1:12345"
+			req.Header.Set("X-Forwarded-For", "203.0.113.9")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Body.String() != tt.expected {
+				t.Errorf("Expected client IP %q in audit origin, got %q", tt.expected, w.Body.String())
+			}
+		})
+	}
+}
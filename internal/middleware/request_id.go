@@ -0,0 +1,71 @@
+// internal/middleware/request_id.go
+
+package middleware
+
+import (
+	"context"
+
+	"github.com/drerr0r/url-shortener/internal/audit"
+	"github.com/drerr0r/url-shortener/internal/auth"
+	"github.com/drerr0r/url-shortener/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader заголовок с идентификатором запроса
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength длина идентификатора, которую принимаем от клиента или прокси
+const maxRequestIDLength = 64
+
+type requestIDKey struct{}
+
+// RequestID берет идентификатор запроса из X-Request-ID или генерирует новый,
+// возвращает его в ответе и кладет в контекст запроса
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !isValidRequestID(id) {
+			id = utils.GenerateRandomString(24)
+		}
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestIDKey{}, id))
+		c.Next()
+	}
+}
+
+// RequestIDFromContext возвращает идентификатор запроса, пустая строка - RequestID не подключен
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// AuditOrigin передает хранилищу источник изменений для журнала аудита: клиента, его IP
+// и идентификатор запроса. Ставится после Authenticator.Middleware
+func AuditOrigin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		c.Request = c.Request.WithContext(audit.WithOrigin(ctx, audit.Origin{
+			Actor:     auth.FromContext(c).Actor(),
+			ClientIP:  c.ClientIP(),
+			RequestID: RequestIDFromContext(ctx),
+		}))
+		c.Next()
+	}
+}
+
+// isValidRequestID пропускает только короткие идентификаторы из безопасных символов,
+// чтобы чужой заголовок не испортил логи и журнал аудита
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+// internal/migrate/migrate.go
+
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/drerr0r/url-shortener/migrations"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+	"github.com/rs/zerolog/log"
+)
+
+// lockID ключ advisory lock, под которым реплики применяют миграции по очереди
+const lockID int64 = 0x75726c73686f7274 // "urlshort"
+
+// Migrator применяет встроенные миграции к PostgreSQL.
+// Все операции выполняются под session advisory lock, поэтому несколько реплик,
+// стартующих одновременно, не применяют одну миграцию дважды
+type Migrator struct {
+	provider *goose.Provider
+}
+
+// New создает Migrator для встроенных миграций
+func New(db *sql.DB) (*Migrator, error) {
+	locker, err := lock.NewPostgresSessionLocker(lock.WithLockID(lockID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration lock: %w", err)
+	}
+
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, migrations.FS,
+		goose.WithSessionLocker(locker),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	return &Migrator{provider: provider}, nil
+}
+
+// Latest возвращает версию последней встроенной миграции
+func (m *Migrator) Latest() int64 {
+	sources := m.provider.ListSources()
+	if len(sources) == 0 {
+		return 0
+	}
+	return sources[len(sources)-1].Version
+}
+
+// Version возвращает текущую версию схемы базы данных
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	version, err := m.provider.GetDBVersion(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	return version, nil
+}
+
+// Up применяет все недостающие миграции
+func (m *Migrator) Up(ctx context.Context) ([]*goose.MigrationResult, error) {
+	results, err := m.provider.Up(ctx)
+	logResults(results)
+	if err != nil {
+		return results, fmt.Errorf("failed to apply migrations: %w", err)
+	}
+	return results, nil
+}
+
+// Down откатывает последнюю примененную миграцию
+func (m *Migrator) Down(ctx context.Context) (*goose.MigrationResult, error) {
+	result, err := m.provider.Down(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to roll back migration: %w", err)
+	}
+	logResults([]*goose.MigrationResult{result})
+	return result, nil
+}
+
+// Redo откатывает и заново применяет последнюю миграцию
+func (m *Migrator) Redo(ctx context.Context) ([]*goose.MigrationResult, error) {
+	down, err := m.Down(ctx)
+	if err != nil {
+		return nil, err
+	}
+	up, err := m.provider.ApplyVersion(ctx, down.Source.Version, true)
+	if err != nil {
+		return []*goose.MigrationResult{down}, fmt.Errorf("failed to reapply migration %d: %w", down.Source.Version, err)
+	}
+	logResults([]*goose.MigrationResult{up})
+	return []*goose.MigrationResult{down, up}, nil
+}
+
+// Status возвращает состояние каждой встроенной миграции
+func (m *Migrator) Status(ctx context.Context) ([]*goose.MigrationStatus, error) {
+	statuses, err := m.provider.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration status: %w", err)
+	}
+	return statuses, nil
+}
+
+func logResults(results []*goose.MigrationResult) {
+	for _, result := range results {
+		if result == nil || result.Error != nil {
+			continue
+		}
+		log.Info().
+			Int64("version", result.Source.Version).
+			Str("direction", result.Direction).
+			Dur("duration", result.Duration).
+			Msg("Migration applied")
+	}
+}
@@ -0,0 +1,46 @@
+package migrate
+
+import (
+	"database/sql"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/drerr0r/url-shortener/migrations"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	// sql.Open не подключается к базе: для загрузки миграций соединение не нужно
+	db, err := sql.Open("postgres", "host=localhost dbname=urlshortener sslmode=disable")
+	require.NoError(t, err)
+	defer db.Close()
+
+	migrator, err := New(db)
+	require.NoError(t, err)
+
+	sources := migrator.provider.ListSources()
+	require.NotEmpty(t, sources)
+	for i, source := range sources {
+		assert.Equal(t, int64(i+1), source.Version, "migration versions must be sequential")
+	}
+	assert.Equal(t, sources[len(sources)-1].Version, migrator.Latest())
+}
+
+func TestMigrationsHaveDown(t *testing.T) {
+	files, err := fs.Glob(migrations.FS, "*.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, name := range files {
+		data, err := fs.ReadFile(migrations.FS, name)
+		require.NoError(t, err)
+
+		content := string(data)
+		assert.Contains(t, content, "-- +goose Up", name)
+		assert.Contains(t, content, "-- +goose Down", name)
+		assert.Less(t, strings.Index(content, "-- +goose Up"), strings.Index(content, "-- +goose Down"), name)
+	}
+}
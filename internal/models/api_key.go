@@ -0,0 +1,25 @@
+// internal/models/api_key.go
+
+package models
+
+import (
+	"time"
+)
+
+// APIKey представляет API ключ клиента. Сам ключ показывается один раз при создании,
+// в базе хранится только его хэш
+type APIKey struct {
+	ID         int64      `db:"id" json:"id"`
+	Name       string     `db:"name" json:"name"`     // Описание владельца ключа
+	Prefix     string     `db:"prefix" json:"prefix"` // Начало ключа для опознания, не секрет
+	KeyHash    string     `db:"key_hash" json:"-"`    // SHA-256 хэш ключа в hex
+	Role       Role       `db:"role" json:"role"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	LastUsedAt *time.Time `db:"last_used_at" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"revoked_at,omitempty"` // nil - ключ действует
+}
+
+// IsRevoked проверяет, отозван ли ключ
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
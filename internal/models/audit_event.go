@@ -0,0 +1,37 @@
+// internal/models/audit_event.go
+
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Действия над ссылками, записываемые в журнал аудита
+const (
+	AuditLinkCreate  = "link.create"
+	AuditLinkDelete  = "link.delete"
+	AuditLinkDisable = "link.disable"
+	AuditLinkEnable  = "link.enable"
+	AuditLinkPurge   = "link.purge"   // Истекшая ссылка удалена фоновой очисткой
+	AuditLinkArchive = "link.archive" // Истекшая ссылка перенесена в urls_archive
+)
+
+// AuditEvent запись журнала аудита об изменении ссылки. Журнал только дополняется
+type AuditEvent struct {
+	ID        int64            `db:"id" json:"id"`
+	CreatedAt time.Time        `db:"created_at" json:"created_at"`
+	Actor     string           `db:"actor" json:"actor"` // "user:<id>", "key:<id>", "anonymous" или "system:<задача>"
+	Action    string           `db:"action" json:"action"`
+	ShortCode string           `db:"short_code" json:"short_code"`
+	Before    *json.RawMessage `db:"before" json:"before,omitempty"` // Ссылка до изменения, nil - ее не было
+	After     *json.RawMessage `db:"after" json:"after,omitempty"`   // Ссылка после изменения, nil - удалена
+	ClientIP  string           `db:"client_ip" json:"client_ip,omitempty"`
+	RequestID string           `db:"request_id" json:"request_id,omitempty"`
+}
+
+// AuditFilter отбирает события журнала. Пустые поля не ограничивают выборку
+type AuditFilter struct {
+	ShortCode string
+	Actor     string
+}
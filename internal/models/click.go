@@ -0,0 +1,26 @@
+// internal/models/click.go
+
+package models
+
+import (
+	"time"
+)
+
+// Click представляет одно событие перехода по короткой ссылке
+type Click struct {
+	ID             int64     `db:"id" json:"id"`                           // Уникальный идентификатор
+	ShortCode      string    `db:"short_code" json:"short_code"`           // Короткий код ссылки
+	ClickedAt      time.Time `db:"clicked_at" json:"clicked_at"`           // Время перехода
+	Referrer       string    `db:"referrer" json:"referrer"`               // Заголовок Referer
+	UserAgent      string    `db:"user_agent" json:"user_agent"`           // Заголовок User-Agent
+	IPHash         string    `db:"ip_hash" json:"-"`                       // Хэш IP адреса клиента, в API не отдается
+	AcceptLanguage string    `db:"accept_language" json:"accept_language"` // Заголовок Accept-Language
+	Country        string    `db:"country" json:"country"`                 // Код страны, если его передал прокси
+	Counted        bool      `db:"-" json:"-"`                             // Счетчик уже увеличен при редиректе (ссылки с лимитом переходов)
+}
+
+// TimeBucket представляет количество переходов за один интервал временного ряда
+type TimeBucket struct {
+	Start time.Time `db:"bucket" json:"start"` // Начало интервала
+	Count int64     `db:"count" json:"count"`  // Количество переходов
+}
@@ -8,12 +8,72 @@ import (
 
 // URL прредставляет модель данных для сокращенной ссылки
 type URL struct {
-	ID          int64     `db:"id" json:"id"`                     // Уникальный идентификатор
-	OriginalURL string    `db:"original_url" json:"original_url"` // Оригинальный URL
-	ShortCode   string    `db:"short_code" json:"short_code"`     // Сокращенный код
-	CreatedAt   time.Time `db:"created_at" json:"created_at"`     // Время создания
-	ClickCount  int64     `db:"click_count" json:"click_count"`   // Счетчик кликов
+	ID           int64      `db:"id" json:"id"`                             // Уникальный идентификатор
+	OriginalURL  string     `db:"original_url" json:"original_url"`         // Оригинальный URL
+	ShortCode    string     `db:"short_code" json:"short_code"`             // Сокращенный код
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`             // Время создания
+	ClickCount   int64      `db:"click_count" json:"click_count"`           // Счетчик кликов
+	ExpiresAt    *time.Time `db:"expires_at" json:"expires_at,omitempty"`   // Время истечения, nil - бессрочная
+	MaxClicks    *int64     `db:"max_clicks" json:"max_clicks,omitempty"`   // Лимит переходов, nil - без ограничений
+	PasswordHash string     `db:"password_hash" json:"-"`                   // bcrypt хэш пароля, пустая строка - без пароля
+	OwnerID      *int64     `db:"owner_id" json:"owner_id,omitempty"`       // API ключ владельца
+	UserID       *int64     `db:"user_id" json:"user_id,omitempty"`         // Пользователь веб-интерфейса, создавший ссылку
+	DisabledAt   *time.Time `db:"disabled_at" json:"disabled_at,omitempty"` // Время отключения администратором, nil - ссылка работает
+}
+
+// Owner владелец ссылки: API ключ и (или) пользователь. Пустой Owner - анонимный клиент
+type Owner struct {
+	KeyID  *int64
+	UserID *int64
+}
+
+// IsAnonymous проверяет, что владелец не задан
+func (o Owner) IsAnonymous() bool {
+	return o.KeyID == nil && o.UserID == nil
+}
+
+// Equal сравнивает владельцев по значению
+func (o Owner) Equal(other Owner) bool {
+	return equalID(o.KeyID, other.KeyID) && equalID(o.UserID, other.UserID)
+}
+
+func equalID(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// IsProtected проверяет, требуется ли пароль для перехода по ссылке
+func (u *URL) IsProtected() bool {
+	return u.PasswordHash != ""
+}
+
+// IsExpired проверяет, истек ли срок действия ссылки на момент now
+func (u *URL) IsExpired(now time.Time) bool {
+	return u.ExpiresAt != nil && !now.Before(*u.ExpiresAt)
+}
+
+// Owner возвращает владельца ссылки
+func (u *URL) Owner() Owner {
+	return Owner{KeyID: u.OwnerID, UserID: u.UserID}
+}
+
+// IsOwnedBy проверяет, может ли owner управлять ссылкой: совпадает ключ или пользователь.
+// Анонимной ссылкой не владеет никто
+func (u *URL) IsOwnedBy(owner Owner) bool {
+	return (owner.KeyID != nil && equalID(u.OwnerID, owner.KeyID)) ||
+		(owner.UserID != nil && equalID(u.UserID, owner.UserID))
+}
+
+// IsDisabled проверяет, отключена ли ссылка администратором
+func (u *URL) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
 
+// IsExhausted проверяет, израсходован ли лимит переходов по ссылке
+func (u *URL) IsExhausted() bool {
+	return u.MaxClicks != nil && u.ClickCount >= *u.MaxClicks
 }
 
 type CreateURLRequest struct {
@@ -0,0 +1,56 @@
+// internal/models/user.go
+
+package models
+
+import (
+	"time"
+)
+
+// Role определяет, что пользователю разрешено делать
+type Role string
+
+// Роли в порядке возрастания прав: каждая следующая включает предыдущие
+const (
+	RoleViewer Role = "viewer" // Просмотр своих ссылок и статистики
+	RoleEditor Role = "editor" // Создание и удаление своих ссылок
+	RoleAdmin  Role = "admin"  // Управление любыми ссылками
+)
+
+var roleRanks = map[Role]int{RoleViewer: 1, RoleEditor: 2, RoleAdmin: 3}
+
+// ParseRole проверяет название роли
+func ParseRole(name string) (Role, bool) {
+	role := Role(name)
+	_, ok := roleRanks[role]
+	return role, ok
+}
+
+// AtLeast сообщает, что роль включает права роли other
+func (r Role) AtLeast(other Role) bool {
+	return roleRanks[r] >= roleRanks[other] && roleRanks[r] > 0
+}
+
+// User представляет пользователя веб-интерфейса
+type User struct {
+	ID           int64     `db:"id" json:"id"`
+	Email        string    `db:"email" json:"email"`     // Email в нижнем регистре, используется как логин
+	PasswordHash string    `db:"password_hash" json:"-"` // Пусто у пользователей, входящих только через SSO
+	Role         Role      `db:"role" json:"role"`
+	OIDCIssuer   *string   `db:"oidc_issuer" json:"-"`  // Провайдер, через который входит пользователь
+	OIDCSubject  *string   `db:"oidc_subject" json:"-"` // Идентификатор пользователя у провайдера (claim sub)
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// Session представляет сессию пользователя. Токен из cookie не хранится, ID - его хэш
+type Session struct {
+	ID        string    `db:"id"`
+	UserID    int64     `db:"user_id"`
+	CSRFToken string    `db:"csrf_token"` // Токен, который формы и запросы сессии передают вместе с cookie
+	CreatedAt time.Time `db:"created_at"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+// IsExpired проверяет, истекла ли сессия на момент now
+func (s *Session) IsExpired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
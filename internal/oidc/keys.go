@@ -0,0 +1,172 @@
+// internal/oidc/keys.go
+
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	jose "github.com/go-jose/go-jose/v4"
+	"golang.org/x/sync/singleflight"
+)
+
+// keysRefreshRate не чаще этого JWKS перезагружается из-за неизвестного kid
+const keysRefreshRate = time.Minute
+
+// curveAlgs алгоритм ECDSA, которым подписывают ключом на каждой кривой
+var curveAlgs = map[string]string{
+	"P-256": gooidc.ES256,
+	"P-384": gooidc.ES384,
+	"P-521": gooidc.ES512,
+}
+
+// keySet ключи подписи из JWKS провайдера (gooidc.KeySet). Ключ выбирается по kid и
+// должен подходить к alg токена: go-jose не сверяет alg с кривой EC ключа и с alg из JWK.
+// Саму подпись проверяет go-oidc. JWKS загружается без блокировок: параллельные проверки
+// ждут один запрос и могут прервать ожидание своим контекстом
+type keySet struct {
+	url    string
+	client *http.Client
+	now    func() time.Time
+
+	fetch   singleflight.Group
+	mu      sync.RWMutex
+	keys    []jose.JSONWebKey
+	fetched time.Time
+}
+
+func newKeySet(url string, client *http.Client, now func() time.Time) *keySet {
+	return &keySet{url: url, client: client, now: now}
+}
+
+// VerifySignature проверяет подпись JWT и возвращает его payload
+func (s *keySet) VerifySignature(ctx context.Context, raw string) ([]byte, error) {
+	algs := make([]jose.SignatureAlgorithm, len(signingAlgs))
+	for i, alg := range signingAlgs {
+		algs[i] = jose.SignatureAlgorithm(alg)
+	}
+	jws, err := jose.ParseSigned(raw, algs)
+	if err != nil {
+		return nil, fmt.Errorf("malformed token: %w", err)
+	}
+	if len(jws.Signatures) != 1 {
+		return nil, fmt.Errorf("token must have exactly one signature")
+	}
+	header := jws.Signatures[0].Header
+
+	keys, err := s.lookup(ctx, header.KeyID, header.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return (&gooidc.StaticKeySet{PublicKeys: keys}).VerifySignature(ctx, raw)
+}
+
+// lookup возвращает ключи для kid и alg. Неизвестный kid означает ротацию ключей у провайдера:
+// JWKS перезагружается, но не чаще keysRefreshRate
+func (s *keySet) lookup(ctx context.Context, kid, alg string) ([]crypto.PublicKey, error) {
+	s.mu.RLock()
+	keys, fresh := s.keys, s.keys != nil && s.now().Sub(s.fetched) < keysRefreshRate
+	s.mu.RUnlock()
+	if matched := matchKeys(keys, kid, alg); len(matched) > 0 {
+		return matched, nil
+	}
+	if fresh {
+		return nil, fmt.Errorf("unknown key %q for %s", kid, alg)
+	}
+
+	// Запрос не отменяется вместе с первым ожидающим: его результат нужен и остальным
+	result := s.fetch.DoChan("jwks", func() (any, error) {
+		return s.refresh(context.WithoutCancel(ctx))
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-result:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		keys = res.Val.([]jose.JSONWebKey)
+	}
+	if matched := matchKeys(keys, kid, alg); len(matched) > 0 {
+		return matched, nil
+	}
+	return nil, fmt.Errorf("unknown key %q for %s", kid, alg)
+}
+
+// refresh загружает JWKS. Ключи неподдерживаемых типов и кривых пропускаются
+func (s *keySet) refresh(ctx context.Context) ([]jose.JSONWebKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc jwks fetch failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc jwks fetch failed: GET %s returned %s", s.url, resp.Status)
+	}
+
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("oidc jwks fetch failed: %w", err)
+	}
+	keys := make([]jose.JSONWebKey, 0, len(set.Keys))
+	for _, data := range set.Keys {
+		var key jose.JSONWebKey
+		if err := key.UnmarshalJSON(data); err != nil || !key.IsPublic() || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	s.mu.Lock()
+	s.keys, s.fetched = keys, s.now()
+	s.mu.Unlock()
+	return keys, nil
+}
+
+// matchKeys отбирает ключи с нужным kid, которые подходят к alg.
+// Токен без kid допустим, только если ключ единственный
+func matchKeys(keys []jose.JSONWebKey, kid, alg string) []crypto.PublicKey {
+	if kid == "" && len(keys) != 1 {
+		return nil
+	}
+	var matched []crypto.PublicKey
+	for _, key := range keys {
+		if (kid == "" || key.KeyID == kid) && keyFits(key, alg) {
+			matched = append(matched, key.Key)
+		}
+	}
+	return matched
+}
+
+// keyFits сообщает, можно ли проверять ключом подпись алгоритмом alg
+func keyFits(key jose.JSONWebKey, alg string) bool {
+	if key.Algorithm != "" && key.Algorithm != alg {
+		return false
+	}
+	switch public := key.Key.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS")
+	case *ecdsa.PublicKey:
+		return curveAlgs[public.Curve.Params().Name] == alg
+	default:
+		return false
+	}
+}
@@ -0,0 +1,92 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyFits(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		key  jose.JSONWebKey
+		alg  string
+		want bool
+	}{
+		{"RSA", jose.JSONWebKey{Key: &rsaKey.PublicKey}, "RS256", true},
+		{"RSA with EC algorithm", jose.JSONWebKey{Key: &rsaKey.PublicKey}, "ES256", false},
+		{"RSA with other JWK alg", jose.JSONWebKey{Key: &rsaKey.PublicKey, Algorithm: "RS384"}, "RS256", false},
+		{"P-256", jose.JSONWebKey{Key: &p256.PublicKey}, "ES256", true},
+		{"P-384", jose.JSONWebKey{Key: &p384.PublicKey}, "ES384", true},
+		{"P-384 with ES256", jose.JSONWebKey{Key: &p384.PublicKey}, "ES256", false},
+		{"P-256 with ES384", jose.JSONWebKey{Key: &p256.PublicKey}, "ES384", false},
+		{"EC with RSA algorithm", jose.JSONWebKey{Key: &p256.PublicKey}, "RS256", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, keyFits(tt.key, tt.alg))
+		})
+	}
+}
+
+func TestKeySetFetchDoesNotBlock(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	known := jose.JSONWebKey{Key: &key.PublicKey, KeyID: "known", Use: "sig"}
+
+	var requests atomic.Int32
+	release := make(chan struct{})
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{known}})
+	}))
+	defer jwks.Close()
+	defer close(release)
+
+	now := time.Now()
+	keys := newKeySet(jwks.URL, jwks.Client(), func() time.Time { return now })
+	keys.keys, keys.fetched = []jose.JSONWebKey{known}, now.Add(-time.Hour)
+
+	// Неизвестный kid запускает загрузку JWKS, которую провайдер не завершает
+	slow := make(chan error, 1)
+	go func() {
+		_, err := keys.lookup(context.Background(), "rotated", "ES256")
+		slow <- err
+	}()
+	require.Eventually(t, func() bool { return requests.Load() == 1 }, time.Second, time.Millisecond)
+
+	// Известный ключ доступен, пока загрузка идет
+	matched, err := keys.lookup(context.Background(), "known", "ES256")
+	require.NoError(t, err)
+	assert.Len(t, matched, 1)
+
+	// Ожидающий ту же загрузку прерывает ожидание своим контекстом, повторного запроса нет
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = keys.lookup(ctx, "rotated", "ES256")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int32(1), requests.Load())
+
+	release <- struct{}{}
+	assert.ErrorContains(t, <-slow, "unknown key")
+}
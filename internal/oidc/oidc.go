@@ -0,0 +1,174 @@
+// internal/oidc/oidc.go
+
+// Package oidc реализует вход через OpenID Connect провайдер: authorization code flow
+// с PKCE, обмен кода на токены и проверку ID токена. Протокол реализуют
+// github.com/coreos/go-oidc и golang.org/x/oauth2, пакет связывает их с настройками сервиса
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/singleflight"
+)
+
+// maxResponseSize ограничивает размер ответов провайдера
+const maxResponseSize = 1 << 20
+
+// Config задает провайдер и зарегистрированного у него клиента
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string // Пусто - публичный клиент, защищенный только PKCE
+	RedirectURL  string
+	Scopes       []string     // Должны включать openid
+	HTTPClient   *http.Client // nil - клиент с таймаутом 10 секунд
+}
+
+// connection настроенные по документу discovery клиент OAuth 2.0 и проверка ID токенов
+type connection struct {
+	oauth    oauth2.Config
+	verifier *gooidc.IDTokenVerifier
+}
+
+// Provider выполняет вход через OIDC провайдер. Метаданные и ключи подписи
+// загружаются при первом обращении, поэтому недоступный провайдер не мешает запуску сервера.
+// Запросы к провайдеру выполняются без блокировок: параллельные входы ждут один и тот же
+// запрос и могут прервать ожидание своим контекстом
+type Provider struct {
+	cfg    Config
+	client *http.Client
+	now    func() time.Time
+
+	discovery singleflight.Group
+	mu        sync.Mutex
+	remote    *connection
+}
+
+func NewProvider(cfg Config) *Provider {
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	return &Provider{cfg: cfg, client: client, now: time.Now}
+}
+
+// Issuer возвращает идентификатор провайдера; вместе с claim sub он однозначно определяет пользователя
+func (p *Provider) Issuer() string {
+	return p.cfg.Issuer
+}
+
+// RandomToken возвращает случайную строку для state, nonce и PKCE code_verifier
+func RandomToken() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic("crypto/rand failed: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// AuthCodeURL возвращает адрес страницы входа провайдера. state, nonce и verifier
+// нужно сохранить до возврата пользователя на RedirectURL
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	remote, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	return remote.oauth.AuthCodeURL(state, gooidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange обменивает код авторизации на ID токен и проверяет его
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*IDToken, error) {
+	remote, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := remote.oauth.Exchange(context.WithValue(ctx, oauth2.HTTPClient, p.client), code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	raw, _ := token.Extra("id_token").(string)
+	if raw == "" {
+		return nil, errors.New("token endpoint returned no id_token")
+	}
+	return p.Verify(ctx, raw, nonce)
+}
+
+// metadata возвращает клиент провайдера, при первом обращении загружая документ discovery.
+// При ошибке следующая попытка будет при следующем входе
+func (p *Provider) metadata(ctx context.Context) (*connection, error) {
+	p.mu.Lock()
+	remote := p.remote
+	p.mu.Unlock()
+	if remote != nil {
+		return remote, nil
+	}
+
+	// Запрос не отменяется вместе с первым ожидающим: его результат нужен и остальным
+	result := p.discovery.DoChan("discovery", func() (any, error) {
+		return p.discover(context.WithoutCancel(ctx))
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-result:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*connection), nil
+	}
+}
+
+func (p *Provider) discover(ctx context.Context) (*connection, error) {
+	// go-oidc проверяет, что issuer в документе совпадает с настроенным (Discovery 1.0, 4.3)
+	provider, err := gooidc.NewProvider(gooidc.ClientContext(ctx, p.client), p.cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	var meta struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := provider.Claims(&meta); err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	endpoint := provider.Endpoint()
+	if endpoint.AuthURL == "" || endpoint.TokenURL == "" || meta.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: authorization_endpoint, token_endpoint and jwks_uri are required")
+	}
+
+	// RFC 6749: секрет передается Basic аутентификацией, публичный клиент передает только client_id
+	endpoint.AuthStyle = oauth2.AuthStyleInHeader
+	if p.cfg.ClientSecret == "" {
+		endpoint.AuthStyle = oauth2.AuthStyleInParams
+	}
+	remote := &connection{
+		oauth: oauth2.Config{
+			ClientID:     p.cfg.ClientID,
+			ClientSecret: p.cfg.ClientSecret,
+			Endpoint:     endpoint,
+			RedirectURL:  p.cfg.RedirectURL,
+			Scopes:       p.cfg.Scopes,
+		},
+		verifier: gooidc.NewVerifier(p.cfg.Issuer, newKeySet(meta.JWKSURI, p.client, p.now), &gooidc.Config{
+			ClientID:             p.cfg.ClientID,
+			SupportedSigningAlgs: signingAlgs,
+			Now:                  p.now,
+		}),
+	}
+
+	p.mu.Lock()
+	p.remote = remote
+	p.mu.Unlock()
+	return remote, nil
+}
@@ -0,0 +1,199 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const redirectURL = "http://app.test/login/sso/callback"
+
+func newProvider(idp *oidctest.Server) *Provider {
+	return NewProvider(Config{
+		Issuer:       idp.Issuer(),
+		ClientID:     idp.ClientID,
+		ClientSecret: idp.ClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"openid", "email"},
+	})
+}
+
+// authorize проходит страницу входа провайдера и возвращает параметры редиректа обратно
+func authorize(t *testing.T, p *Provider, state, nonce, verifier string) url.Values {
+	t.Helper()
+	target, err := p.AuthCodeURL(context.Background(), state, nonce, verifier)
+	require.NoError(t, err)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(target)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(location.String(), redirectURL))
+	return location.Query()
+}
+
+func TestExchange(t *testing.T) {
+	for _, secret := range []string{"client-secret", ""} {
+		idp := oidctest.NewServer("shortener", secret)
+		defer idp.Close()
+		idp.SetUser(map[string]any{"sub": "42", "email": "Alice@Corp.example", "email_verified": true, "groups": []string{"staff"}})
+		p := newProvider(idp)
+
+		verifier := RandomToken()
+		params := authorize(t, p, "state-1", "nonce-1", verifier)
+		assert.Equal(t, "state-1", params.Get("state"))
+
+		token, err := p.Exchange(context.Background(), params.Get("code"), verifier, "nonce-1")
+		require.NoError(t, err, "secret %q", secret)
+		assert.Equal(t, "42", token.Subject)
+		assert.Equal(t, "Alice@Corp.example", token.Email)
+		assert.True(t, token.EmailVerified)
+		assert.Equal(t, idp.Issuer(), token.Issuer)
+
+		// Код одноразовый
+		_, err = p.Exchange(context.Background(), params.Get("code"), verifier, "nonce-1")
+		assert.Error(t, err)
+	}
+}
+
+func TestExchangeErrors(t *testing.T) {
+	idp := oidctest.NewServer("shortener", "client-secret")
+	defer idp.Close()
+	idp.SetUser(map[string]any{"sub": "42"})
+
+	t.Run("Wrong PKCE verifier", func(t *testing.T) {
+		p := newProvider(idp)
+		params := authorize(t, p, "state", "nonce", RandomToken())
+		_, err := p.Exchange(context.Background(), params.Get("code"), RandomToken(), "nonce")
+		assert.ErrorContains(t, err, "invalid_grant")
+	})
+
+	t.Run("Wrong nonce", func(t *testing.T) {
+		p := newProvider(idp)
+		verifier := RandomToken()
+		params := authorize(t, p, "state", "nonce", verifier)
+		_, err := p.Exchange(context.Background(), params.Get("code"), verifier, "other nonce")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Wrong client secret", func(t *testing.T) {
+		p := newProvider(idp)
+		p.cfg.ClientSecret = "guess"
+		verifier := RandomToken()
+		params := authorize(t, p, "state", "nonce", verifier)
+		_, err := p.Exchange(context.Background(), params.Get("code"), verifier, "nonce")
+		assert.ErrorContains(t, err, "invalid_client")
+	})
+
+	t.Run("Issuer mismatch", func(t *testing.T) {
+		p := newProvider(idp)
+		p.cfg.Issuer = "https://evil.example"
+		_, err := p.AuthCodeURL(context.Background(), "state", "nonce", RandomToken())
+		assert.Error(t, err)
+	})
+
+	t.Run("Provider unavailable", func(t *testing.T) {
+		p := NewProvider(Config{Issuer: "http://127.0.0.1:1", ClientID: "shortener"})
+		_, err := p.AuthCodeURL(context.Background(), "state", "nonce", RandomToken())
+		assert.Error(t, err)
+	})
+}
+
+func TestVerify(t *testing.T) {
+	idp := oidctest.NewServer("shortener", "client-secret")
+	defer idp.Close()
+	p := newProvider(idp)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"Valid token", idp.IDToken(map[string]any{"sub": "42", "nonce": "n"}), false},
+		{"Multiple audiences with azp", idp.IDToken(map[string]any{"sub": "42", "nonce": "n", "aud": []string{"shortener", "api"}, "azp": "shortener"}), false},
+		{"Wrong audience", idp.IDToken(map[string]any{"sub": "42", "nonce": "n", "aud": "other-app"}), true},
+		{"Multiple audiences without azp", idp.IDToken(map[string]any{"sub": "42", "nonce": "n", "aud": []string{"shortener", "api"}}), true},
+		{"Wrong issuer", idp.IDToken(map[string]any{"sub": "42", "nonce": "n", "iss": "https://evil.example"}), true},
+		{"Expired", idp.IDToken(map[string]any{"sub": "42", "nonce": "n", "exp": now.Add(-time.Hour).Unix()}), true},
+		{"Issued in the future", idp.IDToken(map[string]any{"sub": "42", "nonce": "n", "iat": now.Add(time.Hour).Unix()}), true},
+		{"Missing subject", idp.IDToken(map[string]any{"nonce": "n"}), true},
+		{"Wrong nonce", idp.IDToken(map[string]any{"sub": "42", "nonce": "other"}), true},
+		{"Unknown key", idp.Sign(map[string]any{"alg": "RS256", "kid": "rotated"}, map[string]any{"sub": "42"}), true},
+		{"Algorithm none", "eyJhbGciOiJub25lIn0.eyJzdWIiOiI0MiJ9.", true},
+		{"Malformed", "not-a-token", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.Verify(context.Background(), tt.token, "n")
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidToken)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("Tampered payload", func(t *testing.T) {
+		parts := strings.Split(idp.IDToken(map[string]any{"sub": "42"}), ".")
+		forged := strings.Split(idp.IDToken(map[string]any{"sub": "admin"}), ".")
+		_, err := p.Verify(context.Background(), parts[0]+"."+forged[1]+"."+parts[2], "")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
+
+func TestRoleMapping(t *testing.T) {
+	mapping, err := ParseRoleMapping("realm_access.roles", "shortener-admins=admin, staff=editor", "viewer")
+	require.NoError(t, err)
+
+	token := func(groups ...any) *IDToken {
+		return &IDToken{Claims: map[string]any{"realm_access": map[string]any{"roles": groups}}}
+	}
+	tests := []struct {
+		name  string
+		token *IDToken
+		want  models.Role
+	}{
+		{"Highest group wins", token("staff", "shortener-admins"), models.RoleAdmin},
+		{"Single group", token("staff"), models.RoleEditor},
+		{"No matching group", token("finance"), models.RoleViewer},
+		{"No claim", &IDToken{Claims: map[string]any{}}, models.RoleViewer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, ok := mapping.Role(tt.token)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, role)
+		})
+	}
+
+	// Без роли по умолчанию вход разрешен только участникам групп
+	strict, err := ParseRoleMapping("groups", "staff=editor", "none")
+	require.NoError(t, err)
+	_, ok := strict.Role(&IDToken{Claims: map[string]any{"groups": "finance"}})
+	assert.False(t, ok)
+	role, ok := strict.Role(&IDToken{Claims: map[string]any{"groups": "staff"}})
+	assert.True(t, ok)
+	assert.Equal(t, models.RoleEditor, role)
+
+	for _, rules := range []string{"staff", "staff=owner", "=admin"} {
+		_, err := ParseRoleMapping("groups", rules, "viewer")
+		assert.Error(t, err, rules)
+	}
+	_, err = ParseRoleMapping("groups", "", "root")
+	assert.Error(t, err)
+}
@@ -0,0 +1,235 @@
+// internal/oidc/oidctest/server.go
+
+// Package oidctest содержит OIDC провайдер в памяти для тестов: discovery, JWKS,
+// authorization и token endpoint с проверкой PKCE и аутентификации клиента
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// KeyID идентификатор ключа подписи в JWKS
+const KeyID = "test-key"
+
+// Server провайдер, который без формы входа выдает код от имени пользователя, заданного SetUser
+type Server struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	user  map[string]any
+	codes map[string]grant
+}
+
+// grant выданный, но еще не обмененный код авторизации
+type grant struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+	claims      map[string]any
+}
+
+// NewServer запускает провайдер; вызывающий закрывает его через Close
+func NewServer(clientID, clientSecret string) *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic("oidctest: failed to generate key: " + err.Error())
+	}
+
+	s := &Server{ClientID: clientID, ClientSecret: clientSecret, key: key, codes: make(map[string]grant)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("GET /jwks", s.jwks)
+	mux.HandleFunc("GET /authorize", s.authorize)
+	mux.HandleFunc("POST /token", s.token)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Issuer возвращает идентификатор провайдера
+func (s *Server) Issuer() string {
+	return s.URL
+}
+
+// SetUser задает claims пользователя (sub, email, groups...), который "входит" на /authorize.
+// nil - пользователь отказывается от входа
+func (s *Server) SetUser(claims map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user = claims
+}
+
+// IDToken подписывает ID токен для клиента; claims дополняют и переопределяют стандартные
+func (s *Server) IDToken(claims map[string]any) string {
+	now := time.Now()
+	payload := map[string]any{
+		"iss": s.Issuer(),
+		"aud": s.ClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for name, value := range claims {
+		if value == nil {
+			delete(payload, name)
+			continue
+		}
+		payload[name] = value
+	}
+	return s.Sign(map[string]any{"alg": "RS256", "kid": KeyID, "typ": "JWT"}, payload)
+}
+
+// Sign подписывает произвольные заголовок и payload ключом провайдера
+func (s *Server) Sign(header, payload map[string]any) string {
+	input := encodeSegment(header) + "." + encodeSegment(payload)
+	digest := sha256.Sum256([]byte(input))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic("oidctest: failed to sign token: " + err.Error())
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func encodeSegment(v any) string {
+	data, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                s.Issuer(),
+		"authorization_endpoint":                s.URL + "/authorize",
+		"token_endpoint":                        s.URL + "/token",
+		"jwks_uri":                              s.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": KeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI := query.Get("redirect_uri")
+	if query.Get("client_id") != s.ClientID || redirectURI == "" {
+		http.Error(w, "unknown client or redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "authorization code flow with PKCE S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	params := url.Values{"state": {query.Get("state")}}
+	s.mu.Lock()
+	if s.user == nil {
+		params.Set("error", "access_denied")
+	} else {
+		code := randomString()
+		s.codes[code] = grant{
+			redirectURI: redirectURI,
+			challenge:   query.Get("code_challenge"),
+			nonce:       query.Get("nonce"),
+			claims:      s.user,
+		}
+		params.Set("code", code)
+	}
+	s.mu.Unlock()
+
+	http.Redirect(w, r, redirectURI+"?"+params.Encode(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if !s.authenticateClient(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	// Код одноразовый: удаляется и при неудачном обмене
+	s.mu.Lock()
+	g, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	switch {
+	case !ok:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "unknown code"})
+		return
+	case g.redirectURI != r.PostForm.Get("redirect_uri"):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "redirect_uri mismatch"})
+		return
+	case base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	claims := map[string]any{"nonce": g.nonce}
+	for name, value := range g.claims {
+		claims[name] = value
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     s.IDToken(claims),
+	})
+}
+
+// authenticateClient проверяет client_secret_basic, а для публичного клиента - client_id в форме
+func (s *Server) authenticateClient(r *http.Request) bool {
+	if err := r.ParseForm(); err != nil {
+		return false
+	}
+	if s.ClientSecret == "" {
+		return r.PostForm.Get("client_id") == s.ClientID
+	}
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	id, _ = url.QueryUnescape(id)
+	secret, _ = url.QueryUnescape(secret)
+	return id == s.ClientID && secret == s.ClientSecret
+}
+
+func randomString() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,87 @@
+// internal/oidc/roles.go
+
+package oidc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+)
+
+// RoleMapping сопоставляет группы из ID токена ролям пользователей
+type RoleMapping struct {
+	Claim   string                 // Claim со списком групп; вложенный задается через точку: realm_access.roles
+	Groups  map[string]models.Role // Группа провайдера -> роль
+	Default models.Role            // Роль пользователя без подходящей группы; пусто - вход запрещен
+}
+
+// ParseRoleMapping разбирает правила вида "group=role" через запятую.
+// defaultRole "none" или пустая строка запрещают вход пользователям без подходящей группы
+func ParseRoleMapping(claim, rules, defaultRole string) (RoleMapping, error) {
+	mapping := RoleMapping{Claim: claim, Groups: make(map[string]models.Role)}
+
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		group, name, ok := strings.Cut(rule, "=")
+		role, valid := models.ParseRole(strings.TrimSpace(name))
+		if !ok || !valid || strings.TrimSpace(group) == "" {
+			return RoleMapping{}, fmt.Errorf("invalid role mapping %q, expected group=viewer|editor|admin", rule)
+		}
+		mapping.Groups[strings.TrimSpace(group)] = role
+	}
+
+	if defaultRole != "" && defaultRole != "none" {
+		role, ok := models.ParseRole(defaultRole)
+		if !ok {
+			return RoleMapping{}, fmt.Errorf("invalid default role %q", defaultRole)
+		}
+		mapping.Default = role
+	}
+	return mapping, nil
+}
+
+// Role выбирает роль пользователя: старшую из ролей его групп, иначе Default.
+// false - у пользователя нет доступа
+func (m RoleMapping) Role(token *IDToken) (models.Role, bool) {
+	role := m.Default
+	for _, group := range claimStrings(token.Claims, m.Claim) {
+		if mapped, ok := m.Groups[group]; ok && mapped.AtLeast(role) {
+			role = mapped
+		}
+	}
+	return role, role != ""
+}
+
+// claimStrings достает строку или список строк по пути через точку
+func claimStrings(claims map[string]any, path string) []string {
+	if path == "" {
+		return nil
+	}
+
+	var value any = claims
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
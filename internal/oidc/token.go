@@ -0,0 +1,108 @@
+// internal/oidc/token.go
+
+package oidc
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+)
+
+// ErrInvalidToken ID токен не прошел проверку
+var ErrInvalidToken = errors.New("invalid id token")
+
+// clockSkew допустимое расхождение часов с провайдером
+const clockSkew = time.Minute
+
+// signingAlgs принимаемые алгоритмы подписи; alg "none" и HMAC не принимаются
+var signingAlgs = []string{gooidc.RS256, gooidc.RS384, gooidc.RS512, gooidc.ES256, gooidc.ES384}
+
+// IDToken проверенные claims ID токена
+type IDToken struct {
+	Issuer        string
+	Subject       string
+	Audience      []string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Expiry        time.Time
+	Claims        map[string]any // Все claims, в том числе нестандартные (группы, роли)
+}
+
+// Verify проверяет ID токен. Подпись, issuer, audience и срок действия проверяет go-oidc,
+// остальное по OpenID Connect Core 1.0, 3.1.3.7 - validate
+func (p *Provider) Verify(ctx context.Context, raw, nonce string) (*IDToken, error) {
+	remote, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	verified, err := remote.verifier.Verify(ctx, raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	var payload json.RawMessage
+	if err := verified.Claims(&payload); err != nil {
+		return nil, fmt.Errorf("%w: payload: %v", ErrInvalidToken, err)
+	}
+	var claims map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&claims); err != nil {
+		return nil, fmt.Errorf("%w: payload: %v", ErrInvalidToken, err)
+	}
+
+	token := tokenFromClaims(verified, claims)
+	if err := p.validate(token, verified.IssuedAt, nonce); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return token, nil
+}
+
+// validate проверяет claims, которые go-oidc оставляет приложению
+func (p *Provider) validate(token *IDToken, issuedAt time.Time, nonce string) error {
+	switch {
+	case len(token.Audience) > 1 && stringClaim(token.Claims, "azp") != p.cfg.ClientID:
+		return errors.New("azp does not match client")
+	case token.Subject == "":
+		return errors.New("sub is missing")
+	case issuedAt.After(p.now().Add(clockSkew)):
+		return errors.New("token is issued in the future")
+	}
+
+	if nonce != "" && subtle.ConstantTimeCompare([]byte(stringClaim(token.Claims, "nonce")), []byte(nonce)) != 1 {
+		return errors.New("nonce does not match")
+	}
+	return nil
+}
+
+func tokenFromClaims(verified *gooidc.IDToken, claims map[string]any) *IDToken {
+	token := &IDToken{
+		Issuer:   verified.Issuer,
+		Subject:  verified.Subject,
+		Audience: verified.Audience,
+		Email:    stringClaim(claims, "email"),
+		Name:     stringClaim(claims, "name"),
+		Expiry:   verified.Expiry,
+		Claims:   claims,
+	}
+	// Некоторые провайдеры передают email_verified строкой
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		token.EmailVerified = verified
+	case string:
+		token.EmailVerified = verified == "true"
+	}
+	return token
+}
+
+func stringClaim(claims map[string]any, name string) string {
+	s, _ := claims[name].(string)
+	return s
+}
@@ -0,0 +1,54 @@
+// internal/ratelimit/attempts.go
+
+package ratelimit
+
+import "time"
+
+// TargetFactor во сколько раз общий лимит ошибок для цели (ссылки, учетной записи)
+// больше лимита для одного клиента
+const TargetFactor = 10
+
+// AttemptLimiter ограничивает неудачные попытки подобрать секрет цели двумя счетчиками:
+// для пары цель + клиент и для цели в целом. Второй не зависит от IP, поэтому перебор
+// с множества адресов тоже упирается в лимит
+type AttemptLimiter struct {
+	clients *FailureLimiter
+	targets *FailureLimiter
+}
+
+// NewAttemptLimiter создает ограничитель: не более maxFailures ошибок за window для клиента
+// и не более maxFailures*TargetFactor для цели
+func NewAttemptLimiter(maxFailures int, window time.Duration) *AttemptLimiter {
+	clients := NewFailureLimiter(maxFailures, window)
+	return &AttemptLimiter{
+		clients: clients,
+		targets: NewFailureLimiter(clients.maxFailures*TargetFactor, window),
+	}
+}
+
+// SetLimits меняет ограничения на лету. Неположительные значения игнорируются
+func (l *AttemptLimiter) SetLimits(maxFailures int, window time.Duration) {
+	l.clients.SetLimits(maxFailures, window)
+	l.targets.SetLimits(maxFailures*TargetFactor, window)
+}
+
+// Allow сообщает, можно ли клиенту сделать еще одну попытку для цели.
+// Если нельзя, возвращает время до снятия блокировки
+func (l *AttemptLimiter) Allow(target, client string) (bool, time.Duration) {
+	if allowed, retryAfter := l.targets.Allow(target); !allowed {
+		return false, retryAfter
+	}
+	return l.clients.Allow(target + "|" + client)
+}
+
+// Fail регистрирует неудачную попытку клиента
+func (l *AttemptLimiter) Fail(target, client string) {
+	l.targets.Fail(target)
+	l.clients.Fail(target + "|" + client)
+}
+
+// Reset сбрасывает ошибки клиента после успешной попытки. Общий счетчик цели не сбрасывается,
+// чтобы удачный вход владельца не возвращал перебирающему весь лимит
+func (l *AttemptLimiter) Reset(target, client string) {
+	l.clients.Reset(target + "|" + client)
+}
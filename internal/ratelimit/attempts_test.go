@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttemptLimiter(t *testing.T) {
+	limiter := NewAttemptLimiter(2, time.Minute)
+
+	limiter.Fail("abc123", "192.0.2.1")
+	limiter.Fail("abc123", "192.0.2.1")
+	allowed, _ := limiter.Allow("abc123", "192.0.2.1")
+	assert.False(t, allowed)
+
+	// Другой клиент и другая цель не затронуты
+	allowed, _ = limiter.Allow("abc123", "192.0.2.2")
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("other1", "192.0.2.1")
+	assert.True(t, allowed)
+}
+
+func TestAttemptLimiter_TargetCap(t *testing.T) {
+	limiter := NewAttemptLimiter(2, time.Minute)
+
+	// Перебор с разных адресов упирается в общий лимит цели
+	for i := 0; i < 2*TargetFactor; i++ {
+		client := fmt.Sprintf("192.0.2.%d", i)
+		allowed, _ := limiter.Allow("abc123", client)
+		assert.True(t, allowed)
+		limiter.Fail("abc123", client)
+	}
+
+	allowed, retryAfter := limiter.Allow("abc123", "198.51.100.1")
+	assert.False(t, allowed)
+	assert.Greater(t, retryAfter, time.Duration(0))
+
+	// Успешная попытка сбрасывает только счетчик клиента
+	limiter.Reset("abc123", "192.0.2.0")
+	allowed, _ = limiter.Allow("abc123", "192.0.2.0")
+	assert.False(t, allowed)
+}
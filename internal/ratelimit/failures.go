@@ -0,0 +1,105 @@
+// internal/ratelimit/failures.go
+
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// FailureLimiter ограничивает количество неудачных попыток (например, ввода пароля)
+// для одного ключа в скользящем окне. Успешные попытки не учитываются.
+type FailureLimiter struct {
+	mu          sync.Mutex
+	maxFailures int
+	window      time.Duration
+	failures    map[string][]time.Time
+	now         func() time.Time
+}
+
+// NewFailureLimiter создает ограничитель: не более maxFailures ошибок за window
+func NewFailureLimiter(maxFailures int, window time.Duration) *FailureLimiter {
+	if maxFailures <= 0 {
+		maxFailures = 5
+	}
+	if window <= 0 {
+		window = 15 * time.Minute
+	}
+	return &FailureLimiter{
+		maxFailures: maxFailures,
+		window:      window,
+		failures:    make(map[string][]time.Time),
+		now:         time.Now,
+	}
+}
+
+// SetLimits меняет ограничения на лету. Уже зарегистрированные ошибки сохраняются
+// и оцениваются по новому окну. Неположительные значения игнорируются
+func (l *FailureLimiter) SetLimits(maxFailures int, window time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if maxFailures > 0 {
+		l.maxFailures = maxFailures
+	}
+	if window > 0 {
+		l.window = window
+	}
+}
+
+// Allow сообщает, можно ли сделать еще одну попытку для ключа.
+// Если нельзя, возвращает время до снятия блокировки
+func (l *FailureLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	recent := l.recent(key)
+	if len(recent) < l.maxFailures {
+		return true, 0
+	}
+	return false, recent[0].Add(l.window).Sub(l.now())
+}
+
+// Fail регистрирует неудачную попытку для ключа
+func (l *FailureLimiter) Fail(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.failures[key] = append(l.recent(key), l.now())
+	l.prune()
+}
+
+// Reset сбрасывает счетчик ошибок после успешной попытки
+func (l *FailureLimiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, key)
+}
+
+// recent возвращает ошибки ключа внутри окна и отбрасывает устаревшие
+func (l *FailureLimiter) recent(key string) []time.Time {
+	cutoff := l.now().Add(-l.window)
+	times := l.failures[key]
+	i := 0
+	for i < len(times) && !times[i].After(cutoff) {
+		i++
+	}
+	if i == len(times) {
+		delete(l.failures, key)
+		return nil
+	}
+	times = times[i:]
+	l.failures[key] = times
+	return times
+}
+
+// prune удаляет ключи без актуальных ошибок, чтобы карта не росла бесконечно.
+// Полный проход выполняется, только когда ключей стало заметно много
+func (l *FailureLimiter) prune() {
+	if len(l.failures) < 1024 {
+		return
+	}
+	for key := range l.failures {
+		l.recent(key)
+	}
+}
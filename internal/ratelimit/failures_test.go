@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailureLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewFailureLimiter(3, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		allowed, _ := limiter.Allow("abc123|192.0.2.1")
+		assert.True(t, allowed)
+		limiter.Fail("abc123|192.0.2.1")
+		now = now.Add(10 * time.Second)
+	}
+
+	allowed, retryAfter := limiter.Allow("abc123|192.0.2.1")
+	assert.False(t, allowed)
+	assert.Equal(t, 30*time.Second, retryAfter)
+
+	// Другие ключи не затронуты
+	allowed, _ = limiter.Allow("abc123|192.0.2.2")
+	assert.True(t, allowed)
+
+	// Самая старая ошибка выходит из окна
+	now = now.Add(30 * time.Second)
+	allowed, _ = limiter.Allow("abc123|192.0.2.1")
+	assert.True(t, allowed)
+}
+
+func TestFailureLimiter_Reset(t *testing.T) {
+	limiter := NewFailureLimiter(1, time.Minute)
+
+	limiter.Fail("key")
+	allowed, _ := limiter.Allow("key")
+	assert.False(t, allowed)
+
+	limiter.Reset("key")
+	allowed, _ = limiter.Allow("key")
+	assert.True(t, allowed)
+}
+
+func TestFailureLimiter_SetLimits(t *testing.T) {
+	limiter := NewFailureLimiter(3, time.Minute)
+
+	limiter.Fail("key")
+	limiter.Fail("key")
+	allowed, _ := limiter.Allow("key")
+	assert.True(t, allowed)
+
+	// Более строгий лимит применяется к уже накопленным ошибкам
+	limiter.SetLimits(2, 0)
+	allowed, _ = limiter.Allow("key")
+	assert.False(t, allowed)
+
+	limiter.SetLimits(5, time.Hour)
+	allowed, _ = limiter.Allow("key")
+	assert.True(t, allowed)
+}
@@ -0,0 +1,130 @@
+// internal/server/server.go
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Options задает параметры HTTP сервера и его остановки
+type Options struct {
+	Addr         string
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+
+	// ShutdownTimeout ограничивает ожидание текущих запросов и, отдельно, каждого шага остановки
+	ShutdownTimeout time.Duration
+	// DrainDelay пауза после снятия готовности, чтобы балансировщик успел убрать инстанс
+	// из ротации, пока listener еще принимает соединения
+	DrainDelay time.Duration
+}
+
+// hook шаг остановки, выполняемый после завершения HTTP запросов
+type hook struct {
+	name string
+	fn   func(context.Context) error
+}
+
+// Server управляет жизненным циклом HTTP сервера: запуск, признак готовности
+// и упорядоченная остановка фоновых компонентов
+type Server struct {
+	http  *http.Server
+	opts  Options
+	ready atomic.Bool
+	hooks []hook
+}
+
+func New(handler http.Handler, opts Options) *Server {
+	if opts.ShutdownTimeout <= 0 {
+		opts.ShutdownTimeout = 20 * time.Second
+	}
+
+	return &Server{
+		http: &http.Server{
+			Addr:         opts.Addr,
+			Handler:      handler,
+			ReadTimeout:  opts.ReadTimeout,
+			WriteTimeout: opts.WriteTimeout,
+			IdleTimeout:  opts.IdleTimeout,
+		},
+		opts: opts,
+	}
+}
+
+// OnShutdown регистрирует шаг остановки. Шаги выполняются в порядке регистрации
+// после того, как сервер перестал принимать запросы и дождался текущих:
+// сначала регистрируются потребители (регистратор кликов, janitor), затем их зависимости (Redis, БД)
+func (s *Server) OnShutdown(name string, fn func(context.Context) error) {
+	s.hooks = append(s.hooks, hook{name: name, fn: fn})
+}
+
+// Ready сообщает, принимает ли сервер новый трафик. Используется health check'ом
+func (s *Server) Ready() bool {
+	return s.ready.Load()
+}
+
+// ListenAndServe слушает Options.Addr и обслуживает запросы до отмены ctx
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.http.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.http.Addr, err)
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve обслуживает запросы на ln до отмены ctx, после чего останавливает сервер:
+// снимает готовность, выжидает DrainDelay, дожидается текущих запросов
+// не дольше ShutdownTimeout и выполняет шаги остановки.
+// Ошибки всех шагов объединяются в возвращаемой ошибке
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.http.Serve(ln)
+	}()
+	s.ready.Store(true)
+
+	var errs []error
+	select {
+	case err := <-serveErr:
+		// Сервер упал сам: запросов уже нет, но фоновые компоненты нужно остановить
+		s.ready.Store(false)
+		if !errors.Is(err, http.ErrServerClosed) {
+			errs = append(errs, fmt.Errorf("server failed: %w", err))
+		}
+	case <-ctx.Done():
+		s.ready.Store(false)
+		log.Info().Dur("drain_delay", s.opts.DrainDelay).Msg("Shutting down: readiness withdrawn")
+		time.Sleep(s.opts.DrainDelay)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.opts.ShutdownTimeout)
+		if err := s.http.Shutdown(shutdownCtx); err != nil {
+			// Не дождались: обрываем оставшиеся соединения, чтобы не держать процесс
+			errs = append(errs, fmt.Errorf("failed to drain requests: %w", err))
+			s.http.Close()
+		}
+		cancel()
+		log.Info().Msg("HTTP server stopped")
+	}
+
+	for _, h := range s.hooks {
+		hookCtx, cancel := context.WithTimeout(context.Background(), s.opts.ShutdownTimeout)
+		if err := h.fn(hookCtx); err != nil {
+			log.Error().Err(err).Str("component", h.name).Msg("Failed to stop component")
+			errs = append(errs, fmt.Errorf("failed to stop %s: %w", h.name, err))
+		} else {
+			log.Info().Str("component", h.name).Msg("Component stopped")
+		}
+		cancel()
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/clicks"
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startServer запускает Serve на случайном порту и возвращает адрес и канал с результатом
+func startServer(t *testing.T, ctx context.Context, srv *Server) (string, <-chan error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(ctx, ln)
+	}()
+	require.Eventually(t, srv.Ready, time.Second, time.Millisecond)
+	return "http://" + ln.Addr().String(), done
+}
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	require.NoError(t, mockStorage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "abc123"}))
+	recorder := clicks.NewBufferedRecorder(mockStorage, clicks.Options{FlushInterval: time.Hour})
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		// Клик регистрируется уже после начала остановки и должен попасть в хранилище
+		recorder.Record(&models.Click{ShortCode: "abc123", ClickedAt: time.Now()})
+		w.WriteHeader(http.StatusFound)
+	})
+
+	srv := New(handler, Options{ShutdownTimeout: 5 * time.Second, DrainDelay: 50 * time.Millisecond})
+
+	var mu sync.Mutex
+	var stopped []string
+	srv.OnShutdown("clicks", func(context.Context) error {
+		mu.Lock()
+		stopped = append(stopped, "clicks")
+		mu.Unlock()
+		return recorder.Close()
+	})
+	srv.OnShutdown("database", func(context.Context) error {
+		mu.Lock()
+		stopped = append(stopped, "database")
+		mu.Unlock()
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	baseURL, done := startServer(t, ctx, srv)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	status := make(chan int, 1)
+	go func() {
+		resp, err := client.Get(baseURL + "/abc123")
+		if err != nil {
+			status <- 0
+			return
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	<-entered
+
+	// Сигнал остановки: готовность снимается сразу, запрос еще выполняется
+	cancel()
+	require.Eventually(t, func() bool { return !srv.Ready() }, time.Second, time.Millisecond)
+
+	select {
+	case <-done:
+		t.Fatal("Serve returned before the in-flight request completed")
+	case <-time.After(100 * time.Millisecond):
+	}
+	mu.Lock()
+	assert.Empty(t, stopped, "components must not stop while requests are in flight")
+	mu.Unlock()
+
+	close(release)
+	assert.Equal(t, http.StatusFound, <-status)
+	require.NoError(t, <-done)
+
+	assert.Equal(t, []string{"clicks", "database"}, stopped)
+
+	url, err := mockStorage.GetURL(context.Background(), "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), url.ClickCount)
+
+	// После остановки новые соединения не принимаются
+	_, err = client.Get(baseURL + "/abc123")
+	assert.Error(t, err)
+}
+
+func TestServeShutdownTimeout(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	})
+
+	srv := New(handler, Options{ShutdownTimeout: 50 * time.Millisecond})
+	hookCalled := false
+	srv.OnShutdown("database", func(context.Context) error {
+		hookCalled = true
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	baseURL, done := startServer(t, ctx, srv)
+
+	go func() {
+		resp, err := http.Get(baseURL + "/")
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-entered
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve did not give up after ShutdownTimeout")
+	}
+	// Шаги остановки выполняются даже если запросы не успели завершиться
+	assert.True(t, hookCalled)
+}
@@ -0,0 +1,155 @@
+// internal/settings/settings.go
+
+package settings
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// Settings снимок настроек, которые можно менять без перезапуска сервера.
+// Снимок неизменяемый: при перезагрузке создается новый и атомарно подменяется в Store
+type Settings struct {
+	LogLevel zerolog.Level
+
+	PasswordMaxAttempts   int
+	PasswordAttemptWindow time.Duration
+
+	BlockedDomains []string // Нормализованные домены в нижнем регистре
+
+	CacheTTL               time.Duration
+	CacheNegativeTTL       time.Duration
+	MemoryCacheTTL         time.Duration
+	MemoryCacheNegativeTTL time.Duration
+}
+
+// FromConfig выбирает из конфигурации настройки, применяемые на лету
+func FromConfig(cfg *config.Config) *Settings {
+	level, err := zerolog.ParseLevel(cfg.LogLevel)
+	if err != nil || cfg.LogLevel == "" {
+		level = zerolog.InfoLevel
+	}
+
+	return &Settings{
+		LogLevel:               level,
+		PasswordMaxAttempts:    cfg.PasswordMaxAttempts,
+		PasswordAttemptWindow:  cfg.PasswordAttemptWindow,
+		BlockedDomains:         parseDomains(cfg.BlockedDomains),
+		CacheTTL:               cfg.CacheTTL,
+		CacheNegativeTTL:       cfg.CacheNegativeTTL,
+		MemoryCacheTTL:         cfg.MemoryCacheTTL,
+		MemoryCacheNegativeTTL: cfg.MemoryCacheNegativeTTL,
+	}
+}
+
+// parseDomains разбирает список доменов через запятую. Префиксы "*." и "." отбрасываются:
+// домен всегда блокируется вместе с поддоменами
+func parseDomains(raw string) []string {
+	var domains []string
+	for _, domain := range strings.Split(raw, ",") {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		domain = strings.TrimPrefix(domain, "*")
+		domain = strings.Trim(domain, ".")
+		if domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// IsBlocked сообщает, входит ли хост или один из его родительских доменов в список блокировки
+func (s *Settings) IsBlocked(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, domain := range s.BlockedDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// field значение настройки под именем ключа конфигурации
+type field struct {
+	key   string
+	value string
+}
+
+func (s *Settings) fields() []field {
+	return []field{
+		{"LOG_LEVEL", s.LogLevel.String()},
+		{"PASSWORD_MAX_ATTEMPTS", fmt.Sprint(s.PasswordMaxAttempts)},
+		{"PASSWORD_ATTEMPT_WINDOW", s.PasswordAttemptWindow.String()},
+		{"BLOCKED_DOMAINS", strings.Join(s.BlockedDomains, ",")},
+		{"CACHE_TTL", s.CacheTTL.String()},
+		{"CACHE_NEGATIVE_TTL", s.CacheNegativeTTL.String()},
+		{"MEMORY_CACHE_TTL", s.MemoryCacheTTL.String()},
+		{"MEMORY_CACHE_NEGATIVE_TTL", s.MemoryCacheNegativeTTL.String()},
+	}
+}
+
+// Keys возвращает ключи конфигурации, которые применяются без перезапуска
+func Keys() []string {
+	fields := (&Settings{}).fields()
+	keys := make([]string, len(fields))
+	for i, f := range fields {
+		keys[i] = f.key
+	}
+	return keys
+}
+
+// Diff перечисляет изменившиеся настройки в виде "KEY: old -> new"
+func (s *Settings) Diff(next *Settings) []string {
+	var changes []string
+	before, after := s.fields(), next.fields()
+	for i := range before {
+		if before[i].value != after[i].value {
+			changes = append(changes, fmt.Sprintf("%s: %q -> %q", before[i].key, before[i].value, after[i].value))
+		}
+	}
+	return changes
+}
+
+// Store хранит текущий снимок настроек. Чтение не требует блокировок,
+// поэтому Current можно вызывать на каждом запросе
+type Store struct {
+	current atomic.Pointer[Settings]
+
+	mu          sync.Mutex
+	subscribers []func(*Settings)
+}
+
+func NewStore(initial *Settings) *Store {
+	s := &Store{}
+	s.current.Store(initial)
+	return s
+}
+
+// Current возвращает действующий снимок настроек
+func (s *Store) Current() *Settings {
+	return s.current.Load()
+}
+
+// Subscribe регистрирует функцию, применяющую настройки к компоненту.
+// Она вызывается сразу с текущим снимком и затем после каждого Update
+func (s *Store) Subscribe(fn func(*Settings)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, fn)
+	fn(s.current.Load())
+}
+
+// Update подменяет снимок и уведомляет подписчиков в порядке регистрации
+func (s *Store) Update(next *Settings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current.Store(next)
+	for _, fn := range s.subscribers {
+		fn(next)
+	}
+}
@@ -0,0 +1,104 @@
+package settings
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromConfig(t *testing.T) {
+	cfg := config.Default()
+	cfg.LogLevel = "debug"
+	cfg.BlockedDomains = " Evil.example, *.phishing.test,.spam.test,, "
+
+	s := FromConfig(cfg)
+	assert.Equal(t, zerolog.DebugLevel, s.LogLevel)
+	assert.Equal(t, []string{"evil.example", "phishing.test", "spam.test"}, s.BlockedDomains)
+	assert.Equal(t, cfg.PasswordMaxAttempts, s.PasswordMaxAttempts)
+	assert.Equal(t, cfg.MemoryCacheTTL, s.MemoryCacheTTL)
+}
+
+func TestSettings_IsBlocked(t *testing.T) {
+	s := &Settings{BlockedDomains: []string{"evil.example"}}
+
+	assert.True(t, s.IsBlocked("evil.example"))
+	assert.True(t, s.IsBlocked("WWW.Evil.Example."))
+	assert.False(t, s.IsBlocked("notevil.example"))
+	assert.False(t, s.IsBlocked("example"))
+}
+
+func TestSettings_Diff(t *testing.T) {
+	before := FromConfig(config.Default())
+	cfg := config.Default()
+	cfg.LogLevel = "warn"
+	cfg.CacheTTL = time.Minute
+
+	changes := before.Diff(FromConfig(cfg))
+	assert.Equal(t, []string{
+		`LOG_LEVEL: "info" -> "warn"`,
+		`CACHE_TTL: "10m0s" -> "1m0s"`,
+	}, changes)
+	assert.Empty(t, before.Diff(before))
+}
+
+func TestStore(t *testing.T) {
+	store := NewStore(&Settings{PasswordMaxAttempts: 1})
+
+	var seen []int
+	store.Subscribe(func(s *Settings) { seen = append(seen, s.PasswordMaxAttempts) })
+	store.Update(&Settings{PasswordMaxAttempts: 2})
+
+	assert.Equal(t, []int{1, 2}, seen)
+	assert.Equal(t, 2, store.Current().PasswordMaxAttempts)
+}
+
+func TestWatcher_Reload(t *testing.T) {
+	initial := config.Default()
+	store := NewStore(FromConfig(initial))
+
+	next := config.Default()
+	var loadErr error
+	watcher := NewWatcher(store, initial, func() (*config.Config, error) {
+		return next, loadErr
+	}, WatchOptions{})
+
+	// Некорректная конфигурация не меняет действующие настройки
+	loadErr = errors.New("invalid config")
+	next = nil
+	assert.Error(t, watcher.Reload())
+	assert.Equal(t, zerolog.InfoLevel, store.Current().LogLevel)
+
+	loadErr = nil
+	next = config.Default()
+	next.LogLevel = "error"
+	next.ServerPort = "9090" // применяется только после перезапуска
+	require.NoError(t, watcher.Reload())
+	assert.Equal(t, zerolog.ErrorLevel, store.Current().LogLevel)
+	assert.Equal(t, []string{"SERVER_PORT"}, restartRequired(initial, next))
+}
+
+func TestWatcher_FileChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("click_ip_salt: salt\nlog_level: info\n"), 0o600))
+
+	initial := config.Default()
+	store := NewStore(FromConfig(initial))
+	watcher := NewWatcher(store, initial, func() (*config.Config, error) {
+		return config.Load([]string{"--config", path})
+	}, WatchOptions{Path: path, Interval: 10 * time.Millisecond})
+	watcher.Start()
+	defer watcher.Stop()
+
+	require.NoError(t, os.WriteFile(path, []byte("click_ip_salt: salt\nlog_level: warn\nblocked_domains: [evil.example]\n"), 0o600))
+	assert.Eventually(t, func() bool {
+		return store.Current().LogLevel == zerolog.WarnLevel
+	}, time.Second, 10*time.Millisecond)
+	assert.True(t, store.Current().IsBlocked("evil.example"))
+}
@@ -0,0 +1,200 @@
+// internal/settings/watcher.go
+
+package settings
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// Loader заново собирает конфигурацию из всех источников
+type Loader func() (*config.Config, error)
+
+// WatchOptions задает источники сигнала о перезагрузке
+type WatchOptions struct {
+	Path     string        // Файл конфигурации, изменения которого отслеживаются; пусто - только SIGHUP
+	Interval time.Duration // Период проверки файла; 0 - только SIGHUP
+}
+
+// Watcher перезагружает конфигурацию по SIGHUP или при изменении файла и
+// публикует новые настройки в Store. Некорректная конфигурация отклоняется,
+// действующие настройки при этом не меняются
+type Watcher struct {
+	store *Store
+	load  Loader
+	opts  WatchOptions
+
+	mu      sync.Mutex
+	applied *config.Config // Последняя принятая конфигурация
+	file    fileState
+
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	started atomic.Bool
+}
+
+// fileState признаки изменения файла; сравниваются вместо чтения содержимого
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewWatcher создает Watcher; cfg - конфигурация, с которой запущен сервер
+func NewWatcher(store *Store, cfg *config.Config, load Loader, opts WatchOptions) *Watcher {
+	w := &Watcher{
+		store:   store,
+		load:    load,
+		opts:    opts,
+		applied: cfg,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	w.file, _ = w.stat()
+	return w
+}
+
+// Start запускает отслеживание SIGHUP и файла конфигурации
+func (w *Watcher) Start() {
+	if w.started.CompareAndSwap(false, true) {
+		go w.run()
+	}
+}
+
+// Stop прекращает отслеживание и дожидается завершения текущей перезагрузки
+func (w *Watcher) Stop() {
+	w.once.Do(func() {
+		close(w.stop)
+	})
+	if w.started.Load() {
+		<-w.done
+	}
+}
+
+// Reload перечитывает конфигурацию и применяет изменившиеся настройки.
+// Возвращает ошибку, если новая конфигурация некорректна
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	cfg, err := w.load()
+	if err != nil {
+		log.Error().Err(err).Msg("Config reload rejected, keeping current settings")
+		return err
+	}
+
+	next := FromConfig(cfg)
+	changes := w.store.Current().Diff(next)
+	restart := restartRequired(w.applied, cfg)
+	w.applied = cfg
+	w.store.Update(next)
+
+	if len(changes) > 0 {
+		log.Info().Strs("changes", changes).Msg("Runtime settings reloaded")
+	} else {
+		log.Info().Msg("Config reloaded, runtime settings unchanged")
+	}
+	if len(restart) > 0 {
+		log.Warn().Strs("keys", restart).Msg("Changed settings take effect only after restart")
+	}
+	return nil
+}
+
+func (w *Watcher) run() {
+	defer close(w.done)
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	var tick <-chan time.Time
+	if w.opts.Path != "" && w.opts.Interval > 0 {
+		ticker := time.NewTicker(w.opts.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-hangup:
+			log.Info().Msg("SIGHUP received, reloading config")
+			_ = w.Reload()
+		case <-tick:
+			if w.fileChanged() {
+				log.Info().Str("path", w.opts.Path).Msg("Config file changed, reloading config")
+				_ = w.Reload()
+			}
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// fileChanged сравнивает время изменения и размер файла с предыдущей проверкой.
+// Отсутствующий файл (например, во время атомарной замены) изменением не считается
+func (w *Watcher) fileChanged() bool {
+	state, err := w.stat()
+	if err != nil {
+		return false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if state == w.file {
+		return false
+	}
+	w.file = state
+	return true
+}
+
+func (w *Watcher) stat() (fileState, error) {
+	if w.opts.Path == "" {
+		return fileState{}, os.ErrNotExist
+	}
+	info, err := os.Stat(w.opts.Path)
+	if err != nil {
+		return fileState{}, err
+	}
+	return fileState{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// restartRequired перечисляет изменившиеся ключи, которые не применяются на лету
+func restartRequired(before, after *config.Config) []string {
+	old, current := printed(before), printed(after)
+	runtime := Keys()
+
+	var keys []string
+	for key, value := range current {
+		if old[key] != value && !slices.Contains(runtime, key) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// printed раскладывает вывод config.Print в карту KEY -> value
+func printed(cfg *config.Config) map[string]string {
+	var buf bytes.Buffer
+	_ = config.Print(&buf, cfg)
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), "="); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
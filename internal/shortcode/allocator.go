@@ -0,0 +1,151 @@
+// internal/shortcode/allocator.go
+
+package shortcode
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/drerr0r/url-shortener/internal/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// Границы длины кода совпадают с utils.IsValidShortCode
+const (
+	MinLength = 4
+	MaxLength = 12
+)
+
+// ErrExhausted возвращается, если за отведенное число попыток не удалось подобрать свободный код
+var ErrExhausted = errors.New("failed to allocate unique short code")
+
+// Options задает параметры выдачи коротких кодов
+type Options struct {
+	Generator   CodeGenerator // Стратегия генерации кодов, по умолчанию RandomGenerator
+	Length      int           // Начальная длина кода
+	MaxLength   int           // Предел автоматического увеличения длины
+	MaxAttempts int           // Количество попыток сохранить ссылку
+	Backoff     time.Duration // Базовая задержка между попытками, удваивается с каждой попыткой
+	MaxBackoff  time.Duration // Предел задержки между попытками, по умолчанию 1 секунда
+	GrowAfter   int           // Количество коллизий подряд, после которого длина увеличивается; только для случайных стратегий
+}
+
+// Allocator генерирует короткие коды и сохраняет ссылки, повторяя попытку при коллизии.
+// Если у случайной стратегии коллизии становятся частыми (пространство кодов заполняется),
+// длина кода увеличивается для всех последующих ссылок. Коллизии детерминированных
+// стратегий длину не меняют, иначе ее мог бы поднять любой, повторяя один и тот же URL
+type Allocator struct {
+	storage storage.Storage
+	opts    Options
+	grows   bool
+	length  atomic.Int32
+}
+
+// NewAllocator создает Allocator, приводя параметры к допустимым значениям
+func NewAllocator(storage storage.Storage, opts Options) *Allocator {
+	opts.Length = clamp(opts.Length, MinLength, MaxLength)
+	if opts.MaxLength == 0 {
+		opts.MaxLength = MaxLength
+	}
+	opts.MaxLength = clamp(opts.MaxLength, opts.Length, MaxLength)
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.GrowAfter <= 0 {
+		opts.GrowAfter = 2
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = time.Second
+	}
+	if opts.Generator == nil {
+		opts.Generator = RandomGenerator{}
+	}
+
+	_, fixed := opts.Generator.(deterministic)
+	a := &Allocator{storage: storage, opts: opts, grows: !fixed}
+	a.length.Store(int32(opts.Length))
+	return a
+}
+
+// Length возвращает текущую длину генерируемых кодов
+func (a *Allocator) Length() int {
+	return int(a.length.Load())
+}
+
+// Allocate подбирает свободный короткий код, записывает его в url.ShortCode и сохраняет ссылку.
+// Ожидание между попытками прерывается отменой ctx
+func (a *Allocator) Allocate(ctx context.Context, url *models.URL) error {
+	collisions := 0
+	for attempt := 0; attempt < a.opts.MaxAttempts; attempt++ {
+		if attempt > 0 && a.opts.Backoff > 0 {
+			select {
+			case <-ctx.Done():
+				url.ShortCode = ""
+				return ctx.Err()
+			case <-time.After(a.backoff(attempt)):
+			}
+		}
+
+		length := a.Length()
+		code, err := a.opts.Generator.Generate(ctx, url.OriginalURL, length, attempt)
+		if err != nil {
+			return err
+		}
+		if utils.IsReservedShortCode(code) || !utils.IsValidShortCode(code) {
+			continue
+		}
+
+		url.ShortCode = code
+		err = a.storage.SaveURL(ctx, url)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, storage.ErrAlreadyExists) {
+			return err
+		}
+
+		collisions++
+		log.Warn().Str("short_code", code).Int("attempt", attempt+1).Msg("Short code collision")
+		if a.grows && collisions >= a.opts.GrowAfter {
+			a.grow(length)
+			collisions = 0
+		}
+	}
+
+	url.ShortCode = ""
+	return ErrExhausted
+}
+
+// backoff возвращает задержку перед попыткой attempt: Backoff, удвоенная за каждую
+// предыдущую повторную попытку, но не больше MaxBackoff
+func (a *Allocator) backoff(attempt int) time.Duration {
+	d := a.opts.Backoff
+	for i := 1; i < attempt && d < a.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, a.opts.MaxBackoff)
+}
+
+// grow увеличивает длину кода на единицу, если ее еще не увеличил параллельный запрос
+func (a *Allocator) grow(from int) {
+	if from >= a.opts.MaxLength {
+		return
+	}
+	if a.length.CompareAndSwap(int32(from), int32(from+1)) {
+		log.Warn().Int("length", from+1).Msg("Short code keyspace is dense, increasing code length")
+	}
+}
+
+func clamp(value, low, high int) int {
+	if value < low {
+		return low
+	}
+	if value > high {
+		return high
+	}
+	return value
+}
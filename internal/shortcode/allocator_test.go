@@ -0,0 +1,140 @@
+package shortcode
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// collidingStorage отвечает ErrAlreadyExists на первые collisions попыток сохранения
+type collidingStorage struct {
+	*storage.MockStorage
+	collisions int
+	lengths    []int
+}
+
+func (s *collidingStorage) SaveURL(ctx context.Context, url *models.URL) error {
+	s.lengths = append(s.lengths, len(url.ShortCode))
+	if s.collisions > 0 {
+		s.collisions--
+		return storage.ErrAlreadyExists
+	}
+	return s.MockStorage.SaveURL(context.Background(), url)
+}
+
+func TestAllocator_UsesConfiguredLength(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	allocator := NewAllocator(mockStorage, Options{Length: 8})
+
+	url := &models.URL{OriginalURL: "https://example.com"}
+	err := allocator.Allocate(context.Background(), url)
+	assert.NoError(t, err)
+	assert.Len(t, url.ShortCode, 8)
+
+	exists, err := mockStorage.URLExists(context.Background(), url.ShortCode)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestAllocator_RetriesOnCollision(t *testing.T) {
+	colliding := &collidingStorage{MockStorage: storage.NewMockStorage(), collisions: 1}
+	allocator := NewAllocator(colliding, Options{Length: 6, GrowAfter: 3})
+
+	url := &models.URL{OriginalURL: "https://example.com"}
+	err := allocator.Allocate(context.Background(), url)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{6, 6}, colliding.lengths)
+	assert.Equal(t, 6, allocator.Length())
+}
+
+func TestAllocator_GrowsLengthWhenKeyspaceIsDense(t *testing.T) {
+	colliding := &collidingStorage{MockStorage: storage.NewMockStorage(), collisions: 2}
+	allocator := NewAllocator(colliding, Options{Length: 6, GrowAfter: 2})
+
+	url := &models.URL{OriginalURL: "https://example.com"}
+	err := allocator.Allocate(context.Background(), url)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{6, 6, 7}, colliding.lengths)
+	assert.Len(t, url.ShortCode, 7)
+
+	// Увеличенная длина сохраняется для следующих ссылок
+	assert.Equal(t, 7, allocator.Length())
+}
+
+func TestAllocator_DeterministicCollisionsDoNotGrowLength(t *testing.T) {
+	colliding := &collidingStorage{MockStorage: storage.NewMockStorage(), collisions: 10}
+	allocator := NewAllocator(colliding, Options{Generator: HashGenerator{}, Length: 6, MaxAttempts: 4, GrowAfter: 1})
+
+	// Один и тот же URL у стратегии hash сталкивается всегда, это не признак заполненности
+	err := allocator.Allocate(context.Background(), &models.URL{OriginalURL: "https://example.com"})
+	assert.ErrorIs(t, err, ErrExhausted)
+	assert.Equal(t, []int{6, 6, 6, 6}, colliding.lengths)
+	assert.Equal(t, 6, allocator.Length())
+}
+
+func TestAllocator_DoesNotGrowBeyondMaxLength(t *testing.T) {
+	colliding := &collidingStorage{MockStorage: storage.NewMockStorage(), collisions: 10}
+	allocator := NewAllocator(colliding, Options{Length: 6, MaxLength: 7, MaxAttempts: 6, GrowAfter: 1})
+
+	err := allocator.Allocate(context.Background(), &models.URL{OriginalURL: "https://example.com"})
+	assert.ErrorIs(t, err, ErrExhausted)
+	assert.Equal(t, 7, allocator.Length())
+	assert.Len(t, colliding.lengths, 6)
+}
+
+func TestAllocator_ReturnsStorageErrors(t *testing.T) {
+	failing := &failingStorage{MockStorage: storage.NewMockStorage()}
+	allocator := NewAllocator(failing, Options{Length: 6})
+
+	err := allocator.Allocate(context.Background(), &models.URL{OriginalURL: "https://example.com"})
+	assert.EqualError(t, err, "connection refused")
+	assert.Equal(t, 1, failing.calls)
+}
+
+func TestAllocator_BackoffIsClamped(t *testing.T) {
+	allocator := NewAllocator(storage.NewMockStorage(), Options{Backoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond})
+
+	assert.Equal(t, 10*time.Millisecond, allocator.backoff(1))
+	assert.Equal(t, 20*time.Millisecond, allocator.backoff(2))
+	assert.Equal(t, 40*time.Millisecond, allocator.backoff(3))
+	assert.Equal(t, 50*time.Millisecond, allocator.backoff(4))
+	assert.Equal(t, 50*time.Millisecond, allocator.backoff(100))
+}
+
+func TestAllocator_BackoffRespectsContext(t *testing.T) {
+	colliding := &collidingStorage{MockStorage: storage.NewMockStorage(), collisions: 10}
+	allocator := NewAllocator(colliding, Options{Length: 6, MaxAttempts: 10, Backoff: time.Hour, MaxBackoff: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	url := &models.URL{OriginalURL: "https://example.com"}
+	start := time.Now()
+	err := allocator.Allocate(ctx, url)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Empty(t, url.ShortCode)
+	assert.Len(t, colliding.lengths, 1)
+}
+
+func TestNewAllocator_ClampsLength(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+
+	assert.Equal(t, MinLength, NewAllocator(mockStorage, Options{Length: 2}).Length())
+	assert.Equal(t, MaxLength, NewAllocator(mockStorage, Options{Length: 20}).Length())
+}
+
+type failingStorage struct {
+	*storage.MockStorage
+	calls int
+}
+
+func (s *failingStorage) SaveURL(ctx context.Context, url *models.URL) error {
+	s.calls++
+	return errors.New("connection refused")
+}
@@ -0,0 +1,118 @@
+// internal/shortcode/generator.go
+
+package shortcode
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/drerr0r/url-shortener/internal/utils"
+)
+
+// Стратегии генерации коротких кодов
+const (
+	StrategyRandom     = "random"
+	StrategySequential = "sequential"
+	StrategyHash       = "hash"
+	StrategyWords      = "words"
+)
+
+// CodeGenerator генерирует кандидата в короткие коды.
+// attempt - номер попытки для этой ссылки (с нуля): детерминированные стратегии
+// используют его, чтобы после коллизии выдать другой код
+type CodeGenerator interface {
+	Generate(ctx context.Context, originalURL string, length, attempt int) (string, error)
+}
+
+// deterministic помечает стратегии, которые выдают код по входным данным, а не случайно.
+// Их коллизии не говорят о заполненности пространства кодов: повторное сокращение
+// того же URL стратегией hash сталкивается всегда
+type deterministic interface {
+	deterministic()
+}
+
+// NewGenerator создает генератор по имени стратегии из конфигурации.
+// key используется стратегией sequential для перестановки номеров
+func NewGenerator(strategy string, storage storage.Storage, key uint64) (CodeGenerator, error) {
+	switch strategy {
+	case "", StrategyRandom:
+		return RandomGenerator{}, nil
+	case StrategySequential:
+		return NewSequentialGenerator(storage, key), nil
+	case StrategyHash:
+		return HashGenerator{}, nil
+	case StrategyWords:
+		return WordGenerator{}, nil
+	}
+	return nil, fmt.Errorf("unknown short code strategy %q", strategy)
+}
+
+// RandomGenerator выдает криптографически случайные коды в алфавите base62
+type RandomGenerator struct{}
+
+func (RandomGenerator) Generate(ctx context.Context, _ string, length, _ int) (string, error) {
+	return utils.GenerateRandomString(length), nil
+}
+
+// HashGenerator выдает детерминированный код на основе SHA-256 от URL.
+// Один и тот же URL всегда получает один и тот же код при первой попытке
+type HashGenerator struct{}
+
+func (HashGenerator) deterministic() {}
+
+func (HashGenerator) Generate(ctx context.Context, originalURL string, length, attempt int) (string, error) {
+	input := originalURL
+	if attempt > 0 {
+		input += "#" + strconv.Itoa(attempt)
+	}
+	sum := sha256.Sum256([]byte(input))
+
+	// 256 бит дают 43 символа base62, этого хватает для кода любой допустимой длины
+	encoded := encodeBase62(new(big.Int).SetBytes(sum[:]), 0)
+	return encoded[:length], nil
+}
+
+var base62 = big.NewInt(62)
+
+// encodeBase62 кодирует неотрицательное число в base62, дополняя слева нулями до width символов
+func encodeBase62(n *big.Int, width int) string {
+	var digits []byte
+	value := new(big.Int).Set(n)
+	mod := new(big.Int)
+	for value.Sign() > 0 {
+		value.DivMod(value, base62, mod)
+		digits = append(digits, utils.Base62Alphabet[mod.Int64()])
+	}
+	for len(digits) < width {
+		digits = append(digits, utils.Base62Alphabet[0])
+	}
+
+	for i, j := 0, len(digits)-1; i < j; i, j = i+1, j-1 {
+		digits[i], digits[j] = digits[j], digits[i]
+	}
+	return string(digits)
+}
+
+// decodeBase62 разбирает строку base62 в число
+func decodeBase62(code string) (*big.Int, error) {
+	n := new(big.Int)
+	for _, char := range code {
+		index := -1
+		for i := 0; i < len(utils.Base62Alphabet); i++ {
+			if rune(utils.Base62Alphabet[i]) == char {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("invalid base62 character %q", char)
+		}
+		n.Mul(n, base62)
+		n.Add(n, big.NewInt(int64(index)))
+	}
+	return n, nil
+}
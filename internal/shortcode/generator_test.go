@@ -0,0 +1,158 @@
+package shortcode
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/drerr0r/url-shortener/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGenerator(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+
+	for _, strategy := range []string{"", StrategyRandom, StrategySequential, StrategyHash, StrategyWords} {
+		generator, err := NewGenerator(strategy, mockStorage, 42)
+		assert.NoError(t, err, strategy)
+		assert.NotNil(t, generator, strategy)
+	}
+
+	_, err := NewGenerator("uuid", mockStorage, 42)
+	assert.Error(t, err)
+}
+
+func TestRandomGenerator(t *testing.T) {
+	code, err := RandomGenerator{}.Generate(context.Background(), "https://example.com", 8, 0)
+	assert.NoError(t, err)
+	assert.Len(t, code, 8)
+	assert.False(t, strings.ContainsAny(code, "-_"))
+}
+
+func TestHashGenerator(t *testing.T) {
+	generator := HashGenerator{}
+
+	first, err := generator.Generate(context.Background(), "https://example.com", 7, 0)
+	assert.NoError(t, err)
+	assert.Len(t, first, 7)
+	assert.True(t, utils.IsValidShortCode(first))
+
+	again, err := generator.Generate(context.Background(), "https://example.com", 7, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, first, again, "same URL should get the same code")
+
+	retry, err := generator.Generate(context.Background(), "https://example.com", 7, 1)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, retry, "retry after collision should produce another code")
+
+	other, err := generator.Generate(context.Background(), "https://example.org", 7, 0)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, other)
+}
+
+func TestSequentialGenerator(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	generator := NewSequentialGenerator(mockStorage, 42)
+
+	first, err := generator.Generate(context.Background(), "", 6, 0)
+	assert.NoError(t, err)
+	second, err := generator.Generate(context.Background(), "", 6, 0)
+	assert.NoError(t, err)
+
+	assert.Len(t, first, 6)
+	assert.Len(t, second, 6)
+	assert.NotEqual(t, first, second)
+	// Перестановка не должна давать соседние коды для соседних номеров
+	assert.NotEqual(t, first[:5], second[:5])
+
+	value, err := generator.Decode(first)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), value)
+
+	value, err = generator.Decode(second)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), value)
+}
+
+func TestSequentialGenerator_IsPermutation(t *testing.T) {
+	generator := NewSequentialGenerator(storage.NewMockStorage(), 7)
+
+	// Для длины 2 пространство - 3844 кода; каждый номер должен получить уникальный код
+	seen := make(map[string]bool)
+	for n := int64(0); n < 62*62; n++ {
+		code, err := generator.Encode(n, 2)
+		assert.NoError(t, err)
+		assert.False(t, seen[code], "duplicate code %s", code)
+		seen[code] = true
+
+		decoded, err := generator.Decode(code)
+		assert.NoError(t, err)
+		assert.Equal(t, n, decoded)
+	}
+}
+
+func TestSequentialGenerator_GrowsWhenKeyspaceIsExhausted(t *testing.T) {
+	generator := NewSequentialGenerator(storage.NewMockStorage(), 7)
+
+	code, err := generator.Encode(62*62*62*62, 4)
+	assert.NoError(t, err)
+	assert.Len(t, code, 5)
+}
+
+func TestWordGenerator(t *testing.T) {
+	generator := WordGenerator{}
+
+	code, err := generator.Generate(context.Background(), "https://example.com", 6, 0)
+	assert.NoError(t, err)
+	assert.Contains(t, code, "-")
+	assert.True(t, utils.IsValidShortCode(code), code)
+
+	retry, err := generator.Generate(context.Background(), "https://example.com", 6, 3)
+	assert.NoError(t, err)
+	assert.True(t, utils.IsValidShortCode(retry), retry)
+	assert.Contains(t, utils.Base62Alphabet[:10], retry[len(retry)-1:])
+
+	// Выросшая длина кода добирается цифрами, но не дальше MaxLength
+	for attempt := 0; attempt < 20; attempt++ {
+		code, err := generator.Generate(context.Background(), "https://example.com", MaxLength, attempt)
+		assert.NoError(t, err)
+		assert.Len(t, code, MaxLength)
+	}
+}
+
+func TestWordCapacity(t *testing.T) {
+	// Пары без суффикса плюс хотя бы одна цифра для самой длинной пары
+	assert.Greater(t, WordCapacity(), int64(len(adjectives)*len(nouns)*11))
+	assert.Greater(t, WordCapacity(), int64(1_000_000))
+}
+
+func TestWordLists_FitShortCodeLength(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, word := range append(append([]string(nil), adjectives...), nouns...) {
+		assert.False(t, seen[word], "duplicate word %q", word)
+		seen[word] = true
+	}
+
+	for _, adjective := range adjectives {
+		for _, noun := range nouns {
+			code := adjective + "-" + noun + "9"
+			assert.True(t, utils.IsValidShortCode(code), code)
+			assert.LessOrEqual(t, len(code), MaxLength, code)
+		}
+	}
+}
+
+func TestAllocator_WithHashGeneratorRetriesWithNextAttempt(t *testing.T) {
+	mockStorage := storage.NewMockStorage()
+	allocator := NewAllocator(mockStorage, Options{Length: 6, Generator: HashGenerator{}})
+
+	first := &models.URL{OriginalURL: "https://example.com"}
+	assert.NoError(t, allocator.Allocate(context.Background(), first))
+
+	// Второй вызов для того же URL сталкивается с первым кодом и берет следующий вариант хэша
+	second := &models.URL{OriginalURL: "https://example.com"}
+	assert.NoError(t, allocator.Allocate(context.Background(), second))
+	assert.NotEqual(t, first.ShortCode, second.ShortCode)
+}
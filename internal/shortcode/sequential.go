@@ -0,0 +1,105 @@
+// internal/shortcode/sequential.go
+
+package shortcode
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+// SequenceSource выдает монотонно растущие номера (последовательность в БД)
+type SequenceSource interface {
+	NextSequenceValue(ctx context.Context) (int64, error)
+}
+
+// SequentialGenerator кодирует номер из последовательности БД в base62.
+// Чтобы соседние ссылки не получали соседние коды, номер проходит через
+// обратимую аффинную перестановку n -> (n*a + b) mod 62^length
+type SequentialGenerator struct {
+	source SequenceSource
+	a, b   *big.Int
+}
+
+func (g *SequentialGenerator) deterministic() {}
+
+// NewSequentialGenerator создает генератор; параметры перестановки выводятся из key
+func NewSequentialGenerator(source SequenceSource, key uint64) *SequentialGenerator {
+	var seed [8]byte
+	binary.BigEndian.PutUint64(seed[:], key)
+	sum := sha256.Sum256(seed[:])
+
+	// Множитель должен быть взаимно прост с 62 = 2*31, иначе перестановка необратима
+	a := binary.BigEndian.Uint64(sum[:8]) | 1
+	if a%31 == 0 {
+		a += 2
+	}
+	b := binary.BigEndian.Uint64(sum[8:16])
+
+	return &SequentialGenerator{
+		source: source,
+		a:      new(big.Int).SetUint64(a),
+		b:      new(big.Int).SetUint64(b),
+	}
+}
+
+// Generate берет следующий номер последовательности. Если номер не помещается
+// в length символов, код удлиняется до минимально достаточной длины
+func (g *SequentialGenerator) Generate(ctx context.Context, _ string, length, _ int) (string, error) {
+	n, err := g.source.NextSequenceValue(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get next sequence value: %w", err)
+	}
+	return g.Encode(n, length)
+}
+
+// Encode переставляет номер n и кодирует его в base62 фиксированной длины
+func (g *SequentialGenerator) Encode(n int64, length int) (string, error) {
+	if n < 0 {
+		return "", fmt.Errorf("negative sequence value %d", n)
+	}
+
+	value := big.NewInt(n)
+	modulus := keyspace(length)
+	for value.Cmp(modulus) >= 0 {
+		if length >= MaxLength {
+			return "", fmt.Errorf("sequence value %d exceeds keyspace", n)
+		}
+		length++
+		modulus = keyspace(length)
+	}
+
+	permuted := new(big.Int).Mul(value, g.a)
+	permuted.Add(permuted, g.b)
+	permuted.Mod(permuted, modulus)
+	return encodeBase62(permuted, length), nil
+}
+
+// Decode восстанавливает номер последовательности по коду
+func (g *SequentialGenerator) Decode(code string) (int64, error) {
+	permuted, err := decodeBase62(code)
+	if err != nil {
+		return 0, err
+	}
+
+	modulus := keyspace(len(code))
+	inverse := new(big.Int).ModInverse(g.a, modulus)
+	if inverse == nil {
+		return 0, fmt.Errorf("permutation is not invertible for length %d", len(code))
+	}
+
+	value := new(big.Int).Sub(permuted, g.b)
+	value.Mul(value, inverse)
+	value.Mod(value, modulus)
+	if !value.IsInt64() {
+		return 0, fmt.Errorf("decoded value overflows int64")
+	}
+	return value.Int64(), nil
+}
+
+// keyspace возвращает количество кодов заданной длины: 62^length
+func keyspace(length int) *big.Int {
+	return new(big.Int).Exp(base62, big.NewInt(int64(length)), nil)
+}
@@ -0,0 +1,88 @@
+// internal/shortcode/words.go
+
+package shortcode
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"strconv"
+)
+
+// Слова не длиннее 5 букв: "adj-noun" с цифрой-суффиксом укладывается в MaxLength символов
+var (
+	adjectives = []string{
+		"agile", "amber", "azure", "bold", "brave", "brief", "brisk", "calm",
+		"civic", "clear", "cool", "cozy", "crisp", "daily", "eager", "early",
+		"fair", "fancy", "fast", "fresh", "giant", "glad", "gold", "grand",
+		"green", "handy", "happy", "jolly", "keen", "kind", "light", "loyal",
+		"lucky", "lunar", "magic", "merry", "mild", "misty", "noble", "polar",
+		"prime", "proud", "quick", "quiet", "rapid", "ready", "royal", "shiny",
+		"silly", "smart", "snowy", "solar", "sunny", "super", "sweet", "swift",
+		"tidy", "urban", "vivid", "warm", "wild", "wise", "witty", "young",
+	}
+	nouns = []string{
+		"apple", "bear", "bird", "bison", "camel", "cedar", "cloud", "comet",
+		"coral", "crane", "crow", "daisy", "deer", "dove", "eagle", "fern",
+		"finch", "fox", "frog", "gecko", "grape", "hawk", "heron", "koala",
+		"lark", "lemon", "lion", "llama", "lotus", "lynx", "maple", "mango",
+		"moon", "moose", "newt", "orca", "otter", "owl", "panda", "peach",
+		"pearl", "pine", "plum", "quail", "raven", "river", "robin", "rose",
+		"seal", "shark", "sky", "star", "stone", "storm", "sun", "swan",
+		"tiger", "tulip", "wave", "whale", "wolf", "wren", "yak", "zebra",
+	}
+)
+
+// WordGenerator выдает читаемые коды из пары слов, например "brave-otter".
+// Без суффикса пар 64 * 64 = 4096. После коллизии добавляются случайные цифры: по одной
+// за каждую попытку и сколько нужно до текущей длины кода, но так, чтобы код не превысил
+// MaxLength. Всего кодов около 1,7 млн (WordCapacity). Длинные пары занимают все MaxLength
+// символов, поэтому стратегия требует APP_SHORT_CODE_MAX_LENGTH = MaxLength
+type WordGenerator struct{}
+
+func (WordGenerator) Generate(ctx context.Context, _ string, length, attempt int) (string, error) {
+	adjective, err := pick(adjectives)
+	if err != nil {
+		return "", err
+	}
+	noun, err := pick(nouns)
+	if err != nil {
+		return "", err
+	}
+
+	code := adjective + "-" + noun
+	digits := min(max(attempt, length-len(code)), MaxLength-len(code))
+	for i := 0; i < digits; i++ {
+		digit, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code += strconv.FormatInt(digit.Int64(), 10)
+	}
+	return code, nil
+}
+
+// WordCapacity возвращает число разных кодов стратегии words: каждая пара слов
+// с суффиксом из 0..MaxLength-len(пары) цифр
+func WordCapacity() int64 {
+	var total int64
+	for _, adjective := range adjectives {
+		for _, noun := range nouns {
+			suffixes := int64(1)
+			for n := 0; n < MaxLength-len(adjective)-1-len(noun); n++ {
+				suffixes *= 10
+				total += suffixes
+			}
+			total++
+		}
+	}
+	return total
+}
+
+func pick(words []string) (string, error) {
+	index, err := rand.Int(rand.Reader, big.NewInt(int64(len(words))))
+	if err != nil {
+		return "", err
+	}
+	return words[index.Int64()], nil
+}
@@ -0,0 +1,56 @@
+// internal/storage/connect.go
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Connect открывает пул соединений с PostgreSQL и проверяет подключение. Каждое новое соединение
+// пробует строки подключения по порядку до первой удачной: так работают режимы sslmode prefer
+// и allow, которых нет в lib/pq
+func Connect(ctx context.Context, dsns ...string) (*sqlx.DB, error) {
+	if len(dsns) == 0 {
+		return nil, errors.New("no connection strings")
+	}
+	connectors := make([]driver.Connector, len(dsns))
+	for i, dsn := range dsns {
+		connector, err := pq.NewConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		connectors[i] = connector
+	}
+
+	db := sqlx.NewDb(sql.OpenDB(fallbackConnector(connectors)), "postgres")
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// fallbackConnector открывает соединение первым сработавшим из connectors
+type fallbackConnector []driver.Connector
+
+func (f fallbackConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	var errs []error
+	for _, connector := range f {
+		conn, err := connector.Connect(ctx)
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (f fallbackConnector) Driver() driver.Driver {
+	return f[0].Driver()
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubConnector возвращает заданную ошибку или соединение и считает попытки
+type stubConnector struct {
+	err   error
+	calls int
+}
+
+func (s *stubConnector) Connect(context.Context) (driver.Conn, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return stubConn{}, nil
+}
+
+func (s *stubConnector) Driver() driver.Driver { return nil }
+
+type stubConn struct{ driver.Conn }
+
+func TestFallbackConnector(t *testing.T) {
+	tls := &stubConnector{err: errors.New("pq: SSL is not enabled on the server")}
+	plain := &stubConnector{}
+
+	conn, err := fallbackConnector{tls, plain}.Connect(context.Background())
+	assert.NoError(t, err)
+	assert.NotNil(t, conn)
+	assert.Equal(t, 1, tls.calls)
+	assert.Equal(t, 1, plain.calls)
+
+	// Первая удачная попытка: остальные не пробуются
+	_, err = fallbackConnector{plain, tls}.Connect(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, tls.calls)
+
+	refused := &stubConnector{err: errors.New("connection refused")}
+	_, err = fallbackConnector{tls, refused}.Connect(context.Background())
+	assert.ErrorContains(t, err, "SSL is not enabled")
+	assert.ErrorContains(t, err, "connection refused")
+}
+
+func TestConnectWithoutDSN(t *testing.T) {
+	_, err := Connect(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"golang.org/x/sync/singleflight"
+)
+
+// MemoryCacheOptions задает параметры кэша ссылок в памяти процесса
+type MemoryCacheOptions struct {
+	Size        int           // Максимальное количество ссылок в кэше
+	TTL         time.Duration // Время жизни найденной ссылки
+	NegativeTTL time.Duration // Время жизни отметки об отсутствии ссылки
+}
+
+// MemoryCacheStats содержит метрики кэша ссылок в памяти
+type MemoryCacheStats struct {
+	Size      int   `json:"size"`      // Текущее количество записей
+	Hits      int64 `json:"hits"`      // Запросов, обслуженных из кэша
+	Misses    int64 `json:"misses"`    // Запросов, ушедших в хранилище
+	Collapsed int64 `json:"collapsed"` // Промахов, присоединившихся к уже идущему запросу
+	Evictions int64 `json:"evictions"` // Записей, вытесненных по размеру
+}
+
+// MemoryCache декоратор Storage с ограниченным LRU кэшем GetURL в памяти процесса.
+// Параллельные промахи по одному коду схлопываются в один запрос к хранилищу,
+// поэтому вирусная ссылка не порождает лавину одинаковых запросов.
+// Как и RedisCache, сбрасывает запись при изменении ссылки, но не при обычных кликах.
+type MemoryCache struct {
+	Storage
+	opts MemoryCacheOptions
+	// TTL меняются на лету через SetTTL, поэтому хранятся отдельно от opts
+	ttl         atomic.Int64
+	negativeTTL atomic.Int64
+
+	mu    sync.Mutex
+	order *list.List // Передний элемент - последний использованный
+	items map[string]*list.Element
+	// generation увеличивается при каждой инвалидации, чтобы загрузка,
+	// начатая до изменения ссылки, не положила в кэш устаревшее значение
+	generation uint64
+
+	group singleflight.Group
+	now   func() time.Time
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	collapsed atomic.Int64
+	evictions atomic.Int64
+}
+
+type memoryEntry struct {
+	shortCode string
+	url       *models.URL // nil - ссылка не найдена
+	expiresAt time.Time
+}
+
+// NewMemoryCache оборачивает хранилище кэшем в памяти
+func NewMemoryCache(storage Storage, opts MemoryCacheOptions) *MemoryCache {
+	if opts.Size <= 0 {
+		opts.Size = 10000
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 5 * time.Second
+	}
+	if opts.NegativeTTL <= 0 {
+		opts.NegativeTTL = 5 * time.Second
+	}
+	c := &MemoryCache{
+		Storage: storage,
+		opts:    opts,
+		order:   list.New(),
+		items:   make(map[string]*list.Element),
+		now:     time.Now,
+	}
+	c.SetTTL(opts.TTL, opts.NegativeTTL)
+	return c
+}
+
+// SetTTL меняет время жизни новых записей; уже закэшированные записи истекают по старому TTL.
+// Неположительные значения игнорируются
+func (c *MemoryCache) SetTTL(ttl, negativeTTL time.Duration) {
+	if ttl > 0 {
+		c.ttl.Store(int64(ttl))
+	}
+	if negativeTTL > 0 {
+		c.negativeTTL.Store(int64(negativeTTL))
+	}
+}
+
+// GetURL возвращает ссылку из кэша или загружает ее из хранилища
+func (c *MemoryCache) GetURL(ctx context.Context, shortCode string) (*models.URL, error) {
+	if entry, ok := c.lookup(shortCode); ok {
+		c.hits.Add(1)
+		return entryResult(entry)
+	}
+	c.misses.Add(1)
+
+	value, err, shared := c.group.Do(shortCode, func() (any, error) {
+		// Запись могла появиться, пока запрос ждал завершения предыдущей загрузки
+		if entry, ok := c.lookup(shortCode); ok {
+			return entry, nil
+		}
+
+		c.mu.Lock()
+		generation := c.generation
+		c.mu.Unlock()
+
+		// Загрузку разделяют все ожидающие запросы, поэтому отмена первого из них не должна ее прерывать
+		url, err := c.Storage.GetURL(context.WithoutCancel(ctx), shortCode)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+
+		ttl := time.Duration(c.ttl.Load())
+		if url == nil {
+			ttl = time.Duration(c.negativeTTL.Load())
+		} else if url.ExpiresAt != nil {
+			// Временную ссылку не держим в кэше дольше срока ее действия
+			if untilExpiry := url.ExpiresAt.Sub(c.now()); untilExpiry < ttl {
+				ttl = untilExpiry
+			}
+		}
+
+		entry := &memoryEntry{shortCode: shortCode, url: url, expiresAt: c.now().Add(ttl)}
+		if ttl > 0 {
+			c.store(entry, generation)
+		}
+		return entry, nil
+	})
+	if shared {
+		c.collapsed.Add(1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return entryResult(value.(*memoryEntry))
+}
+
+// SaveURL сохраняет ссылку и снимает отметку об отсутствии кода
+func (c *MemoryCache) SaveURL(ctx context.Context, url *models.URL) error {
+	if err := c.Storage.SaveURL(ctx, url); err != nil {
+		return err
+	}
+	c.invalidate(url.ShortCode)
+	return nil
+}
+
+// DeleteURL удаляет ссылку из хранилища и из кэша
+func (c *MemoryCache) DeleteURL(ctx context.Context, shortCode string) error {
+	if err := c.Storage.DeleteURL(ctx, shortCode); err != nil {
+		return err
+	}
+	c.invalidate(shortCode)
+	return nil
+}
+
+// SetURLDisabled меняет состояние ссылки и сбрасывает ее запись в кэше
+func (c *MemoryCache) SetURLDisabled(ctx context.Context, shortCode string, disabled bool) error {
+	if err := c.Storage.SetURLDisabled(ctx, shortCode, disabled); err != nil {
+		return err
+	}
+	c.invalidate(shortCode)
+	return nil
+}
+
+// ConsumeClick списывает переход и сбрасывает запись, чтобы исчерпанная ссылка
+// сразу отдавала 410
+func (c *MemoryCache) ConsumeClick(ctx context.Context, shortCode string) error {
+	err := c.Storage.ConsumeClick(ctx, shortCode)
+	if err == nil || errors.Is(err, ErrClickLimitReached) {
+		c.invalidate(shortCode)
+	}
+	return err
+}
+
+// Stats возвращает текущие метрики кэша
+func (c *MemoryCache) Stats() MemoryCacheStats {
+	c.mu.Lock()
+	size := len(c.items)
+	c.mu.Unlock()
+
+	return MemoryCacheStats{
+		Size:      size,
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Collapsed: c.collapsed.Load(),
+		Evictions: c.evictions.Load(),
+	}
+}
+
+func (c *MemoryCache) lookup(shortCode string) (*memoryEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[shortCode]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*memoryEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.items, shortCode)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry, true
+}
+
+func (c *MemoryCache) store(entry *memoryEntry, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	if elem, ok := c.items[entry.shortCode]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[entry.shortCode] = c.order.PushFront(entry)
+	for len(c.items) > c.opts.Size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*memoryEntry).shortCode)
+		c.evictions.Add(1)
+	}
+}
+
+func (c *MemoryCache) invalidate(shortCode string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if elem, ok := c.items[shortCode]; ok {
+		c.order.Remove(elem)
+		delete(c.items, shortCode)
+	}
+	// Следующий промах не должен присоединиться к загрузке, начатой до изменения
+	c.group.Forget(shortCode)
+}
+
+// entryResult возвращает копию ссылки, чтобы вызывающий код не менял закэшированное значение
+func entryResult(entry *memoryEntry) (*models.URL, error) {
+	if entry.url == nil {
+		return nil, ErrNotFound
+	}
+	copied := *entry.url
+	return &copied, nil
+}
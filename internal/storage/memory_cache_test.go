@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// slowStorage задерживает GetURL, чтобы параллельные промахи успели встретиться
+type slowStorage struct {
+	*MockStorage
+	gets    atomic.Int64
+	release chan struct{}
+}
+
+func (s *slowStorage) GetURL(ctx context.Context, shortCode string) (*models.URL, error) {
+	s.gets.Add(1)
+	<-s.release
+	return s.MockStorage.GetURL(context.Background(), shortCode)
+}
+
+func TestMemoryCache_HitsAndMisses(t *testing.T) {
+	backend := &countingStorage{MockStorage: NewMockStorage()}
+	cache := NewMemoryCache(backend, MemoryCacheOptions{Size: 10, TTL: time.Minute})
+
+	err := cache.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "abc123"})
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		url, err := cache.GetURL(context.Background(), "abc123")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com", url.OriginalURL)
+	}
+	_, err = cache.GetURL(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = cache.GetURL(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.Equal(t, int64(2), backend.gets.Load())
+	stats := cache.Stats()
+	assert.Equal(t, int64(3), stats.Hits)
+	assert.Equal(t, int64(2), stats.Misses)
+	assert.Equal(t, 2, stats.Size)
+}
+
+func TestMemoryCache_ReturnsCopies(t *testing.T) {
+	cache := NewMemoryCache(NewMockStorage(), MemoryCacheOptions{})
+
+	err := cache.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "abc123"})
+	assert.NoError(t, err)
+
+	url, _ := cache.GetURL(context.Background(), "abc123")
+	url.OriginalURL = ""
+	url, _ = cache.GetURL(context.Background(), "abc123")
+	assert.Equal(t, "https://example.com", url.OriginalURL)
+}
+
+func TestMemoryCache_SetTTL(t *testing.T) {
+	backend := &countingStorage{MockStorage: NewMockStorage()}
+	cache := NewMemoryCache(backend, MemoryCacheOptions{TTL: time.Minute})
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	err := cache.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "abc123"})
+	assert.NoError(t, err)
+
+	// Новый TTL применяется к записям, загруженным после изменения
+	cache.SetTTL(10*time.Second, 0)
+	_, _ = cache.GetURL(context.Background(), "abc123")
+	now = now.Add(15 * time.Second)
+	_, _ = cache.GetURL(context.Background(), "abc123")
+	assert.Equal(t, int64(2), backend.gets.Load())
+}
+
+func TestMemoryCache_TTL(t *testing.T) {
+	backend := &countingStorage{MockStorage: NewMockStorage()}
+	cache := NewMemoryCache(backend, MemoryCacheOptions{TTL: time.Minute})
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	err := cache.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "abc123"})
+	assert.NoError(t, err)
+
+	_, _ = cache.GetURL(context.Background(), "abc123")
+	now = now.Add(30 * time.Second)
+	_, _ = cache.GetURL(context.Background(), "abc123")
+	assert.Equal(t, int64(1), backend.gets.Load())
+
+	now = now.Add(30 * time.Second)
+	_, _ = cache.GetURL(context.Background(), "abc123")
+	assert.Equal(t, int64(2), backend.gets.Load())
+}
+
+func TestMemoryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	backend := &countingStorage{MockStorage: NewMockStorage()}
+	cache := NewMemoryCache(backend, MemoryCacheOptions{Size: 2, TTL: time.Minute})
+
+	for _, code := range []string{"code1", "code2", "code3"} {
+		err := cache.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com/" + code, ShortCode: code})
+		assert.NoError(t, err)
+	}
+
+	_, _ = cache.GetURL(context.Background(), "code1")
+	_, _ = cache.GetURL(context.Background(), "code2")
+	_, _ = cache.GetURL(context.Background(), "code1") // code2 становится самым старым
+	_, _ = cache.GetURL(context.Background(), "code3") // вытесняет code2
+	assert.Equal(t, int64(3), backend.gets.Load())
+
+	_, _ = cache.GetURL(context.Background(), "code1")
+	assert.Equal(t, int64(3), backend.gets.Load())
+	_, _ = cache.GetURL(context.Background(), "code2")
+	assert.Equal(t, int64(4), backend.gets.Load())
+	assert.Equal(t, int64(2), cache.Stats().Evictions)
+}
+
+func TestMemoryCache_CollapsesConcurrentMisses(t *testing.T) {
+	backend := &slowStorage{MockStorage: NewMockStorage(), release: make(chan struct{})}
+	cache := NewMemoryCache(backend, MemoryCacheOptions{TTL: time.Minute})
+
+	err := cache.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "viral1"})
+	assert.NoError(t, err)
+
+	const requests = 50
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			url, err := cache.GetURL(context.Background(), "viral1")
+			assert.NoError(t, err)
+			assert.Equal(t, "https://example.com", url.OriginalURL)
+		}()
+	}
+
+	// Ждем, пока все запросы станут промахами, и только затем отпускаем хранилище
+	assert.Eventually(t, func() bool {
+		return cache.Stats().Misses == requests
+	}, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(backend.release)
+	wg.Wait()
+
+	assert.Equal(t, int64(1), backend.gets.Load())
+	assert.Greater(t, cache.Stats().Collapsed, int64(0))
+}
+
+func TestMemoryCache_Invalidation(t *testing.T) {
+	cache := NewMemoryCache(NewMockStorage(), MemoryCacheOptions{TTL: time.Minute})
+
+	_, err := cache.GetURL(context.Background(), "abc123")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	err = cache.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "abc123"})
+	assert.NoError(t, err)
+	_, err = cache.GetURL(context.Background(), "abc123")
+	assert.NoError(t, err)
+
+	assert.NoError(t, cache.DeleteURL(context.Background(), "abc123"))
+	_, err = cache.GetURL(context.Background(), "abc123")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	maxClicks := int64(1)
+	err = cache.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "once123", MaxClicks: &maxClicks})
+	assert.NoError(t, err)
+	_, _ = cache.GetURL(context.Background(), "once123")
+	assert.NoError(t, cache.ConsumeClick(context.Background(), "once123"))
+	url, err := cache.GetURL(context.Background(), "once123")
+	assert.NoError(t, err)
+	assert.True(t, url.IsExhausted())
+
+	// Отключенная администратором ссылка не должна открываться из кэша
+	assert.NoError(t, cache.SetURLDisabled(context.Background(), "once123", true))
+	url, err = cache.GetURL(context.Background(), "once123")
+	assert.NoError(t, err)
+	assert.True(t, url.IsDisabled())
+}
@@ -1,22 +1,45 @@
 package storage
 
 import (
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/audit"
 	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/utils"
 )
 
 // MockStorage реализация Storage для тестов
 type MockStorage struct {
-	urls map[string]*models.URL
+	mu       sync.RWMutex
+	urls     map[string]*models.URL
+	clicks   []*models.Click
+	sequence int64
+	archived []*models.URL
+	// Переходы ссылок из archived
+	archivedClicks []*models.Click
+	apiKeys        []*models.APIKey
+	users          []*models.User
+	sessions       map[string]*models.Session
+	audit          []*models.AuditEvent
 }
 
 func NewMockStorage() *MockStorage {
 	return &MockStorage{
-		urls: make(map[string]*models.URL),
+		urls:     make(map[string]*models.URL),
+		sessions: make(map[string]*models.Session),
 	}
 }
 
 // GetURLCount возвращает количество URL (для тестов)
 func (m *MockStorage) GetURLCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return len(m.urls)
 }
 
@@ -25,44 +48,90 @@ func (m *MockStorage) GetAllURLs() map[string]*models.URL {
 	return m.urls
 }
 
-func (m *MockStorage) SaveURL(url *models.URL) error {
+// GetArchivedURLs возвращает ссылки, перенесенные в архив (для тестов)
+func (m *MockStorage) GetArchivedURLs() []*models.URL {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]*models.URL(nil), m.archived...)
+}
+
+// GetArchivedClicks возвращает переходы, перенесенные в архив вместе со ссылками (для тестов)
+func (m *MockStorage) GetArchivedClicks() []*models.Click {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]*models.Click(nil), m.archivedClicks...)
+}
+
+func (m *MockStorage) SaveURL(ctx context.Context, url *models.URL) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Как и уникальный индекс в PostgreSQL, не даем перезаписать существующий код
+	if _, exists := m.urls[url.ShortCode]; exists {
+		return ErrAlreadyExists
+	}
 	m.urls[url.ShortCode] = url
+	m.recordAudit(ctx, models.AuditLinkCreate, linkChange{after: url})
 	return nil
 }
 
-func (m *MockStorage) GetURL(shortCode string) (*models.URL, error) {
+func (m *MockStorage) GetURL(ctx context.Context, shortCode string) (*models.URL, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	url, exists := m.urls[shortCode]
 	if !exists {
 		return nil, ErrNotFound
 	}
-	return url, nil
+	// Возвращаем копию, как и PostgresStorage, чтобы вызывающий код не гонялся с изменениями счетчиков
+	copied := *url
+	return &copied, nil
 }
 
-func (m *MockStorage) GetURLByOriginal(originalURL string) (*models.URL, error) {
+func (m *MockStorage) GetURLByOriginal(ctx context.Context, originalURL string, owner models.Owner) (*models.URL, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	// Как и PostgresStorage, предпочитаем бессрочную ссылку
+	var found *models.URL
 	for _, url := range m.urls {
-		if url.OriginalURL == originalURL {
-			return url, nil
+		if url.OriginalURL != originalURL || !url.Owner().Equal(owner) {
+			continue
 		}
+		if found == nil || (!isUnrestricted(found) && isUnrestricted(url)) {
+			found = url
+		}
+	}
+	if found == nil {
+		return nil, nil
 	}
-	return nil, nil
+	copied := *found
+	return &copied, nil
 }
 
-func (m *MockStorage) URLExists(shortCode string) (bool, error) {
+func (m *MockStorage) URLExists(ctx context.Context, shortCode string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	_, exists := m.urls[shortCode]
 	return exists, nil
 }
 
-func (m *MockStorage) DeleteURL(shortCode string) error {
-	delete(m.urls, shortCode)
+func (m *MockStorage) DeleteURL(ctx context.Context, shortCode string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if url, exists := m.urls[shortCode]; exists {
+		delete(m.urls, shortCode)
+		m.recordAudit(ctx, models.AuditLinkDelete, linkChange{before: url})
+	}
 	return nil
 }
 
-func (m *MockStorage) GetURLs(limit, offset int) ([]*models.URL, error) {
+func (m *MockStorage) GetURLs(ctx context.Context, limit, offset int) ([]*models.URL, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	var result []*models.URL
 	count := 0
 	for _, url := range m.urls {
 		if count >= offset {
-			result = append(result, url)
+			copied := *url
+			result = append(result, &copied)
 		}
 		count++
 		if len(result) >= limit {
@@ -72,6 +141,522 @@ func (m *MockStorage) GetURLs(limit, offset int) ([]*models.URL, error) {
 	return result, nil
 }
 
-func (m *MockStorage) GetURLsCount() (int, error) {
+func (m *MockStorage) GetURLsCount(ctx context.Context) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return len(m.urls), nil
 }
+
+// GetURLsByOwner возвращает ссылки владельца, новые первыми, как и PostgresStorage
+func (m *MockStorage) GetURLsByOwner(ctx context.Context, owner models.Owner, limit, offset int) ([]*models.URL, error) {
+	return m.page(func(url *models.URL) bool { return url.IsOwnedBy(owner) }, limit, offset), nil
+}
+
+// page отбирает ссылки по условию и возвращает страницу, новые первыми
+func (m *MockStorage) page(match func(*models.URL) bool, limit, offset int) []*models.URL {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var matched []*models.URL
+	for _, url := range m.urls {
+		if match(url) {
+			copied := *url
+			matched = append(matched, &copied)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].ShortCode > matched[j].ShortCode
+		}
+		return matched[i].CreatedAt.After(matched[j].CreatedAt)
+	})
+
+	result := []*models.URL{}
+	for i := offset; i < len(matched) && len(result) < limit; i++ {
+		result = append(result, matched[i])
+	}
+	return result
+}
+
+// SearchURLs ищет подстроку в коротком коде или исходном URL без учета регистра
+func (m *MockStorage) SearchURLs(ctx context.Context, query string, limit, offset int) ([]*models.URL, error) {
+	return m.page(searchMatcher(query), limit, offset), nil
+}
+
+func (m *MockStorage) SearchURLsCount(ctx context.Context, query string) (int, error) {
+	return len(m.page(searchMatcher(query), math.MaxInt, 0)), nil
+}
+
+func searchMatcher(query string) func(*models.URL) bool {
+	query = strings.ToLower(query)
+	return func(url *models.URL) bool {
+		return strings.Contains(strings.ToLower(url.ShortCode), query) ||
+			strings.Contains(strings.ToLower(url.OriginalURL), query)
+	}
+}
+
+func (m *MockStorage) SetURLDisabled(ctx context.Context, shortCode string, disabled bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	url, exists := m.urls[shortCode]
+	if !exists {
+		return ErrNotFound
+	}
+	before := *url
+	action := models.AuditLinkEnable
+	switch {
+	case !disabled:
+		url.DisabledAt = nil
+	case url.DisabledAt == nil:
+		now := time.Now()
+		url.DisabledAt = &now
+	}
+	if disabled {
+		action = models.AuditLinkDisable
+	}
+	m.recordAudit(ctx, action, linkChange{before: &before, after: url})
+	return nil
+}
+
+func (m *MockStorage) GetURLsCountByOwner(ctx context.Context, owner models.Owner) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	count := 0
+	for _, url := range m.urls {
+		if url.IsOwnedBy(owner) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MockStorage) IncrementClickCount(ctx context.Context, shortCode string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	url, exists := m.urls[shortCode]
+	if !exists {
+		return ErrNotFound
+	}
+	url.ClickCount++
+	return nil
+}
+
+// ConsumeClick засчитывает переход под мьютексом, повторяя семантику условного UPDATE в PostgresStorage
+func (m *MockStorage) ConsumeClick(ctx context.Context, shortCode string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	url, exists := m.urls[shortCode]
+	if !exists {
+		return ErrNotFound
+	}
+	if url.IsExhausted() {
+		return ErrClickLimitReached
+	}
+	url.ClickCount++
+	return nil
+}
+
+func (m *MockStorage) SaveClickBatch(ctx context.Context, counts map[string]int64, clicks []*models.Click) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for shortCode, delta := range counts {
+		if url, exists := m.urls[shortCode]; exists {
+			url.ClickCount += delta
+		}
+	}
+	m.saveClicks(clicks)
+	return nil
+}
+
+func (m *MockStorage) SaveClicks(ctx context.Context, clicks []*models.Click) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saveClicks(clicks)
+	return nil
+}
+
+// saveClicks добавляет события в журнал. Вызывается под m.mu
+func (m *MockStorage) saveClicks(clicks []*models.Click) {
+	for _, click := range clicks {
+		// Как и в PostgresStorage, события для несуществующих ссылок пропускаются
+		if _, exists := m.urls[click.ShortCode]; !exists {
+			continue
+		}
+		copied := *click
+		copied.ID = int64(len(m.clicks) + 1)
+		m.clicks = append(m.clicks, &copied)
+	}
+}
+
+func (m *MockStorage) GetClicks(ctx context.Context, shortCode string, limit, offset int) ([]*models.Click, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var matched []*models.Click
+	for _, click := range m.clicks {
+		if click.ShortCode == shortCode {
+			copied := *click
+			matched = append(matched, &copied)
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].ClickedAt.Equal(matched[j].ClickedAt) {
+			return matched[i].ID > matched[j].ID
+		}
+		return matched[i].ClickedAt.After(matched[j].ClickedAt)
+	})
+
+	result := []*models.Click{}
+	for i := offset; i < len(matched) && len(result) < limit; i++ {
+		result = append(result, matched[i])
+	}
+	return result, nil
+}
+
+func (m *MockStorage) GetClicksCount(ctx context.Context, shortCode string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	count := 0
+	for _, click := range m.clicks {
+		if click.ShortCode == shortCode {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MockStorage) GetClickTimeSeries(ctx context.Context, shortCode string, from, to time.Time, interval string, loc *time.Location) ([]*models.TimeBucket, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	counts := make(map[int64]*models.TimeBucket)
+	for _, click := range m.clicks {
+		if click.ShortCode != shortCode || click.ClickedAt.Before(from) || !click.ClickedAt.Before(to) {
+			continue
+		}
+		start := utils.TruncateTime(click.ClickedAt.In(loc), interval)
+		bucket, exists := counts[start.Unix()]
+		if !exists {
+			bucket = &models.TimeBucket{Start: start}
+			counts[start.Unix()] = bucket
+		}
+		bucket.Count++
+	}
+
+	result := make([]*models.TimeBucket, 0, len(counts))
+	for _, bucket := range counts {
+		result = append(result, bucket)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Start.Before(result[j].Start)
+	})
+	return result, nil
+}
+
+func (m *MockStorage) NextSequenceValue(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sequence++
+	return m.sequence, nil
+}
+
+func (m *MockStorage) PurgeExpiredURLs(ctx context.Context, before time.Time, limit int, archive bool) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var purged int64
+	for shortCode, url := range m.urls {
+		if purged >= int64(limit) {
+			break
+		}
+		if url.ExpiresAt == nil || url.ExpiresAt.After(before) {
+			continue
+		}
+		action := models.AuditLinkPurge
+		if archive {
+			m.archived = append(m.archived, url)
+			action = models.AuditLinkArchive
+		}
+		// Как ON DELETE CASCADE в PostgreSQL: переходы удаляются вместе со ссылкой,
+		// в режиме архива переносятся
+		kept := m.clicks[:0]
+		for _, click := range m.clicks {
+			switch {
+			case click.ShortCode != shortCode:
+				kept = append(kept, click)
+			case archive:
+				m.archivedClicks = append(m.archivedClicks, click)
+			}
+		}
+		m.clicks = kept
+		delete(m.urls, shortCode)
+		m.recordAudit(ctx, action, linkChange{before: url})
+		purged++
+	}
+	return purged, nil
+}
+
+// recordAudit добавляет события в журнал аудита так же, как PostgresStorage. Вызывается под m.mu
+func (m *MockStorage) recordAudit(ctx context.Context, action string, changes ...linkChange) {
+	origin := audit.OriginFromContext(ctx)
+	for _, change := range changes {
+		event := &models.AuditEvent{
+			ID:        int64(len(m.audit) + 1),
+			CreatedAt: time.Now(),
+			Actor:     origin.Actor,
+			Action:    action,
+			ClientIP:  origin.ClientIP,
+			RequestID: origin.RequestID,
+		}
+		if change.before != nil {
+			event.ShortCode = change.before.ShortCode
+			event.Before = rawLink(change.before)
+		}
+		if change.after != nil {
+			event.ShortCode = change.after.ShortCode
+			event.After = rawLink(change.after)
+		}
+		m.audit = append(m.audit, event)
+	}
+}
+
+func rawLink(url *models.URL) *json.RawMessage {
+	data, _ := linkJSON(url)
+	raw := json.RawMessage(data.String)
+	return &raw
+}
+
+// auditMatches возвращает события журнала по фильтру, новые первыми. Вызывается под m.mu
+func (m *MockStorage) auditMatches(filter models.AuditFilter) []*models.AuditEvent {
+	var result []*models.AuditEvent
+	for i := len(m.audit) - 1; i >= 0; i-- {
+		event := m.audit[i]
+		if (filter.ShortCode == "" || event.ShortCode == filter.ShortCode) && (filter.Actor == "" || event.Actor == filter.Actor) {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+func (m *MockStorage) GetAuditEvents(ctx context.Context, filter models.AuditFilter, limit, offset int) ([]*models.AuditEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	matches := m.auditMatches(filter)
+	if offset >= len(matches) {
+		return nil, nil
+	}
+	matches = matches[offset:]
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	result := make([]*models.AuditEvent, len(matches))
+	for i, event := range matches {
+		copied := *event
+		result[i] = &copied
+	}
+	return result, nil
+}
+
+func (m *MockStorage) GetAuditEventsCount(ctx context.Context, filter models.AuditFilter) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.auditMatches(filter)), nil
+}
+
+func (m *MockStorage) PurgeAuditEvents(ctx context.Context, before time.Time, limit int) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var purged int64
+	kept := m.audit[:0]
+	for _, event := range m.audit {
+		if purged < int64(limit) && event.CreatedAt.Before(before) {
+			purged++
+			continue
+		}
+		kept = append(kept, event)
+	}
+	m.audit = kept
+	return purged, nil
+}
+
+// isUnrestricted проверяет, что у ссылки нет срока действия, лимита переходов и пароля
+func isUnrestricted(url *models.URL) bool {
+	return url.ExpiresAt == nil && url.MaxClicks == nil && url.PasswordHash == ""
+}
+
+func (m *MockStorage) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.apiKeys {
+		if existing.KeyHash == key.KeyHash {
+			return ErrAlreadyExists
+		}
+	}
+	if key.Role == "" {
+		key.Role = models.RoleEditor
+	}
+	key.ID = int64(len(m.apiKeys) + 1)
+	key.CreatedAt = time.Now()
+	copied := *key
+	m.apiKeys = append(m.apiKeys, &copied)
+	return nil
+}
+
+func (m *MockStorage) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, key := range m.apiKeys {
+		if key.KeyHash == keyHash {
+			copied := *key
+			return &copied, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockStorage) ListAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := make([]*models.APIKey, 0, len(m.apiKeys))
+	for _, key := range m.apiKeys {
+		copied := *key
+		result = append(result, &copied)
+	}
+	return result, nil
+}
+
+func (m *MockStorage) RevokeAPIKey(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range m.apiKeys {
+		if key.ID == id {
+			if key.RevokedAt == nil {
+				now := time.Now()
+				key.RevokedAt = &now
+			}
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *MockStorage) TouchAPIKey(ctx context.Context, id int64, usedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range m.apiKeys {
+		if key.ID == id {
+			key.LastUsedAt = &usedAt
+		}
+	}
+	return nil
+}
+
+func (m *MockStorage) CreateUser(ctx context.Context, user *models.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.userConflicts(user) {
+		return ErrAlreadyExists
+	}
+	if user.Role == "" {
+		user.Role = models.RoleEditor
+	}
+	user.ID = int64(len(m.users) + 1)
+	user.CreatedAt = time.Now()
+	copied := *user
+	m.users = append(m.users, &copied)
+	return nil
+}
+
+func (m *MockStorage) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, user := range m.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockStorage) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, user := range m.users {
+		if user.ID == id {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockStorage) GetUserByOIDCSubject(ctx context.Context, issuer, subject string) (*models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, user := range m.users {
+		if user.OIDCIssuer != nil && user.OIDCSubject != nil && *user.OIDCIssuer == issuer && *user.OIDCSubject == subject {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockStorage) UpdateUser(ctx context.Context, user *models.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.userConflicts(user) {
+		return ErrAlreadyExists
+	}
+	for i, existing := range m.users {
+		if existing.ID == user.ID {
+			copied := *user
+			m.users[i] = &copied
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// userConflicts повторяет уникальные индексы users: email и пара issuer + subject
+func (m *MockStorage) userConflicts(user *models.User) bool {
+	for _, existing := range m.users {
+		if existing.ID == user.ID {
+			continue
+		}
+		if existing.Email == user.Email {
+			return true
+		}
+		if user.OIDCSubject != nil && existing.OIDCSubject != nil &&
+			*existing.OIDCIssuer == *user.OIDCIssuer && *existing.OIDCSubject == *user.OIDCSubject {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MockStorage) CreateSession(ctx context.Context, session *models.Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session.CreatedAt = time.Now()
+	copied := *session
+	m.sessions[session.ID] = &copied
+	return nil
+}
+
+func (m *MockStorage) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	session, exists := m.sessions[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (m *MockStorage) DeleteSession(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
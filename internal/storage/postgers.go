@@ -1,13 +1,22 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
 
+	"github.com/drerr0r/url-shortener/internal/audit"
 	"github.com/drerr0r/url-shortener/internal/models"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+// urlColumns список колонок urls, читаемых в models.URL
+const urlColumns = `id, original_url, short_code, created_at, click_count, expires_at, max_clicks, password_hash, owner_id, user_id, disabled_at`
+
 type PostgresStorage struct {
 	db *sqlx.DB
 }
@@ -16,18 +25,44 @@ func NewPostgresStorage(db *sqlx.DB) *PostgresStorage {
 	return &PostgresStorage{db: db}
 }
 
-// SaveURL сохраняет URL в базу данных
-func (s *PostgresStorage) SaveURL(url *models.URL) error {
-	query := `INSERT INTO urls (original_url, short_code) VALUES ($1, $2)`
-	_, err := s.db.Exec(query, url.OriginalURL, url.ShortCode)
-	return err
+// SaveURL сохраняет URL в базу данных и записывает создание в журнал аудита
+func (s *PostgresStorage) SaveURL(ctx context.Context, url *models.URL) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO urls (original_url, short_code, expires_at, max_clicks, password_hash, owner_id, user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING ` + urlColumns
+	var created models.URL
+	err = tx.GetContext(ctx, &created, query, url.OriginalURL, url.ShortCode, url.ExpiresAt, url.MaxClicks, url.PasswordHash, url.OwnerID, url.UserID)
+	if isUniqueViolation(err) {
+		return ErrAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+	if err := recordAudit(ctx, tx, models.AuditLinkCreate, linkChange{after: &created}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// uniqueViolation код ошибки PostgreSQL при нарушении уникального индекса
+const uniqueViolation = "23505"
+
+// isUniqueViolation проверяет, вызвана ли ошибка нарушением уникального индекса
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
 }
 
 // GetURL возвращает URL по короткому коду
-func (s *PostgresStorage) GetURL(shortCode string) (*models.URL, error) {
-	query := `SELECT id, original_url, short_code, created_at FROM urls WHERE short_code = $1`
+func (s *PostgresStorage) GetURL(ctx context.Context, shortCode string) (*models.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE short_code = $1`
 	var url models.URL
-	err := s.db.Get(&url, query, shortCode)
+	err := s.db.GetContext(ctx, &url, query, shortCode)
 	if err == sql.ErrNoRows {
 		return nil, ErrNotFound
 	}
@@ -35,11 +70,14 @@ func (s *PostgresStorage) GetURL(shortCode string) (*models.URL, error) {
 }
 
 // 🟡 ДОБАВЛЕНО: Реализация отсутствующего метода
-// GetURLByOriginal возвращает URL по оригинальному URL
-func (s *PostgresStorage) GetURLByOriginal(originalURL string) (*models.URL, error) {
-	query := `SELECT id, original_url, short_code, created_at FROM urls WHERE original_url = $1`
+// GetURLByOriginal возвращает URL того же владельца по оригинальному URL (пустой owner - среди анонимных ссылок).
+// Если ссылок несколько, предпочтение отдается бессрочной
+func (s *PostgresStorage) GetURLByOriginal(ctx context.Context, originalURL string, owner models.Owner) (*models.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls
+		WHERE original_url = $1 AND owner_id IS NOT DISTINCT FROM $2 AND user_id IS NOT DISTINCT FROM $3
+		ORDER BY expires_at IS NOT NULL OR max_clicks IS NOT NULL OR password_hash <> '', id LIMIT 1`
 	var url models.URL
-	err := s.db.Get(&url, query, originalURL)
+	err := s.db.GetContext(ctx, &url, query, originalURL, owner.KeyID, owner.UserID)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -47,32 +85,576 @@ func (s *PostgresStorage) GetURLByOriginal(originalURL string) (*models.URL, err
 }
 
 // URLExists проверяет существование URL
-func (s *PostgresStorage) URLExists(shortCode string) (bool, error) {
+func (s *PostgresStorage) URLExists(ctx context.Context, shortCode string) (bool, error) {
 	query := `SELECT EXISTS(SELECT 1 FROM urls WHERE short_code = $1)`
 	var exists bool
-	err := s.db.Get(&exists, query, shortCode)
+	err := s.db.GetContext(ctx, &exists, query, shortCode)
 	return exists, err
 }
 
-// DeleteURL удаляет URL по короткому коду
-func (s *PostgresStorage) DeleteURL(shortCode string) error {
-	query := `DELETE FROM urls WHERE short_code = $1`
-	_, err := s.db.Exec(query, shortCode)
-	return err
+// DeleteURL удаляет URL по короткому коду и записывает удаление в журнал аудита
+func (s *PostgresStorage) DeleteURL(ctx context.Context, shortCode string) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM urls WHERE short_code = $1 RETURNING ` + urlColumns
+	var deleted models.URL
+	err = tx.GetContext(ctx, &deleted, query, shortCode)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := recordAudit(ctx, tx, models.AuditLinkDelete, linkChange{before: &deleted}); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // GetURLs возвращает все URL с пагинацией
-func (s *PostgresStorage) GetURLs(limit, offset int) ([]*models.URL, error) {
-	query := `SELECT id, original_url, short_code, created_at FROM urls ORDER BY created_at DESC LIMIT $1 OFFSET $2`
+func (s *PostgresStorage) GetURLs(ctx context.Context, limit, offset int) ([]*models.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls ORDER BY created_at DESC LIMIT $1 OFFSET $2`
 	var urls []*models.URL
-	err := s.db.Select(&urls, query, limit, offset)
+	err := s.db.SelectContext(ctx, &urls, query, limit, offset)
 	return urls, err
 }
 
 // GetURLsCount возвращает количество URL в базе
-func (s *PostgresStorage) GetURLsCount() (int, error) {
+func (s *PostgresStorage) GetURLsCount(ctx context.Context) (int, error) {
 	query := `SELECT COUNT(*) FROM urls`
 	var count int
-	err := s.db.Get(&count, query)
+	err := s.db.GetContext(ctx, &count, query)
+	return count, err
+}
+
+// searchCondition отбирает ссылки, у которых короткий код или исходный URL содержит $1 без учета регистра
+const searchCondition = `(short_code ILIKE $1 OR original_url ILIKE $1)`
+
+// SearchURLs ищет ссылки по подстроке короткого кода или исходного URL, новые первыми
+func (s *PostgresStorage) SearchURLs(ctx context.Context, query string, limit, offset int) ([]*models.URL, error) {
+	q := `SELECT ` + urlColumns + ` FROM urls WHERE ` + searchCondition + ` ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3`
+	var urls []*models.URL
+	err := s.db.SelectContext(ctx, &urls, q, likePattern(query), limit, offset)
+	return urls, err
+}
+
+// SearchURLsCount возвращает количество ссылок, найденных SearchURLs
+func (s *PostgresStorage) SearchURLsCount(ctx context.Context, query string) (int, error) {
+	var count int
+	err := s.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM urls WHERE `+searchCondition, likePattern(query))
+	return count, err
+}
+
+// likePattern экранирует спецсимволы LIKE, чтобы строка поиска искалась буквально
+func likePattern(query string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(query) + "%"
+}
+
+// SetURLDisabled отключает ссылку или снова включает ее. Повторное отключение не меняет время отключения.
+// Состояние до и после изменения записывается в журнал аудита
+func (s *PostgresStorage) SetURLDisabled(ctx context.Context, shortCode string, disabled bool) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var before models.URL
+	err = tx.GetContext(ctx, &before, `SELECT `+urlColumns+` FROM urls WHERE short_code = $1 FOR UPDATE`, shortCode)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE urls SET disabled_at = NULL WHERE short_code = $1 RETURNING ` + urlColumns
+	action := models.AuditLinkEnable
+	if disabled {
+		query = `UPDATE urls SET disabled_at = COALESCE(disabled_at, CURRENT_TIMESTAMP) WHERE short_code = $1 RETURNING ` + urlColumns
+		action = models.AuditLinkDisable
+	}
+	var after models.URL
+	if err := tx.GetContext(ctx, &after, query, shortCode); err != nil {
+		return err
+	}
+	if err := recordAudit(ctx, tx, action, linkChange{before: &before, after: &after}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ownerCondition отбирает ссылки, которыми владеет ключ $1 или пользователь $2
+const ownerCondition = `(owner_id = $1::bigint OR user_id = $2::bigint)`
+
+// GetURLsByOwner возвращает ссылки владельца с пагинацией, новые первыми
+func (s *PostgresStorage) GetURLsByOwner(ctx context.Context, owner models.Owner, limit, offset int) ([]*models.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE ` + ownerCondition + ` ORDER BY created_at DESC, id DESC LIMIT $3 OFFSET $4`
+	var urls []*models.URL
+	err := s.db.SelectContext(ctx, &urls, query, owner.KeyID, owner.UserID, limit, offset)
+	return urls, err
+}
+
+// GetURLsCountByOwner возвращает количество ссылок владельца
+func (s *PostgresStorage) GetURLsCountByOwner(ctx context.Context, owner models.Owner) (int, error) {
+	query := `SELECT COUNT(*) FROM urls WHERE ` + ownerCondition
+	var count int
+	err := s.db.GetContext(ctx, &count, query, owner.KeyID, owner.UserID)
+	return count, err
+}
+
+// IncrementClickCount атомарно увеличивает счетчик переходов по ссылке.
+// Инкремент выполняется внутри UPDATE, поэтому параллельные запросы не теряют клики.
+func (s *PostgresStorage) IncrementClickCount(ctx context.Context, shortCode string) error {
+	query := `UPDATE urls SET click_count = click_count + 1 WHERE short_code = $1`
+	res, err := s.db.ExecContext(ctx, query, shortCode)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ConsumeClick засчитывает переход по ссылке с учетом лимита max_clicks.
+// Условие проверяется в том же UPDATE, что и инкремент, поэтому параллельные
+// запросы не могут превысить лимит: строка блокируется, и второй UPDATE видит новое значение.
+func (s *PostgresStorage) ConsumeClick(ctx context.Context, shortCode string) error {
+	query := `UPDATE urls SET click_count = click_count + 1
+		WHERE short_code = $1 AND (max_clicks IS NULL OR click_count < max_clicks)`
+	res, err := s.db.ExecContext(ctx, query, shortCode)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	// Ни одна строка не обновлена: ссылки нет или лимит уже исчерпан
+	exists, err := s.URLExists(ctx, shortCode)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return ErrClickLimitReached
+}
+
+// SaveClickBatch увеличивает счетчики сразу для нескольких ссылок и сохраняет события переходов
+// одной транзакцией: при ошибке не записывается ничего и счетчики не расходятся с журналом.
+// Используется асинхронным регистратором кликов для сброса накопленных батчей
+func (s *PostgresStorage) SaveClickBatch(ctx context.Context, counts map[string]int64, clicks []*models.Click) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := incrementClickCounts(ctx, tx, counts); err != nil {
+		return err
+	}
+	if err := saveClicks(ctx, tx, clicks); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// incrementClickCounts увеличивает счетчики нескольких ссылок одним запросом
+func incrementClickCounts(ctx context.Context, db sqlx.ExecerContext, counts map[string]int64) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	codes := make([]string, 0, len(counts))
+	deltas := make([]int64, 0, len(counts))
+	for code, delta := range counts {
+		codes = append(codes, code)
+		deltas = append(deltas, delta)
+	}
+
+	query := `UPDATE urls SET click_count = urls.click_count + batch.delta
+		FROM (SELECT unnest($1::text[]) AS short_code, unnest($2::bigint[]) AS delta) AS batch
+		WHERE urls.short_code = batch.short_code`
+	_, err := db.ExecContext(ctx, query, pq.Array(codes), pq.Array(deltas))
+	return err
+}
+
+// SaveClicks сохраняет события переходов одним INSERT.
+// События для ссылок, удаленных до сброса батча, пропускаются, а не валят весь батч
+func (s *PostgresStorage) SaveClicks(ctx context.Context, clicks []*models.Click) error {
+	return saveClicks(ctx, s.db, clicks)
+}
+
+func saveClicks(ctx context.Context, db sqlx.ExecerContext, clicks []*models.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	var (
+		codes, clickedAt, referrers, userAgents []string
+		ipHashes, languages, countries          []string
+	)
+	for _, click := range clicks {
+		codes = append(codes, click.ShortCode)
+		clickedAt = append(clickedAt, click.ClickedAt.Format(time.RFC3339Nano))
+		referrers = append(referrers, click.Referrer)
+		userAgents = append(userAgents, click.UserAgent)
+		ipHashes = append(ipHashes, click.IPHash)
+		languages = append(languages, click.AcceptLanguage)
+		countries = append(countries, click.Country)
+	}
+
+	query := `INSERT INTO clicks (short_code, clicked_at, referrer, user_agent, ip_hash, accept_language, country)
+		SELECT b.short_code, b.clicked_at, b.referrer, b.user_agent, b.ip_hash, b.accept_language, b.country
+		FROM unnest($1::text[], $2::timestamptz[], $3::text[], $4::text[], $5::text[], $6::text[], $7::text[])
+			AS b(short_code, clicked_at, referrer, user_agent, ip_hash, accept_language, country)
+		JOIN urls ON urls.short_code = b.short_code`
+	_, err := db.ExecContext(ctx, query, pq.Array(codes), pq.Array(clickedAt), pq.Array(referrers),
+		pq.Array(userAgents), pq.Array(ipHashes), pq.Array(languages), pq.Array(countries))
+	return err
+}
+
+// GetClicks возвращает события переходов по ссылке, начиная с самых свежих
+func (s *PostgresStorage) GetClicks(ctx context.Context, shortCode string, limit, offset int) ([]*models.Click, error) {
+	query := `SELECT id, short_code, clicked_at, referrer, user_agent, ip_hash, accept_language, country
+		FROM clicks WHERE short_code = $1 ORDER BY clicked_at DESC, id DESC LIMIT $2 OFFSET $3`
+	clicks := []*models.Click{}
+	err := s.db.SelectContext(ctx, &clicks, query, shortCode, limit, offset)
+	return clicks, err
+}
+
+// GetClicksCount возвращает общее количество событий переходов по ссылке
+func (s *PostgresStorage) GetClicksCount(ctx context.Context, shortCode string) (int, error) {
+	query := `SELECT COUNT(*) FROM clicks WHERE short_code = $1`
+	var count int
+	err := s.db.GetContext(ctx, &count, query, shortCode)
+	return count, err
+}
+
+// GetClickTimeSeries возвращает количество переходов по интервалам в полуинтервале [from, to).
+// Границы интервалов считаются в часовом поясе loc; пустые интервалы в результат не попадают
+func (s *PostgresStorage) GetClickTimeSeries(ctx context.Context, shortCode string, from, to time.Time, interval string, loc *time.Location) ([]*models.TimeBucket, error) {
+	query := `SELECT date_trunc($2, clicked_at AT TIME ZONE $3) AT TIME ZONE $3 AS bucket, COUNT(*) AS count
+		FROM clicks WHERE short_code = $1 AND clicked_at >= $4 AND clicked_at < $5
+		GROUP BY bucket ORDER BY bucket`
+	buckets := []*models.TimeBucket{}
+	if err := s.db.SelectContext(ctx, &buckets, query, shortCode, interval, loc.String(), from, to); err != nil {
+		return nil, err
+	}
+	for _, bucket := range buckets {
+		bucket.Start = bucket.Start.In(loc)
+	}
+	return buckets, nil
+}
+
+// NextSequenceValue возвращает следующий номер из последовательности коротких кодов
+func (s *PostgresStorage) NextSequenceValue(ctx context.Context) (int64, error) {
+	var value int64
+	err := s.db.GetContext(ctx, &value, `SELECT nextval('short_code_seq')`)
+	return value, err
+}
+
+// PurgeExpiredURLs удаляет не более limit ссылок, срок действия которых истек до before.
+// При archive = true удаленные строки переносятся в urls_archive вместе с хэшем пароля, а их переходы
+// в clicks_archive тем же запросом: каскадное удаление clicks не успевает их стереть.
+// Каждая удаленная ссылка записывается в журнал аудита в той же транзакции
+func (s *PostgresStorage) PurgeExpiredURLs(ctx context.Context, before time.Time, limit int, archive bool) (int64, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM urls WHERE id IN (
+		SELECT id FROM urls WHERE expires_at <= $1 ORDER BY expires_at LIMIT $2 FOR UPDATE SKIP LOCKED)
+		RETURNING ` + urlColumns
+	action := models.AuditLinkPurge
+	if archive {
+		query = `WITH expired AS (
+			DELETE FROM urls WHERE id IN (
+				SELECT id FROM urls WHERE expires_at <= $1 ORDER BY expires_at LIMIT $2 FOR UPDATE SKIP LOCKED)
+			RETURNING ` + urlColumns + `),
+		archived AS (
+			INSERT INTO urls_archive (` + urlColumns + `)
+			SELECT ` + urlColumns + ` FROM expired),
+		moved_clicks AS (
+			DELETE FROM clicks WHERE short_code IN (SELECT short_code FROM expired)
+			RETURNING id, short_code, clicked_at, referrer, user_agent, ip_hash, accept_language, country),
+		archived_clicks AS (
+			INSERT INTO clicks_archive (id, short_code, clicked_at, referrer, user_agent, ip_hash, accept_language, country)
+			SELECT id, short_code, clicked_at, referrer, user_agent, ip_hash, accept_language, country FROM moved_clicks)
+		SELECT ` + urlColumns + ` FROM expired`
+		action = models.AuditLinkArchive
+	}
+
+	var expired []*models.URL
+	if err := tx.SelectContext(ctx, &expired, query, before, limit); err != nil {
+		return 0, err
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+
+	changes := make([]linkChange, len(expired))
+	for i, url := range expired {
+		changes[i] = linkChange{before: url}
+	}
+	if err := recordAudit(ctx, tx, action, changes...); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int64(len(expired)), nil
+}
+
+// apiKeyColumns список колонок api_keys, читаемых в models.APIKey
+const apiKeyColumns = `id, name, prefix, key_hash, role, created_at, last_used_at, revoked_at`
+
+// CreateAPIKey сохраняет новый ключ и заполняет его ID и время создания. Без роли
+// ключ получает models.RoleEditor
+func (s *PostgresStorage) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	if key.Role == "" {
+		key.Role = models.RoleEditor
+	}
+	query := `INSERT INTO api_keys (name, prefix, key_hash, role) VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+	err := s.db.QueryRowxContext(ctx, query, key.Name, key.Prefix, key.KeyHash, key.Role).Scan(&key.ID, &key.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+// GetAPIKeyByHash возвращает ключ по хэшу, в том числе отозванный
+func (s *PostgresStorage) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1`
+	var key models.APIKey
+	err := s.db.GetContext(ctx, &key, query, keyHash)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return &key, err
+}
+
+// ListAPIKeys возвращает все ключи в порядке создания
+func (s *PostgresStorage) ListAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY id`
+	var keys []*models.APIKey
+	err := s.db.SelectContext(ctx, &keys, query)
+	return keys, err
+}
+
+// RevokeAPIKey отзывает ключ. Повторный отзыв не меняет время отзыва
+func (s *PostgresStorage) RevokeAPIKey(ctx context.Context, id int64) error {
+	query := `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP) WHERE id = $1`
+	res, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// TouchAPIKey запоминает время последнего использования ключа
+func (s *PostgresStorage) TouchAPIKey(ctx context.Context, id int64, usedAt time.Time) error {
+	query := `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`
+	_, err := s.db.ExecContext(ctx, query, id, usedAt)
+	return err
+}
+
+// userColumns список колонок users, читаемых в models.User
+const userColumns = `id, email, password_hash, role, oidc_issuer, oidc_subject, created_at`
+
+// CreateUser сохраняет пользователя и заполняет его ID и время создания. Без роли
+// пользователь получает models.RoleEditor. Занятый email или учетная запись SSO - ErrAlreadyExists
+func (s *PostgresStorage) CreateUser(ctx context.Context, user *models.User) error {
+	if user.Role == "" {
+		user.Role = models.RoleEditor
+	}
+	query := `
+		INSERT INTO users (email, password_hash, role, oidc_issuer, oidc_subject)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+	err := s.db.QueryRowxContext(ctx, query, user.Email, user.PasswordHash, user.Role, user.OIDCIssuer, user.OIDCSubject).
+		Scan(&user.ID, &user.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+// UpdateUser сохраняет email, роль и привязку к SSO. Занятый email - ErrAlreadyExists
+func (s *PostgresStorage) UpdateUser(ctx context.Context, user *models.User) error {
+	query := `UPDATE users SET email = $2, role = $3, oidc_issuer = $4, oidc_subject = $5 WHERE id = $1`
+	result, err := s.db.ExecContext(ctx, query, user.ID, user.Email, user.Role, user.OIDCIssuer, user.OIDCSubject)
+	if isUniqueViolation(err) {
+		return ErrAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetUserByEmail возвращает пользователя по email
+func (s *PostgresStorage) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return s.getUser(ctx, `SELECT `+userColumns+` FROM users WHERE email = $1`, email)
+}
+
+// GetUserByID возвращает пользователя по ID
+func (s *PostgresStorage) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
+	return s.getUser(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id)
+}
+
+// GetUserByOIDCSubject возвращает пользователя, привязанного к учетной записи провайдера
+func (s *PostgresStorage) GetUserByOIDCSubject(ctx context.Context, issuer, subject string) (*models.User, error) {
+	return s.getUser(ctx, `SELECT `+userColumns+` FROM users WHERE oidc_issuer = $1 AND oidc_subject = $2`, issuer, subject)
+}
+
+func (s *PostgresStorage) getUser(ctx context.Context, query string, args ...any) (*models.User, error) {
+	var user models.User
+	err := s.db.GetContext(ctx, &user, query, args...)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return &user, err
+}
+
+// CreateSession сохраняет сессию и заодно удаляет истекшие сессии того же пользователя
+func (s *PostgresStorage) CreateSession(ctx context.Context, session *models.Session) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1 AND expires_at <= CURRENT_TIMESTAMP`, session.UserID); err != nil {
+		return err
+	}
+	query := `INSERT INTO sessions (id, user_id, csrf_token, expires_at) VALUES ($1, $2, $3, $4) RETURNING created_at`
+	if err := tx.QueryRowxContext(ctx, query, session.ID, session.UserID, session.CSRFToken, session.ExpiresAt).Scan(&session.CreatedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetSession возвращает сессию по ID, в том числе истекшую
+func (s *PostgresStorage) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	query := `SELECT id, user_id, csrf_token, created_at, expires_at FROM sessions WHERE id = $1`
+	var session models.Session
+	err := s.db.GetContext(ctx, &session, query, id)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return &session, err
+}
+
+// DeleteSession удаляет сессию; отсутствующая сессия ошибкой не считается
+func (s *PostgresStorage) DeleteSession(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = $1`, id)
+	return err
+}
+
+// linkChange состояние ссылки до и после изменения; nil - ссылки не было или она удалена
+type linkChange struct {
+	before, after *models.URL
+}
+
+// recordAudit записывает изменения ссылок в журнал аудита в транзакции самого изменения.
+// Исполнитель, IP и ID запроса берутся из audit.Origin в контексте
+func recordAudit(ctx context.Context, tx *sqlx.Tx, action string, changes ...linkChange) error {
+	origin := audit.OriginFromContext(ctx)
+	codes := make([]string, len(changes))
+	befores := make([]sql.NullString, len(changes))
+	afters := make([]sql.NullString, len(changes))
+	for i, change := range changes {
+		var err error
+		if befores[i], err = linkJSON(change.before); err != nil {
+			return err
+		}
+		if afters[i], err = linkJSON(change.after); err != nil {
+			return err
+		}
+		if change.after != nil {
+			codes[i] = change.after.ShortCode
+		} else if change.before != nil {
+			codes[i] = change.before.ShortCode
+		}
+	}
+
+	query := `INSERT INTO audit_events (actor, action, short_code, before, after, client_ip, request_id)
+		SELECT $1, $2, code, before, after, $6, $7 FROM unnest($3::text[], $4::jsonb[], $5::jsonb[]) AS t(code, before, after)`
+	_, err := tx.ExecContext(ctx, query, origin.Actor, action, pq.Array(codes), pq.Array(befores), pq.Array(afters),
+		origin.ClientIP, origin.RequestID)
+	return err
+}
+
+// linkJSON сериализует ссылку для журнала аудита; хэш пароля в JSON не попадает
+func linkJSON(url *models.URL) (sql.NullString, error) {
+	if url == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(url)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// auditCondition отбирает события по ссылке $1 и исполнителю $2; пустое значение не ограничивает выборку
+const auditCondition = `($1 = '' OR short_code = $1) AND ($2 = '' OR actor = $2)`
+
+// GetAuditEvents возвращает события журнала аудита с пагинацией, новые первыми
+func (s *PostgresStorage) GetAuditEvents(ctx context.Context, filter models.AuditFilter, limit, offset int) ([]*models.AuditEvent, error) {
+	query := `SELECT id, created_at, actor, action, short_code, before, after, client_ip, request_id
+		FROM audit_events WHERE ` + auditCondition + ` ORDER BY id DESC LIMIT $3 OFFSET $4`
+	var events []*models.AuditEvent
+	err := s.db.SelectContext(ctx, &events, query, filter.ShortCode, filter.Actor, limit, offset)
+	return events, err
+}
+
+// GetAuditEventsCount возвращает количество событий журнала аудита по фильтру
+func (s *PostgresStorage) GetAuditEventsCount(ctx context.Context, filter models.AuditFilter) (int, error) {
+	query := `SELECT COUNT(*) FROM audit_events WHERE ` + auditCondition
+	var count int
+	err := s.db.GetContext(ctx, &count, query, filter.ShortCode, filter.Actor)
 	return count, err
 }
+
+// PurgeAuditEvents удаляет не более limit событий, записанных до before
+func (s *PostgresStorage) PurgeAuditEvents(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `DELETE FROM audit_events WHERE id IN (
+		SELECT id FROM audit_events WHERE created_at < $1 ORDER BY id LIMIT $2)`
+	res, err := s.db.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
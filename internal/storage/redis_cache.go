@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// notFoundMarker значение, которым в кэше помечается несуществующий короткий код
+const notFoundMarker = "-"
+
+// generationTTL время жизни счетчика инвалидаций кода; должно превышать время любого чтения из хранилища
+const generationTTL = time.Hour
+
+// storeIfCurrent записывает значение KEYS[1], только если счетчик инвалидаций KEYS[2]
+// не изменился с момента промаха (ARGV[1], пусто - счетчика не было)
+var storeIfCurrent = redis.NewScript(`
+local current = redis.call('GET', KEYS[2])
+if (current or '') ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
+// RedisCacheOptions задает параметры кэша ссылок
+type RedisCacheOptions struct {
+	TTL         time.Duration // Время жизни найденной ссылки в кэше
+	NegativeTTL time.Duration // Время жизни отметки об отсутствии ссылки
+	Prefix      string        // Префикс ключей Redis
+}
+
+// RedisCache декоратор Storage, который читает ссылки по короткому коду через Redis.
+// Остальные методы делегируются обернутому хранилищу; методы, меняющие ссылку,
+// сбрасывают ее запись в кэше. Ошибки Redis не ломают редирект: запрос уходит в хранилище.
+//
+// Обычные клики кэш не сбрасывают, иначе популярные ссылки не задерживались бы в нем,
+// поэтому click_count в закэшированной ссылке может отставать не более чем на TTL.
+// Временные ссылки не хранятся в кэше дольше срока действия, поэтому очистка
+// истекших ссылок janitor'ом тоже не требует инвалидации.
+//
+// Инвалидация увеличивает счетчик кода в Redis. Промах запоминает счетчик и кладет
+// прочитанную ссылку в кэш, только если он не изменился: иначе чтение, начатое до удаления
+// или отключения ссылки, вернуло бы ее в кэш на весь TTL, в том числе на других экземплярах.
+type RedisCache struct {
+	Storage
+	client *redis.Client
+	opts   RedisCacheOptions
+	// TTL меняются на лету через SetTTL, поэтому хранятся отдельно от opts
+	ttl         atomic.Int64
+	negativeTTL atomic.Int64
+}
+
+// cachedURL представление ссылки в кэше. Отличается от JSON модели тем,
+// что содержит хэш пароля, который не отдается в API
+type cachedURL struct {
+	ID           int64      `json:"id"`
+	OriginalURL  string     `json:"original_url"`
+	ShortCode    string     `json:"short_code"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ClickCount   int64      `json:"click_count"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	MaxClicks    *int64     `json:"max_clicks,omitempty"`
+	PasswordHash string     `json:"password_hash,omitempty"`
+	OwnerID      *int64     `json:"owner_id,omitempty"`
+	UserID       *int64     `json:"user_id,omitempty"`
+	DisabledAt   *time.Time `json:"disabled_at,omitempty"`
+}
+
+// NewRedisCache оборачивает хранилище кэшем в Redis
+func NewRedisCache(storage Storage, client *redis.Client, opts RedisCacheOptions) *RedisCache {
+	if opts.TTL <= 0 {
+		opts.TTL = 10 * time.Minute
+	}
+	if opts.NegativeTTL <= 0 {
+		opts.NegativeTTL = 30 * time.Second
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = "url:"
+	}
+	c := &RedisCache{Storage: storage, client: client, opts: opts}
+	c.SetTTL(opts.TTL, opts.NegativeTTL)
+	return c
+}
+
+// SetTTL меняет время жизни новых записей; уже закэшированные записи истекают по старому TTL.
+// Неположительные значения игнорируются
+func (c *RedisCache) SetTTL(ttl, negativeTTL time.Duration) {
+	if ttl > 0 {
+		c.ttl.Store(int64(ttl))
+	}
+	if negativeTTL > 0 {
+		c.negativeTTL.Store(int64(negativeTTL))
+	}
+}
+
+func (c *RedisCache) key(shortCode string) string {
+	return c.opts.Prefix + shortCode
+}
+
+// generationKey ключ счетчика инвалидаций; ':' не встречается в коротких кодах
+func (c *RedisCache) generationKey(shortCode string) string {
+	return c.opts.Prefix + "gen:" + shortCode
+}
+
+// GetURL возвращает ссылку из кэша, а при промахе читает ее из хранилища и кэширует.
+// Отсутствующие коды кэшируются на NegativeTTL, чтобы перебор кодов не нагружал базу
+func (c *RedisCache) GetURL(ctx context.Context, shortCode string) (*models.URL, error) {
+	// Ошибка Redis оставляет счетчик неизвестным: ссылка читается из хранилища без записи в кэш
+	cacheable := false
+	var generation string
+	values, err := c.client.MGet(ctx, c.key(shortCode), c.generationKey(shortCode)).Result()
+	if err != nil {
+		log.Warn().Err(err).Str("short_code", shortCode).Msg("Cache read failed")
+	} else {
+		cacheable = true
+		generation, _ = values[1].(string)
+		if value, ok := values[0].(string); ok {
+			if value == notFoundMarker {
+				return nil, ErrNotFound
+			}
+			var cached cachedURL
+			if err := json.Unmarshal([]byte(value), &cached); err == nil {
+				return cached.toModel(), nil
+			}
+			log.Warn().Str("short_code", shortCode).Msg("Corrupted cache entry, reading from storage")
+		}
+	}
+
+	url, err := c.Storage.GetURL(ctx, shortCode)
+	if errors.Is(err, ErrNotFound) {
+		if cacheable {
+			c.set(ctx, shortCode, generation, notFoundMarker, time.Duration(c.negativeTTL.Load()))
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := time.Duration(c.ttl.Load())
+	// Временную ссылку не держим в кэше дольше срока ее действия
+	if url.ExpiresAt != nil {
+		if untilExpiry := time.Until(*url.ExpiresAt); untilExpiry < ttl {
+			ttl = untilExpiry
+		}
+	}
+	if cacheable && ttl > 0 {
+		data, err := json.Marshal(newCachedURL(url))
+		if err == nil {
+			c.set(ctx, shortCode, generation, string(data), ttl)
+		}
+	}
+	return url, nil
+}
+
+// SaveURL сохраняет ссылку и снимает отметку об отсутствии кода
+func (c *RedisCache) SaveURL(ctx context.Context, url *models.URL) error {
+	if err := c.Storage.SaveURL(ctx, url); err != nil {
+		return err
+	}
+	c.invalidate(ctx, url.ShortCode)
+	return nil
+}
+
+// DeleteURL удаляет ссылку из хранилища и из кэша
+func (c *RedisCache) DeleteURL(ctx context.Context, shortCode string) error {
+	if err := c.Storage.DeleteURL(ctx, shortCode); err != nil {
+		return err
+	}
+	c.invalidate(ctx, shortCode)
+	return nil
+}
+
+// SetURLDisabled меняет состояние ссылки и сбрасывает ее запись в кэше,
+// чтобы отключенная ссылка сразу перестала открываться
+func (c *RedisCache) SetURLDisabled(ctx context.Context, shortCode string, disabled bool) error {
+	if err := c.Storage.SetURLDisabled(ctx, shortCode, disabled); err != nil {
+		return err
+	}
+	c.invalidate(ctx, shortCode)
+	return nil
+}
+
+// ConsumeClick списывает переход и сбрасывает кэш, чтобы исчерпанная ссылка
+// сразу отдавала 410 без обращения к хранилищу
+func (c *RedisCache) ConsumeClick(ctx context.Context, shortCode string) error {
+	err := c.Storage.ConsumeClick(ctx, shortCode)
+	if err == nil || errors.Is(err, ErrClickLimitReached) {
+		c.invalidate(ctx, shortCode)
+	}
+	return err
+}
+
+// set кладет значение в кэш, если с момента промаха код не инвалидировали (generation)
+func (c *RedisCache) set(ctx context.Context, shortCode, generation, value string, ttl time.Duration) {
+	keys := []string{c.key(shortCode), c.generationKey(shortCode)}
+	if err := storeIfCurrent.Run(ctx, c.client, keys, generation, value, ttl.Milliseconds()).Err(); err != nil {
+		log.Warn().Err(err).Str("short_code", shortCode).Msg("Cache write failed")
+	}
+}
+
+// invalidate удаляет записи из кэша и увеличивает счетчики инвалидаций. Отмена запроса
+// не должна оставить в кэше устаревшую ссылку, поэтому запросы выполняются без учета отмены контекста
+func (c *RedisCache) invalidate(ctx context.Context, shortCodes ...string) {
+	if len(shortCodes) == 0 {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, shortCode := range shortCodes {
+			pipe.Incr(ctx, c.generationKey(shortCode))
+			pipe.PExpire(ctx, c.generationKey(shortCode), generationTTL)
+			pipe.Del(ctx, c.key(shortCode))
+		}
+		return nil
+	})
+	if err != nil {
+		log.Warn().Err(err).Strs("short_codes", shortCodes).Msg("Cache invalidation failed")
+	}
+}
+
+func newCachedURL(url *models.URL) cachedURL {
+	return cachedURL{
+		ID:           url.ID,
+		OriginalURL:  url.OriginalURL,
+		ShortCode:    url.ShortCode,
+		CreatedAt:    url.CreatedAt,
+		ClickCount:   url.ClickCount,
+		ExpiresAt:    url.ExpiresAt,
+		MaxClicks:    url.MaxClicks,
+		PasswordHash: url.PasswordHash,
+		OwnerID:      url.OwnerID,
+		UserID:       url.UserID,
+		DisabledAt:   url.DisabledAt,
+	}
+}
+
+func (c cachedURL) toModel() *models.URL {
+	return &models.URL{
+		ID:           c.ID,
+		OriginalURL:  c.OriginalURL,
+		ShortCode:    c.ShortCode,
+		CreatedAt:    c.CreatedAt,
+		ClickCount:   c.ClickCount,
+		ExpiresAt:    c.ExpiresAt,
+		MaxClicks:    c.MaxClicks,
+		PasswordHash: c.PasswordHash,
+		OwnerID:      c.OwnerID,
+		UserID:       c.UserID,
+		DisabledAt:   c.DisabledAt,
+	}
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingStorage считает обращения к GetURL обернутого хранилища
+type countingStorage struct {
+	*MockStorage
+	gets atomic.Int64
+}
+
+func (s *countingStorage) GetURL(ctx context.Context, shortCode string) (*models.URL, error) {
+	s.gets.Add(1)
+	return s.MockStorage.GetURL(context.Background(), shortCode)
+}
+
+func newTestRedisCache(t *testing.T) (*RedisCache, *countingStorage, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+
+	backend := &countingStorage{MockStorage: NewMockStorage()}
+	cache := NewRedisCache(backend, client, RedisCacheOptions{TTL: time.Minute, NegativeTTL: 5 * time.Second})
+	return cache, backend, server
+}
+
+func TestRedisCache_ReadThrough(t *testing.T) {
+	cache, backend, server := newTestRedisCache(t)
+
+	err := cache.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "abc123", PasswordHash: "hash"})
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		url, err := cache.GetURL(context.Background(), "abc123")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com", url.OriginalURL)
+		assert.Equal(t, "hash", url.PasswordHash)
+	}
+	assert.Equal(t, int64(1), backend.gets.Load())
+	assert.Equal(t, time.Minute, server.TTL("url:abc123"))
+
+	// После истечения TTL ссылка снова читается из хранилища
+	server.FastForward(time.Minute)
+	_, err = cache.GetURL(context.Background(), "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), backend.gets.Load())
+}
+
+func TestRedisCache_NegativeCaching(t *testing.T) {
+	cache, backend, server := newTestRedisCache(t)
+
+	for i := 0; i < 3; i++ {
+		_, err := cache.GetURL(context.Background(), "missing")
+		assert.ErrorIs(t, err, ErrNotFound)
+	}
+	assert.Equal(t, int64(1), backend.gets.Load())
+	assert.Equal(t, 5*time.Second, server.TTL("url:missing"))
+
+	// Сохранение ссылки снимает отметку об отсутствии
+	err := cache.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "missing"})
+	assert.NoError(t, err)
+	url, err := cache.GetURL(context.Background(), "missing")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", url.OriginalURL)
+}
+
+func TestRedisCache_Invalidation(t *testing.T) {
+	cache, _, server := newTestRedisCache(t)
+
+	maxClicks := int64(1)
+	err := cache.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "once123", MaxClicks: &maxClicks})
+	assert.NoError(t, err)
+	err = cache.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "abc123"})
+	assert.NoError(t, err)
+
+	_, _ = cache.GetURL(context.Background(), "once123")
+	assert.NoError(t, cache.ConsumeClick(context.Background(), "once123"))
+	assert.False(t, server.Exists("url:once123"))
+	url, err := cache.GetURL(context.Background(), "once123")
+	assert.NoError(t, err)
+	assert.True(t, url.IsExhausted())
+
+	_, _ = cache.GetURL(context.Background(), "abc123")
+	assert.NoError(t, cache.DeleteURL(context.Background(), "abc123"))
+	_, err = cache.GetURL(context.Background(), "abc123")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+// racingStorage выполняет afterGet между чтением ссылки и возвратом результата
+type racingStorage struct {
+	*MockStorage
+	afterGet func()
+}
+
+func (s *racingStorage) GetURL(ctx context.Context, shortCode string) (*models.URL, error) {
+	url, err := s.MockStorage.GetURL(context.Background(), shortCode)
+	if s.afterGet != nil {
+		afterGet := s.afterGet
+		s.afterGet = nil
+		afterGet()
+	}
+	return url, err
+}
+
+func TestRedisCache_StaleReadIsNotCached(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	backend := &racingStorage{MockStorage: NewMockStorage()}
+	cache := NewRedisCache(backend, client, RedisCacheOptions{TTL: time.Minute})
+
+	tests := []struct {
+		name   string
+		change func(shortCode string) error
+	}{
+		{"Delete", func(shortCode string) error { return cache.DeleteURL(context.Background(), shortCode) }},
+		{"Disable", func(shortCode string) error { return cache.SetURLDisabled(context.Background(), shortCode, true) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shortCode := "race" + tt.name
+			err := cache.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: shortCode})
+			assert.NoError(t, err)
+
+			// Ссылка меняется, пока промах читает ее из хранилища
+			backend.afterGet = func() { assert.NoError(t, tt.change(shortCode)) }
+			url, err := cache.GetURL(context.Background(), shortCode)
+			assert.NoError(t, err)
+			assert.False(t, url.IsDisabled())
+			assert.False(t, server.Exists("url:"+shortCode))
+
+			// Следующее чтение видит изменение и кэширует уже его
+			url, err = cache.GetURL(context.Background(), shortCode)
+			if tt.name == "Delete" {
+				assert.ErrorIs(t, err, ErrNotFound)
+			} else {
+				assert.NoError(t, err)
+				assert.True(t, url.IsDisabled())
+			}
+			assert.True(t, server.Exists("url:"+shortCode))
+		})
+	}
+}
+
+func TestRedisCache_ExpiringLinkTTL(t *testing.T) {
+	cache, _, server := newTestRedisCache(t)
+
+	expiresAt := time.Now().Add(10 * time.Second)
+	err := cache.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "temp123", ExpiresAt: &expiresAt})
+	assert.NoError(t, err)
+
+	_, err = cache.GetURL(context.Background(), "temp123")
+	assert.NoError(t, err)
+	ttl := server.TTL("url:temp123")
+	assert.True(t, ttl > 0 && ttl <= 10*time.Second, "unexpected TTL %v", ttl)
+}
+
+func TestRedisCache_FallbackWhenRedisIsDown(t *testing.T) {
+	cache, backend, server := newTestRedisCache(t)
+
+	err := cache.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "abc123"})
+	assert.NoError(t, err)
+	server.Close()
+
+	url, err := cache.GetURL(context.Background(), "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", url.OriginalURL)
+	assert.Equal(t, int64(1), backend.gets.Load())
+}
@@ -1,7 +1,9 @@
 package storage
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"github.com/drerr0r/url-shortener/internal/models"
 )
@@ -9,13 +11,62 @@ import (
 // 🟡 ДОБАВЛЕНО: Определяем ошибку для отсутствующих записей
 var ErrNotFound = errors.New("record not found")
 
+// ErrAlreadyExists возвращается при попытке сохранить ссылку с уже занятым коротким кодом
+var ErrAlreadyExists = errors.New("record already exists")
+
+// ErrClickLimitReached возвращается, когда лимит переходов по ссылке уже израсходован
+var ErrClickLimitReached = errors.New("click limit reached")
+
 // Storage интерфейс для работы с хранилищем URL
 type Storage interface {
-	SaveURL(url *models.URL) error
-	GetURL(shortCode string) (*models.URL, error)
-	GetURLByOriginal(originalURL string) (*models.URL, error) // 🟡 ДОБАВЛЕНО: отсутствующий метод
-	URLExists(shortCode string) (bool, error)
-	DeleteURL(shortCode string) error
-	GetURLs(limit, offset int) ([]*models.URL, error)
-	GetURLsCount() (int, error)
+	SaveURL(ctx context.Context, url *models.URL) error
+	GetURL(ctx context.Context, shortCode string) (*models.URL, error)
+	GetURLByOriginal(ctx context.Context, originalURL string, owner models.Owner) (*models.URL, error) // 🟡 ДОБАВЛЕНО: отсутствующий метод
+	URLExists(ctx context.Context, shortCode string) (bool, error)
+	DeleteURL(ctx context.Context, shortCode string) error
+	GetURLs(ctx context.Context, limit, offset int) ([]*models.URL, error)
+	GetURLsCount(ctx context.Context) (int, error)
+	SearchURLs(ctx context.Context, query string, limit, offset int) ([]*models.URL, error)
+	SearchURLsCount(ctx context.Context, query string) (int, error)
+	SetURLDisabled(ctx context.Context, shortCode string, disabled bool) error
+	GetURLsByOwner(ctx context.Context, owner models.Owner, limit, offset int) ([]*models.URL, error)
+	GetURLsCountByOwner(ctx context.Context, owner models.Owner) (int, error)
+	IncrementClickCount(ctx context.Context, shortCode string) error
+	SaveClickBatch(ctx context.Context, counts map[string]int64, clicks []*models.Click) error
+	ConsumeClick(ctx context.Context, shortCode string) error
+	SaveClicks(ctx context.Context, clicks []*models.Click) error
+	GetClicks(ctx context.Context, shortCode string, limit, offset int) ([]*models.Click, error)
+	GetClicksCount(ctx context.Context, shortCode string) (int, error)
+	NextSequenceValue(ctx context.Context) (int64, error)
+	PurgeExpiredURLs(ctx context.Context, before time.Time, limit int, archive bool) (int64, error)
+	GetClickTimeSeries(ctx context.Context, shortCode string, from, to time.Time, interval string, loc *time.Location) ([]*models.TimeBucket, error)
+}
+
+// AuditStorage интерфейс для чтения журнала аудита. События пишет само хранилище
+// в одной транзакции с изменением ссылки, источник изменения берется из audit.Origin в контексте
+type AuditStorage interface {
+	GetAuditEvents(ctx context.Context, filter models.AuditFilter, limit, offset int) ([]*models.AuditEvent, error)
+	GetAuditEventsCount(ctx context.Context, filter models.AuditFilter) (int, error)
+	PurgeAuditEvents(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+// APIKeyStorage интерфейс для работы с API ключами
+type APIKeyStorage interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]*models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int64) error
+	TouchAPIKey(ctx context.Context, id int64, usedAt time.Time) error
+}
+
+// UserStorage интерфейс для работы с пользователями и их сессиями
+type UserStorage interface {
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByID(ctx context.Context, id int64) (*models.User, error)
+	GetUserByOIDCSubject(ctx context.Context, issuer, subject string) (*models.User, error)
+	UpdateUser(ctx context.Context, user *models.User) error
+	CreateSession(ctx context.Context, session *models.Session) error
+	GetSession(ctx context.Context, id string) (*models.Session, error)
+	DeleteSession(ctx context.Context, id string) error
 }
@@ -1,9 +1,13 @@
 package storage
 
 import (
+	"context"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/drerr0r/url-shortener/internal/audit"
 	"github.com/drerr0r/url-shortener/internal/models"
 	"github.com/stretchr/testify/assert"
 )
@@ -17,18 +21,18 @@ func TestMockStorage_SaveAndGetURL(t *testing.T) {
 		CreatedAt:   time.Now(),
 	}
 
-	err := storage.SaveURL(url)
+	err := storage.SaveURL(context.Background(), url)
 	assert.NoError(t, err)
 
 	count := storage.GetURLCount()
 	assert.Equal(t, 1, count)
 
-	retrievedURL, err := storage.GetURL("test123")
+	retrievedURL, err := storage.GetURL(context.Background(), "test123")
 	assert.NoError(t, err)
 	assert.Equal(t, url.OriginalURL, retrievedURL.OriginalURL)
 	assert.Equal(t, url.ShortCode, retrievedURL.ShortCode)
 
-	_, err = storage.GetURL("nonexistent")
+	_, err = storage.GetURL(context.Background(), "nonexistent")
 	assert.Equal(t, ErrNotFound, err)
 }
 
@@ -40,17 +44,17 @@ func TestMockStorage_URLExists(t *testing.T) {
 		ShortCode:   "test123",
 	}
 
-	err := storage.SaveURL(url)
+	err := storage.SaveURL(context.Background(), url)
 	assert.NoError(t, err)
 
 	count := storage.GetURLCount()
 	assert.Equal(t, 1, count)
 
-	exists, err := storage.URLExists("test123")
+	exists, err := storage.URLExists(context.Background(), "test123")
 	assert.NoError(t, err)
 	assert.True(t, exists)
 
-	exists, err = storage.URLExists("nonexistent")
+	exists, err = storage.URLExists(context.Background(), "nonexistent")
 	assert.NoError(t, err)
 	assert.False(t, exists)
 }
@@ -63,16 +67,16 @@ func TestMockStorage_DeleteURL(t *testing.T) {
 		ShortCode:   "test123",
 	}
 
-	err := storage.SaveURL(url)
+	err := storage.SaveURL(context.Background(), url)
 	assert.NoError(t, err)
 
 	count := storage.GetURLCount()
 	assert.Equal(t, 1, count)
 
-	err = storage.DeleteURL("test123")
+	err = storage.DeleteURL(context.Background(), "test123")
 	assert.NoError(t, err)
 
-	_, err = storage.GetURL("test123")
+	_, err = storage.GetURL(context.Background(), "test123")
 	assert.Equal(t, ErrNotFound, err)
 
 	count = storage.GetURLCount()
@@ -87,15 +91,15 @@ func TestMockStorage_GetURLByOriginal(t *testing.T) {
 		ShortCode:   "test123",
 	}
 
-	err := storage.SaveURL(url)
+	err := storage.SaveURL(context.Background(), url)
 	assert.NoError(t, err)
 
-	retrievedURL, err := storage.GetURLByOriginal("https://example.com")
+	retrievedURL, err := storage.GetURLByOriginal(context.Background(), "https://example.com", models.Owner{})
 	assert.NoError(t, err)
 	assert.NotNil(t, retrievedURL)
 	assert.Equal(t, url.ShortCode, retrievedURL.ShortCode)
 
-	retrievedURL, err = storage.GetURLByOriginal("https://nonexistent.com")
+	retrievedURL, err = storage.GetURLByOriginal(context.Background(), "https://nonexistent.com", models.Owner{})
 	assert.NoError(t, err)
 	assert.Nil(t, retrievedURL)
 }
@@ -110,15 +114,356 @@ func TestMockStorage_GetURLs(t *testing.T) {
 	}
 
 	for _, url := range urls {
-		err := storage.SaveURL(url)
+		err := storage.SaveURL(context.Background(), url)
 		assert.NoError(t, err)
 	}
 
-	retrievedURLs, err := storage.GetURLs(2, 0)
+	retrievedURLs, err := storage.GetURLs(context.Background(), 2, 0)
 	assert.NoError(t, err)
 	assert.Len(t, retrievedURLs, 2)
 
-	count, err := storage.GetURLsCount()
+	count, err := storage.GetURLsCount(context.Background())
 	assert.NoError(t, err)
 	assert.Equal(t, 3, count)
 }
+
+func TestMockStorage_SearchURLs(t *testing.T) {
+	storage := NewMockStorage()
+
+	for _, url := range []*models.URL{
+		{OriginalURL: "https://example.com/Docs", ShortCode: "docs1"},
+		{OriginalURL: "https://example.com/blog", ShortCode: "blog1"},
+		{OriginalURL: "https://example.com/100%_off", ShortCode: "sale1"},
+	} {
+		assert.NoError(t, storage.SaveURL(context.Background(), url))
+	}
+
+	found, err := storage.SearchURLs(context.Background(), "docs", 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, found, 1)
+	assert.Equal(t, "docs1", found[0].ShortCode)
+
+	// % и _ ищутся как обычные символы
+	count, err := storage.SearchURLsCount(context.Background(), "%_")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = storage.SearchURLsCount(context.Background(), "example")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestMockStorage_SetURLDisabled(t *testing.T) {
+	storage := NewMockStorage()
+	assert.NoError(t, storage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "test1"}))
+
+	assert.NoError(t, storage.SetURLDisabled(context.Background(), "test1", true))
+	url, err := storage.GetURL(context.Background(), "test1")
+	assert.NoError(t, err)
+	assert.True(t, url.IsDisabled())
+
+	assert.NoError(t, storage.SetURLDisabled(context.Background(), "test1", false))
+	url, err = storage.GetURL(context.Background(), "test1")
+	assert.NoError(t, err)
+	assert.False(t, url.IsDisabled())
+
+	assert.ErrorIs(t, storage.SetURLDisabled(context.Background(), "missing", true), ErrNotFound)
+}
+
+func TestMockStorage_AuditEvents(t *testing.T) {
+	storage := NewMockStorage()
+	ctx := audit.WithOrigin(context.Background(), audit.Origin{Actor: "user:1", ClientIP: "192.0.2.1", RequestID: "req-1"})
+
+	assert.NoError(t, storage.SaveURL(ctx, &models.URL{OriginalURL: "https://example.com", ShortCode: "test1", PasswordHash: "secret-hash"}))
+	assert.NoError(t, storage.SetURLDisabled(ctx, "test1", true))
+	assert.NoError(t, storage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.org", ShortCode: "test2"}))
+	assert.NoError(t, storage.DeleteURL(ctx, "test1"))
+	assert.NoError(t, storage.DeleteURL(ctx, "missing"))
+
+	events, err := storage.GetAuditEvents(context.Background(), models.AuditFilter{ShortCode: "test1"}, 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, events, 3)
+	assert.Equal(t, models.AuditLinkDelete, events[0].Action)
+	assert.Equal(t, models.AuditLinkDisable, events[1].Action)
+	assert.Equal(t, models.AuditLinkCreate, events[2].Action)
+	assert.Equal(t, "user:1", events[2].Actor)
+	assert.Equal(t, "192.0.2.1", events[2].ClientIP)
+	assert.Equal(t, "req-1", events[2].RequestID)
+	assert.Nil(t, events[2].Before)
+	assert.NotContains(t, string(*events[2].After), "secret-hash")
+	assert.Contains(t, string(*events[1].After), "disabled_at")
+	assert.NotContains(t, string(*events[1].Before), "disabled_at")
+
+	// Изменение без источника в контексте записывается от имени системы
+	count, err := storage.GetAuditEventsCount(context.Background(), models.AuditFilter{Actor: audit.SystemActor})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	page, err := storage.GetAuditEvents(context.Background(), models.AuditFilter{}, 2, 3)
+	assert.NoError(t, err)
+	assert.Len(t, page, 1)
+}
+
+func TestMockStorage_IncrementClickCount(t *testing.T) {
+	storage := NewMockStorage()
+
+	url := &models.URL{
+		OriginalURL: "https://example.com",
+		ShortCode:   "test123",
+	}
+
+	err := storage.SaveURL(context.Background(), url)
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		err = storage.IncrementClickCount(context.Background(), "test123")
+		assert.NoError(t, err)
+	}
+
+	retrievedURL, err := storage.GetURL(context.Background(), "test123")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), retrievedURL.ClickCount)
+
+	err = storage.IncrementClickCount(context.Background(), "nonexistent")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestMockStorage_SaveClickBatch(t *testing.T) {
+	storage := NewMockStorage()
+
+	urls := []*models.URL{
+		{OriginalURL: "https://example1.com", ShortCode: "test1"},
+		{OriginalURL: "https://example2.com", ShortCode: "test2"},
+	}
+	for _, url := range urls {
+		err := storage.SaveURL(context.Background(), url)
+		assert.NoError(t, err)
+	}
+
+	clicks := []*models.Click{{ShortCode: "test1"}, {ShortCode: "test2"}, {ShortCode: "unknown"}}
+	err := storage.SaveClickBatch(context.Background(), map[string]int64{"test1": 5, "test2": 2, "unknown": 1}, clicks)
+	assert.NoError(t, err)
+
+	events, err := storage.GetClicksCount(context.Background(), "test1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, events)
+
+	retrievedURL, err := storage.GetURL(context.Background(), "test1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), retrievedURL.ClickCount)
+
+	retrievedURL, err = storage.GetURL(context.Background(), "test2")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), retrievedURL.ClickCount)
+}
+
+func TestMockStorage_Clicks(t *testing.T) {
+	storage := NewMockStorage()
+
+	err := storage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "test123"})
+	assert.NoError(t, err)
+
+	now := time.Now()
+	clicks := []*models.Click{
+		{ShortCode: "test123", ClickedAt: now.Add(-2 * time.Minute), Referrer: "first"},
+		{ShortCode: "test123", ClickedAt: now.Add(-time.Minute), Referrer: "second"},
+		{ShortCode: "test123", ClickedAt: now, Referrer: "third"},
+		{ShortCode: "unknown", ClickedAt: now},
+	}
+	err = storage.SaveClicks(context.Background(), clicks)
+	assert.NoError(t, err)
+
+	count, err := storage.GetClicksCount(context.Background(), "test123")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	count, err = storage.GetClicksCount(context.Background(), "unknown")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	page, err := storage.GetClicks(context.Background(), "test123", 2, 0)
+	assert.NoError(t, err)
+	assert.Len(t, page, 2)
+	assert.Equal(t, "third", page[0].Referrer)
+	assert.Equal(t, "second", page[1].Referrer)
+
+	page, err = storage.GetClicks(context.Background(), "test123", 2, 2)
+	assert.NoError(t, err)
+	assert.Len(t, page, 1)
+	assert.Equal(t, "first", page[0].Referrer)
+}
+
+func TestMockStorage_GetClickTimeSeries(t *testing.T) {
+	storage := NewMockStorage()
+
+	err := storage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "test123"})
+	assert.NoError(t, err)
+
+	base := time.Date(2025, time.March, 3, 10, 0, 0, 0, time.UTC)
+	err = storage.SaveClicks(context.Background(), []*models.Click{
+		{ShortCode: "test123", ClickedAt: base},
+		{ShortCode: "test123", ClickedAt: base.Add(20 * time.Minute)},
+		{ShortCode: "test123", ClickedAt: base.Add(2 * time.Hour)},
+		{ShortCode: "test123", ClickedAt: base.Add(-time.Hour)}, // раньше from
+	})
+	assert.NoError(t, err)
+
+	buckets, err := storage.GetClickTimeSeries(context.Background(), "test123", base, base.Add(24*time.Hour), "hour", time.UTC)
+	assert.NoError(t, err)
+	assert.Len(t, buckets, 2)
+	assert.Equal(t, base, buckets[0].Start)
+	assert.Equal(t, int64(2), buckets[0].Count)
+	assert.Equal(t, base.Add(2*time.Hour), buckets[1].Start)
+	assert.Equal(t, int64(1), buckets[1].Count)
+}
+
+func TestMockStorage_SaveURLDuplicateShortCode(t *testing.T) {
+	storage := NewMockStorage()
+
+	err := storage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example1.com", ShortCode: "test123"})
+	assert.NoError(t, err)
+
+	err = storage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example2.com", ShortCode: "test123"})
+	assert.ErrorIs(t, err, ErrAlreadyExists)
+
+	url, err := storage.GetURL(context.Background(), "test123")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example1.com", url.OriginalURL)
+}
+
+func TestMockStorage_PurgeExpiredURLs(t *testing.T) {
+	storage := NewMockStorage()
+
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+	urls := []*models.URL{
+		{OriginalURL: "https://example1.com", ShortCode: "expired1", ExpiresAt: &past},
+		{OriginalURL: "https://example2.com", ShortCode: "expired2", ExpiresAt: &past},
+		{OriginalURL: "https://example3.com", ShortCode: "future1", ExpiresAt: &future},
+		{OriginalURL: "https://example4.com", ShortCode: "forever1"},
+	}
+	for _, url := range urls {
+		err := storage.SaveURL(context.Background(), url)
+		assert.NoError(t, err)
+	}
+
+	purged, err := storage.PurgeExpiredURLs(context.Background(), now, 1, true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	purged, err = storage.PurgeExpiredURLs(context.Background(), now, 10, false)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	assert.Equal(t, 2, storage.GetURLCount())
+	assert.Len(t, storage.GetArchivedURLs(), 1)
+}
+
+func TestMockStorage_PurgeExpiredURLsKeepsArchivedClicks(t *testing.T) {
+	storage := NewMockStorage()
+
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	for _, url := range []*models.URL{
+		{OriginalURL: "https://example1.com", ShortCode: "archive1", ExpiresAt: &past, PasswordHash: "hash"},
+		{OriginalURL: "https://example2.com", ShortCode: "forever1"},
+	} {
+		assert.NoError(t, storage.SaveURL(context.Background(), url))
+	}
+	assert.NoError(t, storage.SaveClicks(context.Background(), []*models.Click{
+		{ShortCode: "archive1", ClickedAt: past, Referrer: "https://news.example.org"},
+		{ShortCode: "archive1", ClickedAt: past},
+		{ShortCode: "forever1", ClickedAt: past},
+	}))
+
+	purged, err := storage.PurgeExpiredURLs(context.Background(), now, 10, true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	// Архивная ссылка сохраняет защиту паролем
+	archivedURLs := storage.GetArchivedURLs()
+	assert.Len(t, archivedURLs, 1)
+	assert.Equal(t, "hash", archivedURLs[0].PasswordHash)
+
+	archived := storage.GetArchivedClicks()
+	assert.Len(t, archived, 2)
+	assert.Equal(t, "https://news.example.org", archived[0].Referrer)
+
+	count, err := storage.GetClicksCount(context.Background(), "archive1")
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+	count, err = storage.GetClicksCount(context.Background(), "forever1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestMockStorage_PurgeExpiredURLsDeletesClicks(t *testing.T) {
+	storage := NewMockStorage()
+
+	past := time.Now().Add(-time.Hour)
+	assert.NoError(t, storage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "purge1", ExpiresAt: &past}))
+	assert.NoError(t, storage.SaveClicks(context.Background(), []*models.Click{{ShortCode: "purge1", ClickedAt: past}}))
+
+	purged, err := storage.PurgeExpiredURLs(context.Background(), time.Now(), 10, false)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	assert.Empty(t, storage.GetArchivedClicks())
+}
+
+func TestMockStorage_GetURLByOriginalPrefersPermanent(t *testing.T) {
+	storage := NewMockStorage()
+
+	future := time.Now().Add(time.Hour)
+	err := storage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "temp123", ExpiresAt: &future})
+	assert.NoError(t, err)
+	err = storage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "perm123"})
+	assert.NoError(t, err)
+
+	url, err := storage.GetURLByOriginal(context.Background(), "https://example.com", models.Owner{})
+	assert.NoError(t, err)
+	assert.Equal(t, "perm123", url.ShortCode)
+}
+
+func TestMockStorage_ConsumeClick(t *testing.T) {
+	storage := NewMockStorage()
+
+	maxClicks := int64(2)
+	err := storage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "limited", MaxClicks: &maxClicks})
+	assert.NoError(t, err)
+
+	assert.NoError(t, storage.ConsumeClick(context.Background(), "limited"))
+	assert.NoError(t, storage.ConsumeClick(context.Background(), "limited"))
+	assert.ErrorIs(t, storage.ConsumeClick(context.Background(), "limited"), ErrClickLimitReached)
+	assert.ErrorIs(t, storage.ConsumeClick(context.Background(), "unknown"), ErrNotFound)
+
+	url, err := storage.GetURL(context.Background(), "limited")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), url.ClickCount)
+}
+
+func TestMockStorage_ConsumeClickConcurrent(t *testing.T) {
+	storage := NewMockStorage()
+
+	maxClicks := int64(5)
+	err := storage.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "limited", MaxClicks: &maxClicks})
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var consumed atomic.Int64
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if storage.ConsumeClick(context.Background(), "limited") == nil {
+				consumed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(5), consumed.Load())
+	url, err := storage.GetURL(context.Background(), "limited")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), url.ClickCount)
+}
@@ -0,0 +1,56 @@
+// internal/tracing/middleware.go
+
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware начинает серверный спан на каждый запрос. Если клиент прислал
+// заголовок traceparent, спан становится дочерним для его трейса.
+// Контекст со спаном записывается в c.Request, откуда его берут обработчики и хранилище
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		// Имя спана строится по шаблону маршрута, а не по пути, чтобы не плодить уникальные имена
+		route := c.FullPath()
+		name := c.Request.Method + " " + route
+		if route == "" {
+			name = c.Request.Method
+		}
+
+		ctx, span := tracer().Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.URLPath(c.Request.URL.Path),
+				semconv.ClientAddress(c.ClientIP()),
+			),
+		)
+		defer span.End()
+
+		if route != "" {
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		for _, err := range c.Errors {
+			span.RecordError(err.Err)
+		}
+	}
+}
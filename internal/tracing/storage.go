@@ -0,0 +1,238 @@
+// internal/tracing/storage.go
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// statement описывает SQL запрос, которым PostgresStorage реализует метод
+type statement struct {
+	operation  string // SQL операция (SELECT, INSERT, ...)
+	collection string // Основная таблица запроса
+}
+
+// statements SQL запросы методов Storage для атрибутов спанов
+var statements = map[string]statement{
+	"SaveURL":             {"INSERT", "urls"},
+	"GetURL":              {"SELECT", "urls"},
+	"GetURLByOriginal":    {"SELECT", "urls"},
+	"URLExists":           {"SELECT", "urls"},
+	"DeleteURL":           {"DELETE", "urls"},
+	"GetURLs":             {"SELECT", "urls"},
+	"GetURLsCount":        {"SELECT", "urls"},
+	"GetURLsByOwner":      {"SELECT", "urls"},
+	"GetURLsCountByOwner": {"SELECT", "urls"},
+	"SearchURLs":          {"SELECT", "urls"},
+	"SearchURLsCount":     {"SELECT", "urls"},
+	"SetURLDisabled":      {"UPDATE", "urls"},
+	"IncrementClickCount": {"UPDATE", "urls"},
+	"SaveClickBatch":      {"INSERT", "clicks"},
+	"ConsumeClick":        {"UPDATE", "urls"},
+	"SaveClicks":          {"INSERT", "clicks"},
+	"GetClicks":           {"SELECT", "clicks"},
+	"GetClicksCount":      {"SELECT", "clicks"},
+	"NextSequenceValue":   {"SELECT", "short_code_seq"},
+	"PurgeExpiredURLs":    {"DELETE", "urls"},
+	"GetClickTimeSeries":  {"SELECT", "clicks"},
+}
+
+// TracedStorage декоратор Storage, создающий дочерний спан на каждый вызов метода
+type TracedStorage struct {
+	storage storage.Storage
+}
+
+// NewTracedStorage оборачивает хранилище трассировкой
+func NewTracedStorage(storage storage.Storage) *TracedStorage {
+	return &TracedStorage{storage: storage}
+}
+
+// start начинает спан вида "SELECT urls" с именем метода и SQL операцией в атрибутах
+func (s *TracedStorage) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	stmt := statements[method]
+	attrs = append(attrs,
+		semconv.DBSystemNamePostgreSQL,
+		semconv.DBOperationName(stmt.operation),
+		semconv.DBCollectionName(stmt.collection),
+		semconv.DBQuerySummary(stmt.operation+" "+stmt.collection),
+		attribute.String("code.function.name", "Storage."+method),
+	)
+	return tracer().Start(ctx, stmt.operation+" "+stmt.collection,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// end завершает спан. Ожидаемые ошибки предметной области (ссылка не найдена,
+// код занят, лимит исчерпан) записываются событием, но спан не помечается ошибкой
+func end(span trace.Span, err error) {
+	defer span.End()
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	if errors.Is(err, storage.ErrNotFound) ||
+		errors.Is(err, storage.ErrAlreadyExists) ||
+		errors.Is(err, storage.ErrClickLimitReached) {
+		return
+	}
+	span.SetStatus(codes.Error, err.Error())
+}
+
+var shortCodeKey = attribute.Key("url_shortener.short_code")
+
+func (s *TracedStorage) SaveURL(ctx context.Context, url *models.URL) error {
+	ctx, span := s.start(ctx, "SaveURL", shortCodeKey.String(url.ShortCode))
+	err := s.storage.SaveURL(ctx, url)
+	end(span, err)
+	return err
+}
+
+func (s *TracedStorage) GetURL(ctx context.Context, shortCode string) (*models.URL, error) {
+	ctx, span := s.start(ctx, "GetURL", shortCodeKey.String(shortCode))
+	url, err := s.storage.GetURL(ctx, shortCode)
+	end(span, err)
+	return url, err
+}
+
+func (s *TracedStorage) GetURLByOriginal(ctx context.Context, originalURL string, owner models.Owner) (*models.URL, error) {
+	ctx, span := s.start(ctx, "GetURLByOriginal")
+	url, err := s.storage.GetURLByOriginal(ctx, originalURL, owner)
+	end(span, err)
+	return url, err
+}
+
+func (s *TracedStorage) URLExists(ctx context.Context, shortCode string) (bool, error) {
+	ctx, span := s.start(ctx, "URLExists", shortCodeKey.String(shortCode))
+	exists, err := s.storage.URLExists(ctx, shortCode)
+	end(span, err)
+	return exists, err
+}
+
+func (s *TracedStorage) DeleteURL(ctx context.Context, shortCode string) error {
+	ctx, span := s.start(ctx, "DeleteURL", shortCodeKey.String(shortCode))
+	err := s.storage.DeleteURL(ctx, shortCode)
+	end(span, err)
+	return err
+}
+
+func (s *TracedStorage) GetURLs(ctx context.Context, limit, offset int) ([]*models.URL, error) {
+	ctx, span := s.start(ctx, "GetURLs")
+	urls, err := s.storage.GetURLs(ctx, limit, offset)
+	end(span, err)
+	return urls, err
+}
+
+func (s *TracedStorage) GetURLsCount(ctx context.Context) (int, error) {
+	ctx, span := s.start(ctx, "GetURLsCount")
+	count, err := s.storage.GetURLsCount(ctx)
+	end(span, err)
+	return count, err
+}
+
+func (s *TracedStorage) GetURLsByOwner(ctx context.Context, owner models.Owner, limit, offset int) ([]*models.URL, error) {
+	ctx, span := s.start(ctx, "GetURLsByOwner")
+	urls, err := s.storage.GetURLsByOwner(ctx, owner, limit, offset)
+	end(span, err)
+	return urls, err
+}
+
+func (s *TracedStorage) GetURLsCountByOwner(ctx context.Context, owner models.Owner) (int, error) {
+	ctx, span := s.start(ctx, "GetURLsCountByOwner")
+	count, err := s.storage.GetURLsCountByOwner(ctx, owner)
+	end(span, err)
+	return count, err
+}
+
+func (s *TracedStorage) SearchURLs(ctx context.Context, query string, limit, offset int) ([]*models.URL, error) {
+	ctx, span := s.start(ctx, "SearchURLs")
+	urls, err := s.storage.SearchURLs(ctx, query, limit, offset)
+	end(span, err)
+	return urls, err
+}
+
+func (s *TracedStorage) SearchURLsCount(ctx context.Context, query string) (int, error) {
+	ctx, span := s.start(ctx, "SearchURLsCount")
+	count, err := s.storage.SearchURLsCount(ctx, query)
+	end(span, err)
+	return count, err
+}
+
+func (s *TracedStorage) SetURLDisabled(ctx context.Context, shortCode string, disabled bool) error {
+	ctx, span := s.start(ctx, "SetURLDisabled", shortCodeKey.String(shortCode))
+	err := s.storage.SetURLDisabled(ctx, shortCode, disabled)
+	end(span, err)
+	return err
+}
+
+func (s *TracedStorage) IncrementClickCount(ctx context.Context, shortCode string) error {
+	ctx, span := s.start(ctx, "IncrementClickCount", shortCodeKey.String(shortCode))
+	err := s.storage.IncrementClickCount(ctx, shortCode)
+	end(span, err)
+	return err
+}
+
+func (s *TracedStorage) SaveClickBatch(ctx context.Context, counts map[string]int64, clicks []*models.Click) error {
+	ctx, span := s.start(ctx, "SaveClickBatch", attribute.Int("db.operation.batch.size", len(clicks)))
+	err := s.storage.SaveClickBatch(ctx, counts, clicks)
+	end(span, err)
+	return err
+}
+
+func (s *TracedStorage) ConsumeClick(ctx context.Context, shortCode string) error {
+	ctx, span := s.start(ctx, "ConsumeClick", shortCodeKey.String(shortCode))
+	err := s.storage.ConsumeClick(ctx, shortCode)
+	end(span, err)
+	return err
+}
+
+func (s *TracedStorage) SaveClicks(ctx context.Context, clicks []*models.Click) error {
+	ctx, span := s.start(ctx, "SaveClicks", attribute.Int("db.operation.batch.size", len(clicks)))
+	err := s.storage.SaveClicks(ctx, clicks)
+	end(span, err)
+	return err
+}
+
+func (s *TracedStorage) GetClicks(ctx context.Context, shortCode string, limit, offset int) ([]*models.Click, error) {
+	ctx, span := s.start(ctx, "GetClicks", shortCodeKey.String(shortCode))
+	clicks, err := s.storage.GetClicks(ctx, shortCode, limit, offset)
+	end(span, err)
+	return clicks, err
+}
+
+func (s *TracedStorage) GetClicksCount(ctx context.Context, shortCode string) (int, error) {
+	ctx, span := s.start(ctx, "GetClicksCount", shortCodeKey.String(shortCode))
+	count, err := s.storage.GetClicksCount(ctx, shortCode)
+	end(span, err)
+	return count, err
+}
+
+func (s *TracedStorage) NextSequenceValue(ctx context.Context) (int64, error) {
+	ctx, span := s.start(ctx, "NextSequenceValue")
+	value, err := s.storage.NextSequenceValue(ctx)
+	end(span, err)
+	return value, err
+}
+
+func (s *TracedStorage) PurgeExpiredURLs(ctx context.Context, before time.Time, limit int, archive bool) (int64, error) {
+	ctx, span := s.start(ctx, "PurgeExpiredURLs", attribute.Bool("url_shortener.archive", archive))
+	purged, err := s.storage.PurgeExpiredURLs(ctx, before, limit, archive)
+	end(span, err)
+	return purged, err
+}
+
+func (s *TracedStorage) GetClickTimeSeries(ctx context.Context, shortCode string, from, to time.Time, interval string, loc *time.Location) ([]*models.TimeBucket, error) {
+	ctx, span := s.start(ctx, "GetClickTimeSeries", shortCodeKey.String(shortCode))
+	buckets, err := s.storage.GetClickTimeSeries(ctx, shortCode, from, to, interval, loc)
+	end(span, err)
+	return buckets, err
+}
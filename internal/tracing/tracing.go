@@ -0,0 +1,93 @@
+// internal/tracing/tracing.go
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Экспортеры трейсов
+const (
+	ExporterNone   = "none"   // Трейсы не собираются
+	ExporterStdout = "stdout" // Трейсы печатаются в stdout (для локальной отладки)
+	ExporterOTLP   = "otlp"   // Трейсы отправляются в коллектор по OTLP/HTTP
+)
+
+// instrumentationName имя инструментации для tracer провайдера
+const instrumentationName = "github.com/drerr0r/url-shortener"
+
+// Options задает параметры трассировки
+type Options struct {
+	Exporter    string  // none, stdout или otlp
+	Endpoint    string  // Адрес OTLP коллектора host:port; пусто - из OTEL_EXPORTER_OTLP_ENDPOINT
+	Insecure    bool    // Отправлять в коллектор без TLS
+	ServiceName string  // Имя сервиса в трейсах
+	SampleRatio float64 // Доля сэмплируемых корневых трейсов от 0 до 1
+}
+
+// Setup настраивает глобальный tracer provider и W3C propagator.
+// Возвращает функцию, которая сбрасывает накопленные спаны при остановке сервиса
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	// Распространение контекста включаем всегда, чтобы не рвать трейсы вызывающих сервисов
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch opts.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		var clientOpts []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			clientOpts = append(clientOpts, otlptracehttp.WithEndpoint(opts.Endpoint))
+		}
+		if opts.Insecure {
+			clientOpts = append(clientOpts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, clientOpts...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", opts.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s exporter: %w", opts.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		// Решение родителя из traceparent имеет приоритет над локальной долей
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// tracer возвращает tracer сервиса из глобального провайдера.
+// Провайдер запрашивается при каждом вызове, чтобы учитывать настройку из Setup
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
@@ -0,0 +1,124 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drerr0r/url-shortener/internal/models"
+	"github.com/drerr0r/url-shortener/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// failingStorage возвращает ошибку при сохранении ссылки
+type failingStorage struct {
+	*storage.MockStorage
+}
+
+func (s *failingStorage) SaveURL(ctx context.Context, url *models.URL) error {
+	return errors.New("database unavailable")
+}
+
+// setupRecorder подменяет глобальный провайдер на записывающий спаны в память
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func TestMiddlewarePropagatesTraceparent(t *testing.T) {
+	recorder := setupRecorder(t)
+	gin.SetMode(gin.TestMode)
+
+	store := NewTracedStorage(storage.NewMockStorage())
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/:shortCode", func(c *gin.Context) {
+		_, err := store.GetURL(c.Request.Context(), c.Param("shortCode"))
+		if errors.Is(err, storage.ErrNotFound) {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Status(http.StatusFound)
+	})
+
+	req := httptest.NewRequest("GET", "/abc123", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	dbSpan, serverSpan := spans[0], spans[1]
+
+	assert.Equal(t, "GET /:shortCode", serverSpan.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", serverSpan.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", serverSpan.Parent().SpanID().String())
+	assert.Equal(t, codes.Unset, serverSpan.Status().Code)
+
+	assert.Equal(t, "SELECT urls", dbSpan.Name())
+	assert.Equal(t, serverSpan.SpanContext().SpanID(), dbSpan.Parent().SpanID())
+	assert.Equal(t, serverSpan.SpanContext().TraceID(), dbSpan.SpanContext().TraceID())
+	// Ссылка не найдена - ожидаемый результат, а не сбой
+	assert.Equal(t, codes.Unset, dbSpan.Status().Code)
+}
+
+func TestMiddlewareStartsNewTrace(t *testing.T) {
+	recorder := setupRecorder(t)
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/health", func(c *gin.Context) {
+		c.Status(http.StatusInternalServerError)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.True(t, spans[0].SpanContext().TraceID().IsValid())
+	assert.False(t, spans[0].Parent().IsValid())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
+
+func TestTracedStorageRecordsErrors(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	store := NewTracedStorage(&failingStorage{MockStorage: storage.NewMockStorage()})
+	err := store.SaveURL(context.Background(), &models.URL{OriginalURL: "https://example.com", ShortCode: "abc123"})
+	assert.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "INSERT urls", spans[0].Name())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
+
+func TestSetup(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Options{Exporter: ExporterNone})
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = Setup(context.Background(), Options{Exporter: "jaeger"})
+	assert.Error(t, err)
+}
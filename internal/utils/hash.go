@@ -0,0 +1,39 @@
+// internal/utils/hash.go
+
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HashIP возвращает HMAC-SHA256 от IP адреса в hex.
+// Соль не позволяет восстановить адрес перебором всего пространства IPv4
+func HashIP(ip, salt string) string {
+	if ip == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// MaxPasswordLength ограничение bcrypt: байты сверх 72 не участвуют в хэше
+const MaxPasswordLength = 72
+
+// HashPassword возвращает bcrypt хэш пароля
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword сравнивает пароль с bcrypt хэшем за постоянное время
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
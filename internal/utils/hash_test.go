@@ -0,0 +1,41 @@
+package utils
+
+import "testing"
+
+func TestHashIP(t *testing.T) {
+	hash := HashIP("192.168.0.1", "salt")
+	if len(hash) != 64 {
+		t.Errorf("Expected 64 hex characters, got %d", len(hash))
+	}
+	if hash != HashIP("192.168.0.1", "salt") {
+		t.Error("Hash should be deterministic for the same IP and salt")
+	}
+	if hash == HashIP("192.168.0.1", "other") {
+		t.Error("Hash should depend on salt")
+	}
+	if hash == HashIP("192.168.0.2", "salt") {
+		t.Error("Different IPs should produce different hashes")
+	}
+	if HashIP("", "salt") != "" {
+		t.Error("Empty IP should produce empty hash")
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("s3cret")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	if hash == "s3cret" {
+		t.Error("Password must not be stored in plain text")
+	}
+	if !CheckPassword(hash, "s3cret") {
+		t.Error("Correct password should match")
+	}
+	if CheckPassword(hash, "wrong") {
+		t.Error("Wrong password should not match")
+	}
+	if CheckPassword("", "s3cret") {
+		t.Error("Empty hash should never match")
+	}
+}
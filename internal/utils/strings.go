@@ -4,23 +4,38 @@ package utils
 
 import (
 	"crypto/rand" // 🔴 ИСПРАВЛЕНО: Заменен math/rand на crypto/rand
-	"encoding/base64"
+	"strings"
 )
 
-// GenerateRandomString генерирует случайную строку заданной длины
+// Base62Alphabet алфавит коротких кодов: только буквы и цифры, без '-' и '_'
+const Base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// GenerateRandomString генерирует случайную строку заданной длины в алфавите base62
 // 🔴 ИСПРАВЛЕНО: Заменен небезопасный math/rand на криптографически безопасный crypto/rand
 func GenerateRandomString(length int) string {
-	b := make([]byte, length)
-
-	// БЫЛО: rand.Read(b) // math/rand - предсказуемо и небезопасно
-	// СТАЛО: использование crypto/rand для генерации криптографически безопасных случайных значений
-	_, err := rand.Read(b)
-	if err != nil {
-		// В продакшене следует использовать proper error handling
-		panic("failed to generate random string: " + err.Error())
+	result := make([]byte, 0, length)
+	buf := make([]byte, length+length/2)
+
+	for len(result) < length {
+		_, err := rand.Read(buf)
+		if err != nil {
+			// В продакшене следует использовать proper error handling
+			panic("failed to generate random string: " + err.Error())
+		}
+
+		// Отбрасываем байты >= 248 (62*4), чтобы символы были распределены равномерно
+		for _, b := range buf {
+			if b >= 248 {
+				continue
+			}
+			result = append(result, Base62Alphabet[b%62])
+			if len(result) == length {
+				break
+			}
+		}
 	}
 
-	return base64.URLEncoding.EncodeToString(b)[:length]
+	return string(result)
 }
 
 // IsValidShortCode проверяет валидность короткого кода
@@ -41,6 +56,30 @@ func IsValidShortCode(code string) bool {
 	return true
 }
 
+// reservedShortCodes коды, совпадающие с маршрутами сервиса или зарезервированные под них
+var reservedShortCodes = map[string]struct{}{
+	"api":     {},
+	"health":  {},
+	"static":  {},
+	"assets":  {},
+	"debug":   {},
+	"metrics": {},
+	"admin":   {},
+	"login":   {},
+	"logout":  {},
+	"signup":  {},
+	"my":      {},
+	"livez":   {},
+	"readyz":  {},
+}
+
+// IsReservedShortCode проверяет, зарезервирован ли код под служебные маршруты.
+// Сравнение без учета регистра, чтобы нельзя было занять, например, "Admin"
+func IsReservedShortCode(code string) bool {
+	_, reserved := reservedShortCodes[strings.ToLower(code)]
+	return reserved
+}
+
 // TruncateString обрезает строку до указанной длины
 func TruncateString(s string, length int) string {
 	if len(s) <= length {
@@ -25,7 +25,7 @@ func TestGenerateRandomString(t *testing.T) {
 
 			// Проверяем, что строка содержит только допустимые символы
 			for _, char := range result {
-				if !strings.ContainsRune("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_", char) {
+				if !strings.ContainsRune(Base62Alphabet, char) {
 					t.Errorf("Invalid character in random string: %c", char)
 				}
 			}
@@ -91,3 +91,25 @@ func TestTruncateString(t *testing.T) {
 		})
 	}
 }
+
+func TestIsReservedShortCode(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected bool
+	}{
+		{"api", true},
+		{"health", true},
+		{"Static", true},
+		{"ADMIN", true},
+		{"abc123", false},
+		{"my-api", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if result := IsReservedShortCode(tt.code); result != tt.expected {
+				t.Errorf("IsReservedShortCode(%q) = %v, expected %v", tt.code, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+// internal/utils/time.go
+
+package utils
+
+import (
+	"time"
+)
+
+// Интервалы агрегации временных рядов
+const (
+	IntervalHour = "hour"
+	IntervalDay  = "day"
+	IntervalWeek = "week"
+)
+
+// IsValidInterval проверяет, поддерживается ли интервал агрегации
+func IsValidInterval(interval string) bool {
+	switch interval {
+	case IntervalHour, IntervalDay, IntervalWeek:
+		return true
+	}
+	return false
+}
+
+// TruncateTime округляет время вниз до начала интервала в часовом поясе t.
+// Неделя начинается с понедельника, как и в date_trunc('week') PostgreSQL
+func TruncateTime(t time.Time, interval string) time.Time {
+	year, month, day := t.Date()
+	switch interval {
+	case IntervalHour:
+		return time.Date(year, month, day, t.Hour(), 0, 0, 0, t.Location())
+	case IntervalWeek:
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-daysSinceMonday, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// NextBucket возвращает начало следующего интервала.
+// Дни и недели считаются по календарю, чтобы переход на летнее время не сдвигал границы
+func NextBucket(t time.Time, interval string) time.Time {
+	switch interval {
+	case IntervalHour:
+		return t.Add(time.Hour)
+	case IntervalWeek:
+		return t.AddDate(0, 0, 7)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
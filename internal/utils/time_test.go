@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsValidInterval(t *testing.T) {
+	for _, interval := range []string{IntervalHour, IntervalDay, IntervalWeek} {
+		if !IsValidInterval(interval) {
+			t.Errorf("Interval %q should be valid", interval)
+		}
+	}
+	for _, interval := range []string{"", "month", "minute"} {
+		if IsValidInterval(interval) {
+			t.Errorf("Interval %q should be invalid", interval)
+		}
+	}
+}
+
+func TestTruncateTime(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skipf("Timezone data is not available: %v", err)
+	}
+
+	// Среда, 15 октября 2025, 14:35:10 по Москве
+	ts := time.Date(2025, time.October, 15, 14, 35, 10, 0, moscow)
+
+	tests := []struct {
+		name     string
+		interval string
+		expected time.Time
+	}{
+		{"Hour", IntervalHour, time.Date(2025, time.October, 15, 14, 0, 0, 0, moscow)},
+		{"Day", IntervalDay, time.Date(2025, time.October, 15, 0, 0, 0, 0, moscow)},
+		{"Week", IntervalWeek, time.Date(2025, time.October, 13, 0, 0, 0, 0, moscow)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := TruncateTime(ts, tt.interval)
+			if !result.Equal(tt.expected) {
+				t.Errorf("TruncateTime(%v, %q) = %v, expected %v", ts, tt.interval, result, tt.expected)
+			}
+		})
+	}
+
+	sunday := time.Date(2025, time.October, 19, 23, 0, 0, 0, time.UTC)
+	if result := TruncateTime(sunday, IntervalWeek); !result.Equal(time.Date(2025, time.October, 13, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Sunday should belong to the week starting on Monday, got %v", result)
+	}
+}
+
+func TestNextBucket(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("Timezone data is not available: %v", err)
+	}
+
+	// 26 октября 2025 в Берлине длится 25 часов из-за перехода на зимнее время
+	day := time.Date(2025, time.October, 26, 0, 0, 0, 0, berlin)
+	next := NextBucket(day, IntervalDay)
+	if !next.Equal(time.Date(2025, time.October, 27, 0, 0, 0, 0, berlin)) {
+		t.Errorf("Expected next day at local midnight, got %v", next)
+	}
+
+	hour := time.Date(2025, time.October, 26, 10, 0, 0, 0, time.UTC)
+	if next := NextBucket(hour, IntervalHour); next.Sub(hour) != time.Hour {
+		t.Errorf("Expected one hour step, got %v", next.Sub(hour))
+	}
+
+	if next := NextBucket(day, IntervalWeek); !next.Equal(time.Date(2025, time.November, 2, 0, 0, 0, 0, berlin)) {
+		t.Errorf("Expected next week, got %v", next)
+	}
+}
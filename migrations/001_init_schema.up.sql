@@ -1,3 +1,4 @@
+-- +goose Up
 -- Миграция для создания таблицы URLs
 CREATE TABLE urls (
     id SERIAL PRIMARY KEY,
@@ -17,4 +18,7 @@ COMMENT ON TABLE urls IS 'Таблица для хранения сокраще
 COMMENT ON COLUMN urls.original_url IS 'Оригинальный URL';
 COMMENT ON COLUMN urls.short_code IS 'Сокращенный код URL';
 COMMENT ON COLUMN urls.created_at IS 'Время создания записи';
-COMMENT ON COLUMN urls.access_count IS 'Количество переходов по ссылке';
\ No newline at end of file
+COMMENT ON COLUMN urls.access_count IS 'Количество переходов по ссылке';
+
+-- +goose Down
+DROP TABLE urls;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Приводим имя колонки счетчика в соответствие с моделью models.URL (click_count)
+ALTER TABLE urls RENAME COLUMN access_count TO click_count;
+UPDATE urls SET click_count = 0 WHERE click_count IS NULL;
+ALTER TABLE urls ALTER COLUMN click_count TYPE BIGINT;
+ALTER TABLE urls ALTER COLUMN click_count SET NOT NULL;
+
+COMMENT ON COLUMN urls.click_count IS 'Количество переходов по ссылке';
+
+-- +goose Down
+ALTER TABLE urls ALTER COLUMN click_count DROP NOT NULL;
+ALTER TABLE urls ALTER COLUMN click_count TYPE INTEGER;
+ALTER TABLE urls RENAME COLUMN click_count TO access_count;
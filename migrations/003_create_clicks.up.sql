@@ -0,0 +1,21 @@
+-- +goose Up
+-- Журнал переходов по ссылкам: одна строка на каждый редирект
+CREATE TABLE clicks (
+    id BIGSERIAL PRIMARY KEY,
+    short_code VARCHAR(12) NOT NULL REFERENCES urls(short_code) ON DELETE CASCADE,
+    clicked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    referrer TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_hash VARCHAR(64) NOT NULL DEFAULT '',
+    accept_language TEXT NOT NULL DEFAULT '',
+    country VARCHAR(2) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_clicks_short_code_clicked_at ON clicks(short_code, clicked_at);
+
+COMMENT ON TABLE clicks IS 'Журнал переходов по сокращенным ссылкам';
+COMMENT ON COLUMN clicks.ip_hash IS 'HMAC-SHA256 от IP адреса клиента, сам адрес не хранится';
+COMMENT ON COLUMN clicks.country IS 'Код страны ISO 3166-1 из заголовка прокси (например, CF-IPCountry)';
+
+-- +goose Down
+DROP TABLE clicks;
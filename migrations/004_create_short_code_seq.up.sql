@@ -0,0 +1,6 @@
+-- +goose Up
+-- Последовательность для стратегии генерации коротких кодов sequential
+CREATE SEQUENCE short_code_seq AS BIGINT START WITH 1;
+
+-- +goose Down
+DROP SEQUENCE short_code_seq;
@@ -0,0 +1,25 @@
+-- +goose Up
+-- Срок действия ссылок и архив для истекших ссылок
+ALTER TABLE urls ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_urls_expires_at ON urls(expires_at) WHERE expires_at IS NOT NULL;
+
+CREATE TABLE urls_archive (
+    id INTEGER PRIMARY KEY,
+    original_url TEXT NOT NULL,
+    short_code VARCHAR(12) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE,
+    click_count BIGINT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_urls_archive_short_code ON urls_archive(short_code);
+
+COMMENT ON COLUMN urls.expires_at IS 'Время истечения ссылки, NULL - бессрочная';
+COMMENT ON TABLE urls_archive IS 'Истекшие ссылки, перенесенные фоновым janitor';
+
+-- +goose Down
+DROP TABLE urls_archive;
+DROP INDEX idx_urls_expires_at;
+ALTER TABLE urls DROP COLUMN expires_at;
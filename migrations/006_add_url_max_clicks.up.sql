@@ -0,0 +1,10 @@
+-- +goose Up
+-- Лимит переходов для одноразовых и N-разовых ссылок
+ALTER TABLE urls ADD COLUMN max_clicks BIGINT CHECK (max_clicks > 0);
+ALTER TABLE urls_archive ADD COLUMN max_clicks BIGINT;
+
+COMMENT ON COLUMN urls.max_clicks IS 'Максимальное количество переходов, NULL - без ограничений';
+
+-- +goose Down
+ALTER TABLE urls_archive DROP COLUMN max_clicks;
+ALTER TABLE urls DROP COLUMN max_clicks;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Пароль для защищенных ссылок (bcrypt хэш)
+ALTER TABLE urls ADD COLUMN password_hash TEXT NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN urls.password_hash IS 'bcrypt хэш пароля, пустая строка - ссылка без пароля';
+
+-- +goose Down
+ALTER TABLE urls DROP COLUMN password_hash;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- API ключи клиентов; сам ключ не хранится, только его SHA-256 хэш
+CREATE TABLE api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Владелец ссылки: ключ, с которым она создана
+ALTER TABLE urls ADD COLUMN owner_id BIGINT REFERENCES api_keys(id);
+ALTER TABLE urls_archive ADD COLUMN owner_id BIGINT;
+
+CREATE INDEX idx_urls_owner_id ON urls(owner_id) WHERE owner_id IS NOT NULL;
+
+COMMENT ON TABLE api_keys IS 'API ключи для Authorization: Bearer';
+COMMENT ON COLUMN api_keys.prefix IS 'Начало ключа для опознания в списке, не секрет';
+COMMENT ON COLUMN api_keys.revoked_at IS 'Время отзыва, NULL - ключ действует';
+COMMENT ON COLUMN urls.owner_id IS 'API ключ, создавший ссылку; NULL - анонимная ссылка';
+
+-- +goose Down
+DROP INDEX idx_urls_owner_id;
+ALTER TABLE urls_archive DROP COLUMN owner_id;
+ALTER TABLE urls DROP COLUMN owner_id;
+DROP TABLE api_keys;
@@ -0,0 +1,35 @@
+-- +goose Up
+-- Пользователи веб-интерфейса и их сессии
+CREATE TABLE users (
+    id BIGSERIAL PRIMARY KEY,
+    email TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE sessions (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    csrf_token VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+
+-- Ссылки, созданные из веб-интерфейса, принадлежат пользователю
+ALTER TABLE urls ADD COLUMN user_id BIGINT REFERENCES users(id);
+ALTER TABLE urls_archive ADD COLUMN user_id BIGINT;
+
+CREATE INDEX idx_urls_user_id ON urls(user_id, created_at) WHERE user_id IS NOT NULL;
+
+COMMENT ON COLUMN users.email IS 'Email в нижнем регистре, используется как логин';
+COMMENT ON COLUMN sessions.id IS 'SHA-256 хэш токена из cookie, сам токен не хранится';
+COMMENT ON COLUMN urls.user_id IS 'Пользователь, создавший ссылку; NULL - ссылка создана анонимно или по API ключу';
+
+-- +goose Down
+DROP INDEX idx_urls_user_id;
+ALTER TABLE urls_archive DROP COLUMN user_id;
+ALTER TABLE urls DROP COLUMN user_id;
+DROP TABLE sessions;
+DROP TABLE users;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Роли пользователей и привязка к учетной записи корпоративного OIDC провайдера
+ALTER TABLE users ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'editor'
+    CHECK (role IN ('viewer', 'editor', 'admin'));
+ALTER TABLE users ADD COLUMN oidc_issuer TEXT;
+ALTER TABLE users ADD COLUMN oidc_subject TEXT;
+
+CREATE UNIQUE INDEX idx_users_oidc_subject ON users(oidc_issuer, oidc_subject) WHERE oidc_subject IS NOT NULL;
+
+COMMENT ON COLUMN users.role IS 'viewer, editor или admin; у пользователей SSO обновляется при каждом входе';
+COMMENT ON COLUMN users.oidc_subject IS 'Claim sub из ID токена; уникален в пределах oidc_issuer';
+COMMENT ON COLUMN users.password_hash IS 'bcrypt хэш; пустая строка - вход только через SSO';
+
+-- +goose Down
+DROP INDEX idx_users_oidc_subject;
+ALTER TABLE users DROP COLUMN oidc_subject;
+ALTER TABLE users DROP COLUMN oidc_issuer;
+ALTER TABLE users DROP COLUMN role;
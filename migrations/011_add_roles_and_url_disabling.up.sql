@@ -0,0 +1,15 @@
+-- +goose Up
+-- Роли API ключей и отключение ссылок администратором
+ALTER TABLE api_keys ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'editor'
+    CHECK (role IN ('viewer', 'editor', 'admin'));
+
+ALTER TABLE urls ADD COLUMN disabled_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE urls_archive ADD COLUMN disabled_at TIMESTAMP WITH TIME ZONE;
+
+COMMENT ON COLUMN api_keys.role IS 'viewer, editor или admin; существующие ключи получают editor';
+COMMENT ON COLUMN urls.disabled_at IS 'Время отключения ссылки администратором; NULL - ссылка работает';
+
+-- +goose Down
+ALTER TABLE urls_archive DROP COLUMN disabled_at;
+ALTER TABLE urls DROP COLUMN disabled_at;
+ALTER TABLE api_keys DROP COLUMN role;
@@ -0,0 +1,37 @@
+-- +goose Up
+-- Журнал аудита изменений ссылок. Пишется в одной транзакции с изменением и только дополняется:
+-- UPDATE запрещен триггером, DELETE выполняет только задача хранения журнала
+CREATE TABLE audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    actor VARCHAR(64) NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    short_code VARCHAR(255) NOT NULL,
+    before JSONB,
+    after JSONB,
+    client_ip VARCHAR(64) NOT NULL DEFAULT '',
+    request_id VARCHAR(64) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_audit_events_short_code ON audit_events(short_code, id);
+CREATE INDEX idx_audit_events_actor ON audit_events(actor, id);
+CREATE INDEX idx_audit_events_created_at ON audit_events(created_at);
+
+-- +goose StatementBegin
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+COMMENT ON COLUMN audit_events.actor IS 'user:<id>, key:<id>, anonymous или system:<задача> (system:janitor, system:internal)';
+COMMENT ON COLUMN audit_events.before IS 'Ссылка до изменения без хэша пароля; NULL - ссылки не было';
+COMMENT ON COLUMN audit_events.after IS 'Ссылка после изменения без хэша пароля; NULL - ссылка удалена';
+
+-- +goose Down
+DROP TABLE audit_events;
+DROP FUNCTION audit_events_append_only();
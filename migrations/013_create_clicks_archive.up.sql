@@ -0,0 +1,21 @@
+-- +goose Up
+-- Архив журнала переходов: janitor в режиме archive переносит сюда переходы истекших ссылок
+-- вместе с самими ссылками, иначе ON DELETE CASCADE стирал бы историю
+CREATE TABLE clicks_archive (
+    id BIGINT PRIMARY KEY,
+    short_code VARCHAR(12) NOT NULL,
+    clicked_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    referrer TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_hash VARCHAR(64) NOT NULL DEFAULT '',
+    accept_language TEXT NOT NULL DEFAULT '',
+    country VARCHAR(2) NOT NULL DEFAULT '',
+    archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_clicks_archive_short_code_clicked_at ON clicks_archive(short_code, clicked_at);
+
+COMMENT ON TABLE clicks_archive IS 'Переходы по ссылкам, перенесенным в urls_archive';
+
+-- +goose Down
+DROP TABLE clicks_archive;
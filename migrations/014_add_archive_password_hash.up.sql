@@ -0,0 +1,8 @@
+-- +goose Up
+-- Архивная ссылка хранит хэш пароля, чтобы ее можно было восстановить вместе с защитой
+ALTER TABLE urls_archive ADD COLUMN password_hash TEXT NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN urls_archive.password_hash IS 'bcrypt хэш пароля, пустая строка - ссылка без пароля';
+
+-- +goose Down
+ALTER TABLE urls_archive DROP COLUMN password_hash;
@@ -0,0 +1,11 @@
+// migrations/migrations.go
+
+// Package migrations содержит SQL миграции схемы базы данных, встроенные в бинарник
+package migrations
+
+import "embed"
+
+// FS файлы миграций в формате goose (-- +goose Up / -- +goose Down)
+//
+//go:embed *.sql
+var FS embed.FS